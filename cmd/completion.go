@@ -0,0 +1,203 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+)
+
+// completionTTL is how long job names, contexts and namespaces are cached for completion.
+const completionTTL = 10 * time.Second
+
+// jobFileExtensions are the extensions of job specifications completed for "frink run".
+var jobFileExtensions = []string{"yaml", "yml", "json"}
+
+var (
+	// completionCache caches the names listed by completion functions, since completion runs frink on every <TAB>.
+	completionCache = cli.NewCache(completionTTL)
+
+	// newCompletionClient returns the client used by completion functions.
+	newCompletionClient = k8s.NewClient
+)
+
+type completionContext struct {
+}
+
+func newCompletionCmd() *cobra.Command {
+	ctx := &completionContext{}
+	cmd := &cobra.Command{
+		Use:   "completion <bash|zsh|fish>",
+		Short: "Generate a shell completion script",
+		Long: `Generate a shell completion script.
+
+Besides commands and flags, the script completes job names for logs, rm and debug, job specifications
+for run, contexts for --context and namespaces for -n, which are looked up in the cluster and cached briefly.
+
+To load completions in the current shell:
+
+  bash:  source <(frink completion bash)
+  zsh:   source <(frink completion zsh)
+  fish:  frink completion fish | source
+
+To load completions in every shell, write the script to the completion directory of your shell, e.g.
+"frink completion bash > ~/.local/share/bash-completion/completions/frink". Completion in bash requires
+the bash-completion package.`,
+		ValidArgs: []string{"bash", "zsh", "fish"},
+		Args:      cobra.ExactValidArgs(1),
+
+		RunE: ctx.Run,
+	}
+
+	return cmd
+}
+
+func (ctx *completionContext) Run(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	root := cmd.Root()
+
+	switch args[0] {
+	case "bash":
+		return root.GenBashCompletionV2(out, true)
+	case "zsh":
+		return root.GenZshCompletion(out)
+	case "fish":
+		return root.GenFishCompletion(out, true)
+	}
+
+	return fmt.Errorf("unsupported shell %q", args[0])
+}
+
+// registerFlagCompletions registers completion of contexts for --context, and namespaces for -n, on cmd.
+// Commands defining their own namespace flag must register its completion themselves.
+func registerFlagCompletions(cmd *cobra.Command) {
+	_ = cmd.RegisterFlagCompletionFunc("context", completeContexts)
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeNamespaces)
+}
+
+// completeJobName completes the name of a single job.
+func completeJobName(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return completeJobNames(cmd, args, toComplete)
+}
+
+// completeJobNames completes the names of jobs that are not already given as arguments.
+func completeJobNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := listCompletions(cmd, "jobs", func(client k8s.Client) ([]string, error) {
+		jobs, err := client.ListJobs()
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(jobs))
+		for _, job := range jobs {
+			names = append(names, job.Name)
+		}
+
+		return names, nil
+	})
+
+	return filterCompletions(names, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeCronJobNames completes the names of cron jobs that are not already given as arguments.
+func completeCronJobNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := listCompletions(cmd, "cronjobs", func(client k8s.Client) ([]string, error) {
+		cronJobs, err := client.ListCronJobs()
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(cronJobs))
+		for _, cronJob := range cronJobs {
+			names = append(names, cronJob.Name)
+		}
+
+		return names, nil
+	})
+
+	return filterCompletions(names, args, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeJobFile completes the file name of a single job specification.
+func completeJobFile(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) > 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return jobFileExtensions, cobra.ShellCompDirectiveFilterFileExt
+}
+
+// completeContexts completes the names of the contexts in the kubeconfig.
+func completeContexts(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names, err := k8s.ContextNames()
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	return filterCompletions(names, nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// completeNamespaces completes the names of the namespaces in the cluster of the context.
+func completeNamespaces(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	names := listCompletions(cmd, "namespaces", func(client k8s.Client) ([]string, error) {
+		namespaces, err := client.ListNamespaces()
+		if err != nil {
+			return nil, err
+		}
+
+		names := make([]string, 0, len(namespaces))
+		for _, namespace := range namespaces {
+			names = append(names, namespace.Name)
+		}
+
+		return names, nil
+	})
+
+	return filterCompletions(names, nil, toComplete), cobra.ShellCompDirectiveNoFileComp
+}
+
+// listCompletions returns the names listed by list using a client for the context and namespace of cmd,
+// caching them under kind. Errors are only logged when debugging completion, since they cannot be shown while completing.
+func listCompletions(cmd *cobra.Command, kind string, list func(client k8s.Client) ([]string, error)) []string {
+	cfg, err := cli.ParseConfig(cmd)
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil
+	}
+
+	key := strings.Join([]string{kind, cfg.Context, cfg.Namespace}, "-")
+	names, err := completionCache.Strings(key, func() ([]string, error) {
+		client, err := newCompletionClient(cfg.Context, cfg.Namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		return list(client)
+	})
+	if err != nil {
+		cobra.CompDebugln(err.Error(), true)
+		return nil
+	}
+
+	return names
+}
+
+// filterCompletions returns the names with the given prefix, except those in exclude.
+func filterCompletions(names, exclude []string, prefix string) []string {
+	var filtered []string
+	for _, name := range names {
+		if strings.HasPrefix(name, prefix) && !containsName(exclude, name) {
+			filtered = append(filtered, name)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// useCompletionClient makes completion functions use client, without caching.
+func useCompletionClient(t *testing.T, client k8s.Client) {
+	cache, newClient := completionCache, newCompletionClient
+	t.Cleanup(func() { completionCache, newCompletionClient = cache, newClient })
+
+	completionCache = &cli.Cache{}
+	newCompletionClient = func(context, namespace string) (k8s.Client, error) {
+		return client, nil
+	}
+}
+
+// complete runs the hidden completion command of cobra with args, returning the completions it printed.
+func complete(t *testing.T, args ...string) []string {
+	var out strings.Builder
+	cmd := newRootCmd()
+	cmd.SetOut(&out)
+	cmd.SetErr(&strings.Builder{})
+	cmd.SetArgs(append([]string{"__complete"}, args...))
+	assert.NoError(t, cmd.Execute())
+
+	return strings.Split(strings.TrimSpace(out.String()), "\n")
+}
+
+func TestCompletionJobNames(t *testing.T) {
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{
+		{ObjectMeta: metav1.ObjectMeta{Name: "exp-1"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "exp-2"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "eval"}},
+	}, nil)
+	useCompletionClient(t, client)
+
+	assert.Equal(t, []string{"exp-1", "exp-2", ":4"}, complete(t, "logs", "exp"))
+	assert.Equal(t, []string{":4"}, complete(t, "logs", "exp-1", ""))
+	assert.Equal(t, []string{"eval", ":4"}, complete(t, "debug", "ev"))
+	assert.Equal(t, []string{"exp-2", "eval", ":4"}, complete(t, "rm", "exp-1", ""))
+}
+
+func TestCompletionCronJobNames(t *testing.T) {
+	client := &fake.Client{}
+	client.On("ListCronJobs").Return([]batchv1.CronJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nightly"}},
+	}, nil)
+	useCompletionClient(t, client)
+
+	assert.Equal(t, []string{"nightly", ":4"}, complete(t, "rm", "--cron", ""))
+	client.AssertNotCalled(t, "ListJobs")
+}
+
+func TestCompletionNamespaces(t *testing.T) {
+	client := &fake.Client{}
+	client.On("ListNamespaces").Return([]corev1.Namespace{
+		{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "jane"}},
+	}, nil)
+	useCompletionClient(t, client)
+
+	assert.Equal(t, []string{"jane", ":4"}, complete(t, "ls", "-n", "j"))
+	assert.Equal(t, []string{"default", ":4"}, complete(t, "debug", "-n", "d"))
+}
+
+func TestCompletionJobFile(t *testing.T) {
+	assert.Equal(t, []string{"yaml", "yml", "json", ":8"}, complete(t, "run", ""))
+}
+
+func TestCompletionScripts(t *testing.T) {
+	for _, shell := range []string{"bash", "zsh", "fish"} {
+		var out strings.Builder
+		cmd := newRootCmd()
+		cmd.SetOut(&out)
+		cmd.SetArgs([]string{"completion", shell})
+
+		assert.NoError(t, cmd.Execute(), shell)
+		assert.Contains(t, out.String(), "__complete", shell)
+	}
+
+	cmd := newRootCmd()
+	cmd.SetOut(&strings.Builder{})
+	cmd.SetErr(&strings.Builder{})
+	cmd.SetArgs([]string{"completion", "powershell"})
+	assert.Error(t, cmd.Execute())
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+)
+
+type configContext struct {
+	ShowOrigin bool
+}
+
+func newConfigCmd() *cobra.Command {
+	ctx := &configContext{}
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "View and edit the frink configuration",
+		Long: `View and edit the frink configuration.
+
+Settings are read from the user configuration file, followed by any project configuration files
+named .frink.yaml in the current directory and its parents, up to the root of the git repository.
+Files closer to the current directory take precedence, and FRINK_* environment variables and
+command-line flags take precedence over all files. The set command only edits the user configuration file.
+
+Settings deciding where data is sent (context, gpu.url, metrics.gpuURL, and the notification sinks) can only
+be set in the user configuration file or the environment. The email password can only be set via the
+FRINK_NOTIFY_EMAIL_PASSWORD environment variable, and is redacted when viewing the configuration.`,
+	}
+
+	viewCmd := &cobra.Command{
+		Use:   "view",
+		Short: "Print the effective configuration",
+		Args:  cobra.NoArgs,
+
+		RunE: ctx.View,
+	}
+	viewCmd.Flags().BoolVar(&ctx.ShowOrigin, "show-origin", false, "show where each setting came from")
+	cmd.AddCommand(viewCmd)
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get <key>",
+		Short: "Print the effective value of a setting",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: ctx.Get,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set <key> <value>",
+		Short: "Set a setting in the configuration file",
+		Args:  cobra.ExactArgs(2),
+
+		RunE: ctx.Set,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the configuration file",
+		Args:  cobra.NoArgs,
+
+		Run: ctx.Path,
+	})
+
+	return cmd
+}
+
+func (ctx *configContext) View(cmd *cobra.Command, args []string) error {
+	settings, err := cli.Settings(cmd)
+	if err != nil {
+		return err
+	}
+
+	if !ctx.ShowOrigin {
+		return printYAML(cmd, settings)
+	}
+
+	origins, err := cli.SettingOrigins(cmd)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]string, 0, len(origins))
+	for key := range origins {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintln(w, "KEY\tVALUE\tORIGIN\t")
+	for _, key := range keys {
+		value, err := cli.LookupSetting(settings, key)
+		if err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", key, b, origins[key])
+	}
+
+	return nil
+}
+
+func (ctx *configContext) Get(cmd *cobra.Command, args []string) error {
+	value, err := cli.Setting(cmd, args[0])
+	if err != nil {
+		return err
+	}
+
+	if _, ok := value.(map[string]interface{}); ok {
+		return printYAML(cmd, value)
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), value)
+	return nil
+}
+
+func (ctx *configContext) Set(cmd *cobra.Command, args []string) error {
+	if err := cli.SetConfigValue(cli.ConfigFile(), args[0], args[1]); err != nil {
+		return fmt.Errorf("unable to set %s: %w", args[0], err)
+	}
+
+	return nil
+}
+
+func (ctx *configContext) Path(cmd *cobra.Command, args []string) {
+	fmt.Fprintln(cmd.OutOrStdout(), cli.ConfigFile())
+}
+
+func printYAML(cmd *cobra.Command, value interface{}) error {
+	return printStructured(cmd.OutOrStdout(), "yaml", value)
+}
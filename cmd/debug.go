@@ -22,10 +22,13 @@ func newDebugCmd() *cobra.Command {
 		Args:    cobra.ExactArgs(1), // Ensures exactly one argument is passed
 		PreRunE: ctx.PreRun,
 		RunE:    ctx.Run,
+
+		ValidArgsFunction: completeJobName,
 	}
 
 	flags := cmd.Flags()
 	flags.StringVarP(&ctx.Namespace, "namespace", "n", "default", "Specify the namespace of the resource")
+	_ = cmd.RegisterFlagCompletionFunc("namespace", completeNamespaces)
 
 	return cmd
 }
@@ -1,153 +1,452 @@
-package cmd
-import (
-	"context"
-	"fmt"
-	"io"
-	"net/http"
-	"net/url"
-	"os"
-	"strconv"
-	"strings"
-	"time"
-	"github.com/spf13/cobra"
-	"github.com/uitml/frink/internal/cli"
-	"k8s.io/client-go/rest"
-	"k8s.io/client-go/tools/clientcmd"
-)
-type gpuContext struct {
-	cli.CommandContext
-	ServiceURL string        // URL to the gpu-viewer service; if empty, use API server proxy
-	Format     string        // "table" or "oneline"
-	Color      bool          // enable ANSI colors
-	Limit      int           // only used for oneline
-	Timeout    time.Duration // HTTP timeout
-}
-func newGPUCmd() *cobra.Command {
-	ctx := &gpuContext{
-		Format:  "table",
-		Timeout: 5 * time.Second,
-	}
-	cmd := &cobra.Command{
-		Use:   "gpu",
-		Short: "Show cluster GPU availability",
-		PreRunE: func(cmd *cobra.Command, args []string) error {
-			return ctx.Initialize(cmd)
-		},
-		RunE: ctx.Run,
-	}
-	flags := cmd.Flags()
-	flags.StringVar(&ctx.ServiceURL, "service-url", "", "GPU viewer service URL (env GPU_VIEWER_URL or API proxy if empty)")
-	flags.StringVar(&ctx.Format, "format", "table", "Output format: table|oneline")
-	flags.BoolVar(&ctx.Color, "color", false, "Enable ANSI colors")
-	flags.IntVar(&ctx.Limit, "limit", 0, "Limit number of nodes (only for --format=oneline)")
-	flags.DurationVar(&ctx.Timeout, "timeout", 5*time.Second, "HTTP request timeout")
-	return cmd
-}
-func (ctx *gpuContext) Run(cmd *cobra.Command, args []string) error {
-	// 1) If user provided a direct URL (flag or env), use it (works with port-forward or Ingress)
-	if ctx.ServiceURL != "" || os.Getenv("GPU_VIEWER_URL") != "" {
-		serviceURL := ctx.ServiceURL
-		if serviceURL == "" {
-			serviceURL = os.Getenv("GPU_VIEWER_URL")
-		}
-		return fetchDirect(cmd, serviceURL, ctx.Format, ctx.Color, ctx.Limit, ctx.Timeout)
-	}
-	// 2) Otherwise, go through the API server service proxy using kubeconfig
-	return fetchViaAPIServerProxy(cmd, ctx.Format, ctx.Color, ctx.Limit, ctx.Timeout)
-}
-// Direct HTTP call (port-forward or Ingress)
-func fetchDirect(cmd *cobra.Command, baseURL, format string, color bool, limit int, timeout time.Duration) error {
-	u, err := url.Parse(strings.TrimRight(baseURL, "/"))
-	if err != nil {
-		return fmt.Errorf("invalid service-url: %w", err)
-	}
-	q := u.Query()
-	switch strings.ToLower(format) {
-	case "oneline":
-		q.Set("format", "oneline")
-		if limit > 0 {
-			q.Set("limit", strconv.Itoa(limit))
-		}
-	case "table", "":
-		// default
-	default:
-		return fmt.Errorf("unknown format %q (use table or oneline)", format)
-	}
-	if color {
-		q.Set("color", "1")
-	}
-	u.RawQuery = q.Encode()
-	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.String(), nil)
-	resp, err := (&http.Client{Timeout: timeout}).Do(req)
-	if err != nil {
-		return fmt.Errorf("request failed: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("service error: %s: %s", resp.Status, string(b))
-	}
-	_, err = io.Copy(cmd.OutOrStdout(), resp.Body)
-	return err
-}
-// API server service proxy (no port-forward; requires RBAC to get services/proxy)
-func fetchViaAPIServerProxy(cmd *cobra.Command, format string, color bool, limit int, timeout time.Duration) error {
-	// Pick up the same --context flag your root command defines
-	ctxFlag, _ := cmd.InheritedFlags().GetString("context")
-	// Load kubeconfig with overrides (so --context is honored)
-	loading := clientcmd.NewDefaultClientConfigLoadingRules()
-	overrides := &clientcmd.ConfigOverrides{}
-	if ctxFlag != "" {
-	  overrides.CurrentContext = ctxFlag
-	}
-	cfg, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loading, overrides).ClientConfig()
-	if err != nil {
-	  return fmt.Errorf("kubeconfig load failed: %w", err)
-	}
-	// Authenticated transport to the API server
-	transport, err := rest.TransportFor(cfg)
-	if err != nil {
-	  return fmt.Errorf("transport build failed: %w", err)
-	}
-	host := strings.TrimRight(cfg.Host, "/")
-	ns := "gpu-availability"
-	svc := "gpu-viewer"
-	scheme := "http"
-	portName := "http"
-	// proxy URL that WORKS in your cluster:
-	proxyURL := fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s:%s/proxy/",
-	  host, ns, scheme, svc, portName,
-	)
-	// Query params
-	u, _ := url.Parse(proxyURL)
-	q := u.Query()
-	switch strings.ToLower(format) {
-	case "oneline":
-	  q.Set("format", "oneline")
-	  if limit > 0 {
-		q.Set("limit", strconv.Itoa(limit))
-	  }
-	case "table", "":
-	  // default
-	default:
-	  return fmt.Errorf("unknown format %q (use table or oneline)", format)
-	}
-	if color {
-	  q.Set("color", "1")
-	}
-	u.RawQuery = q.Encode()
-	// Do request via API server proxy
-	req, _ := http.NewRequestWithContext(context.Background(), http.MethodGet, u.String(), nil)
-	client := &http.Client{Transport: transport, Timeout: timeout}
-	resp, err := client.Do(req)
-	if err != nil {
-	  return fmt.Errorf("proxy request failed: %w", err)
-	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-	  b, _ := io.ReadAll(resp.Body)
-	  return fmt.Errorf("service/proxy error: %s: %s", resp.Status, string(b))
-	}
-	_, err = io.Copy(cmd.OutOrStdout(), resp.Body)
-	return err
-  }
\ No newline at end of file
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
+	"github.com/uitml/frink/internal/k8s"
+)
+
+// ansiClear moves the cursor to the top left corner and clears the screen.
+const ansiClear = "\x1b[H\x1b[2J"
+
+// ANSI escape sequences used to highlight output.
+// The color sequences have equal lengths, so that tabwriter aligns colored and uncolored columns alike.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiDefault = "\x1b[39m"
+)
+
+type gpuContext struct {
+	cli.CommandContext
+	ServiceURL string        // URL to the gpu-viewer service; if empty, use API server proxy
+	Source     string        // "auto", "service", or "native"
+	Output     string        // "table", "oneline", "json", or "yaml"
+	Color      string        // color mode: auto, always, or never
+	Limit      int           // only used for oneline
+	Timeout    time.Duration // HTTP timeout
+
+	Type      string        // only show nodes whose GPU product contains this, ignoring case
+	Watch     bool          // refresh availability at an interval
+	Interval  time.Duration // time between refreshes when watching
+	UntilFree int64         // when watching, exit once a single node has this many free GPUs
+}
+
+func newGPUCmd() *cobra.Command {
+	ctx := &gpuContext{
+		Timeout: 5 * time.Second,
+	}
+	cmd := &cobra.Command{
+		Use:   "gpu",
+		Short: "Show cluster GPU availability",
+		Long: `Show cluster GPU availability.
+
+With --source service, availability is fetched as JSON from the gpu-viewer service, either directly
+via --service-url or through the API server service proxy. With --source native, it is computed from
+the allocatable GPUs of each node and the GPU requests of the non-terminated pods scheduled on it.
+The default, auto, uses the service when it is reachable and falls back to native otherwise.
+
+The table, as well as JSON and YAML output, first summarizes each GPU product (as given by the
+nvidia.com/gpu.product node label): its nodes, free and total GPUs, and the most free GPUs on a single
+node. The nodes are listed after that.
+
+With --watch, availability is refreshed every --interval, and changes in the number of free GPUs
+since the previous refresh are highlighted. With --until-free N, frink keeps watching until a single
+node has N free GPUs of the product selected by --type (e.g. "a100"), then rings the terminal bell
+and exits; this can be used to trigger actions, e.g. "frink gpu --until-free 4 --type a100 && frink run job.yaml".`,
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&ctx.ServiceURL, "service-url", "", "GPU viewer service URL (env GPU_VIEWER_URL or API proxy if empty)")
+	flags.StringVar(&ctx.Source, "source", "", "where to get availability from: auto|service|native")
+	flags.StringVarP(&ctx.Output, "output", "o", "", "output format: table|oneline|json|yaml")
+	flags.StringVar(&ctx.Output, "format", "", "output format: table|oneline")
+	flags.MarkDeprecated("format", "use --output instead")
+	flags.StringVar(&ctx.Color, "color", "auto", "Use ANSI colors: auto|always|never")
+	flags.Lookup("color").NoOptDefVal = "always"
+	flags.IntVar(&ctx.Limit, "limit", 0, "Limit number of nodes (only for --output=oneline)")
+	flags.DurationVar(&ctx.Timeout, "timeout", 5*time.Second, "HTTP request timeout")
+	flags.StringVar(&ctx.Type, "type", "", "only show GPU products containing this, e.g. a100")
+	flags.BoolVarP(&ctx.Watch, "watch", "w", false, "refresh availability at an interval")
+	flags.DurationVar(&ctx.Interval, "interval", 5*time.Second, "time between refreshes when watching")
+	flags.Int64Var(&ctx.UntilFree, "until-free", 0, "watch until a single node has this many free GPUs, then exit")
+
+	return cmd
+}
+
+func (ctx *gpuContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	// The color flag is bound to the color setting, so the configuration holds the effective mode.
+	ctx.Color = ctx.Config.Color
+	ctx.ServiceURL = gpuServiceURL(ctx.ServiceURL, ctx.Config)
+	if ctx.Source == "" {
+		ctx.Source = ctx.Config.GPU.Source
+	}
+	if ctx.Output == "" {
+		ctx.Output = ctx.Config.Output
+	}
+
+	return nil
+}
+
+func (ctx *gpuContext) Run(cmd *cobra.Command, args []string) error {
+	switch ctx.Output {
+	case "", "table", "oneline", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q (use table, oneline, json or yaml)", ctx.Output)
+	}
+
+	// Only report falling back once, rather than on every refresh when watching.
+	reported := false
+	source, err := newGPUSource(&ctx.CommandContext, ctx.Source, ctx.ServiceURL, ctx.Timeout, func(err error) {
+		if !reported {
+			fmt.Fprintf(ctx.Err, "GPU viewer unavailable (%v); computing availability from the cluster\n", err)
+			reported = true
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	if !ctx.Watch && ctx.UntilFree == 0 {
+		availability, err := ctx.availability(source)
+		if err != nil {
+			return err
+		}
+
+		color := cli.ColorEnabled(ctx.Color, cmd.OutOrStdout())
+
+		return renderGPUAvailability(cmd.OutOrStdout(), availability, nil, ctx.Output, color, ctx.Limit)
+	}
+
+	return ctx.watch(cmd, source)
+}
+
+// availability returns the availability of the nodes with the selected GPU product.
+func (ctx *gpuContext) availability(source gpu.Source) (*gpu.Availability, error) {
+	availability, err := source.Availability(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return availability.Matching(ctx.Type), nil
+}
+
+// watch renders the availability every interval, until UntilFree GPUs are free on a single node, if set.
+// Tables are redrawn in place on terminals, while other output formats, and output to non-terminals,
+// are appended to the previous output.
+func (ctx *gpuContext) watch(cmd *cobra.Command, source gpu.Source) error {
+	out := cmd.OutOrStdout()
+	color := cli.ColorEnabled(ctx.Color, out)
+	redraw := cli.IsTerminal(out) && (ctx.Output == "" || ctx.Output == "table")
+
+	interval := ctx.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	var previous *gpu.Availability
+	for {
+		availability, err := ctx.availability(source)
+		if err != nil {
+			return err
+		}
+
+		if redraw {
+			fmt.Fprint(out, ansiClear)
+			fmt.Fprintf(out, "Every %s; updated %s\n\n", interval, time.Now().Format("15:04:05"))
+		}
+		if err := renderGPUAvailability(out, availability, previous, ctx.Output, color, ctx.Limit); err != nil {
+			return err
+		}
+
+		if ctx.UntilFree > 0 && availability.MaxFree() >= ctx.UntilFree {
+			description := "GPUs"
+			if ctx.Type != "" {
+				description = ctx.Type + " GPUs"
+			}
+			fmt.Fprintf(ctx.Err, "\a%d free %s available on a single node\n", ctx.UntilFree, description)
+			return nil
+		}
+
+		previous = availability
+		time.Sleep(interval)
+	}
+}
+
+// gpuServiceURL returns the URL of the gpu-viewer service, with the flag value taking precedence
+// over the GPU_VIEWER_URL environment variable and the configuration.
+func gpuServiceURL(flag string, cfg *cli.Config) string {
+	if flag != "" {
+		return flag
+	}
+	if url := os.Getenv("GPU_VIEWER_URL"); url != "" {
+		return url
+	}
+	if cfg != nil {
+		return cfg.GPU.URL
+	}
+
+	return ""
+}
+
+// newGPUSource returns the GPU availability source identified by source; see gpuContext.
+// With the auto source, onFallback is called when the service cannot be used.
+func newGPUSource(ctx *cli.CommandContext, source, serviceURL string, timeout time.Duration, onFallback func(error)) (gpu.Source, error) {
+	native := &gpu.ClusterSource{Client: ctx.Client}
+
+	switch source {
+	case "native":
+		return native, nil
+	case "service":
+		// Return an untyped nil on error, since a nil *gpu.ServiceClient would be a non-nil gpu.Source.
+		service, err := newGPUServiceClient(ctx.Config, serviceURL, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return service, nil
+	case "", "auto":
+		service, err := newGPUServiceClient(ctx.Config, serviceURL, timeout)
+		if err != nil {
+			if onFallback != nil {
+				onFallback(err)
+			}
+			return native, nil
+		}
+
+		return &gpu.FallbackSource{Primary: service, Secondary: native, OnFallback: onFallback}, nil
+	}
+
+	return nil, fmt.Errorf("unknown source %q (use auto, service or native)", source)
+}
+
+// newGPUServiceClient returns a client that reaches the gpu-viewer service directly if serviceURL is set
+// (e.g. via port-forward or ingress), and otherwise via the API server service proxy.
+func newGPUServiceClient(cfg *cli.Config, serviceURL string, timeout time.Duration) (*gpu.ServiceClient, error) {
+	if serviceURL != "" {
+		return gpu.NewServiceClient(serviceURL, timeout), nil
+	}
+
+	if cfg == nil {
+		cfg = cli.DefaultConfig()
+	}
+
+	config, _, err := k8s.NewRESTConfig(cfg.Context, "")
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig load failed: %w", err)
+	}
+
+	opts := gpu.ProxyOptions{
+		Namespace: cfg.GPU.Namespace,
+		Service:   cfg.GPU.Service,
+		Scheme:    cfg.GPU.Scheme,
+		Port:      cfg.GPU.Port,
+	}
+
+	return gpu.NewProxyServiceClient(config, opts, timeout)
+}
+
+// gpuReport is the structured output of the gpu command: the availability grouped by GPU product, and per node.
+type gpuReport struct {
+	Products []gpu.Product `json:"products"`
+	Nodes    []gpu.Node    `json:"nodes"`
+}
+
+// renderGPUAvailability writes availability to w in the given output format.
+// Tables and structured output summarize the availability of each GPU product before listing the nodes.
+// If previous is not nil, changes in the number of free GPUs since then are highlighted.
+func renderGPUAvailability(w io.Writer, availability, previous *gpu.Availability, output string, color bool, limit int) error {
+	previousNodes := map[string]gpu.Node{}
+	if previous != nil {
+		for _, node := range previous.Nodes {
+			previousNodes[node.Name] = node
+		}
+	}
+
+	switch output {
+	case "json", "yaml":
+		return printStructured(w, output, gpuReport{Products: availability.Products(), Nodes: availability.Nodes})
+	case "oneline":
+		fmt.Fprintln(w, gpuOneline(availability, previousNodes, color, limit))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, gpuProductHeader(color))
+	for _, product := range availability.Products() {
+		fmt.Fprintln(tw, gpuProductRow(product, color))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+
+	tw = tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, gpuHeader(color))
+	for _, node := range availability.Nodes {
+		fmt.Fprintln(tw, gpuRow(node, previousNodes, color))
+	}
+
+	return nil
+}
+
+func gpuProductHeader(color bool) string {
+	free := "FREE/TOTAL"
+	if color {
+		free = ansiDefault + free + ansiReset
+	}
+
+	columnNames := []string{
+		"PRODUCT",
+		"NODES",
+		free,
+		"MAX FREE",
+		"MEMORY",
+	}
+
+	return strings.Join(columnNames, "\t") + "\t"
+}
+
+func gpuProductRow(product gpu.Product, color bool) string {
+	free := fmt.Sprintf("%d/%d", product.Free, product.Total)
+	if color {
+		if product.Free > 0 {
+			free = ansiGreen + free + ansiReset
+		} else {
+			free = ansiRed + free + ansiReset
+		}
+	}
+
+	columns := []string{
+		product.Name,
+		fmt.Sprint(product.Nodes),
+		free,
+		fmt.Sprint(product.MaxFree),
+		gpuMemory(product.MemoryMiB),
+	}
+
+	return strings.Join(columns, "\t") + "\t"
+}
+
+func gpuHeader(color bool) string {
+	free := "FREE/TOTAL"
+	if color {
+		free = ansiDefault + free + ansiReset
+	}
+
+	columnNames := []string{
+		"NODE",
+		"PRODUCT",
+		free,
+		"MEMORY",
+		"NAMESPACES",
+	}
+
+	return strings.Join(columnNames, "\t") + "\t"
+}
+
+func gpuRow(node gpu.Node, previous map[string]gpu.Node, color bool) string {
+	columns := []string{
+		node.Name,
+		node.Product,
+		gpuFree(node, previous, color),
+		gpuMemory(node.MemoryMiB),
+		gpuNamespaces(node.Namespaces),
+	}
+
+	return strings.Join(columns, "\t") + "\t"
+}
+
+// gpuFree returns the number of free and total GPUs of the node, along with the change in free GPUs
+// since the previous state of the node, if any.
+func gpuFree(node gpu.Node, previous map[string]gpu.Node, color bool) string {
+	free := fmt.Sprintf("%d/%d", node.Free, node.Total)
+	if node.Unschedulable {
+		free += " (unschedulable)"
+	}
+
+	prev, ok := previous[node.Name]
+	changed := ok && prev.Free != node.Free
+	if changed {
+		free += fmt.Sprintf(" (%+d)", node.Free-prev.Free)
+	}
+
+	if !color {
+		return free
+	}
+
+	if changed {
+		return ansiYellow + free + ansiReset
+	}
+
+	if node.Free > 0 {
+		return ansiGreen + free + ansiReset
+	}
+
+	return ansiRed + free + ansiReset
+}
+
+func gpuMemory(mib int64) string {
+	if mib == 0 {
+		return "-"
+	}
+
+	return humanize.IBytes(uint64(mib) * 1024 * 1024)
+}
+
+func gpuNamespaces(namespaces map[string]int64) string {
+	if len(namespaces) == 0 {
+		return "-"
+	}
+
+	names := make([]string, 0, len(namespaces))
+	for ns := range namespaces {
+		names = append(names, ns)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, 0, len(names))
+	for _, ns := range names {
+		parts = append(parts, fmt.Sprintf("%s(%d)", ns, namespaces[ns]))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// gpuOneline returns a single line summary of the nodes, ordered by the number of free GPUs.
+func gpuOneline(availability *gpu.Availability, previous map[string]gpu.Node, color bool, limit int) string {
+	nodes := append([]gpu.Node{}, availability.Nodes...)
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].Free > nodes[j].Free })
+	if limit > 0 && len(nodes) > limit {
+		nodes = nodes[:limit]
+	}
+
+	parts := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		parts = append(parts, fmt.Sprintf("%s[%s]:%s", node.Name, node.Product, gpuFree(node, previous, color)))
+	}
+
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
+	"github.com/uitml/frink/internal/k8s/fake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var gpuNode = corev1.Node{
+	ObjectMeta: metav1.ObjectMeta{
+		Name:   "node-1",
+		Labels: map[string]string{gpu.ProductLabel: "A100", gpu.MemoryLabel: "40960"},
+	},
+	Status: corev1.NodeStatus{
+		Allocatable: corev1.ResourceList{gpu.ResourceName: resource.MustParse("8")},
+	},
+}
+
+func newNativeGPUContext(client *fake.Client, output string) *gpuContext {
+	return &gpuContext{
+		CommandContext: cli.CommandContext{
+			Out:    &strings.Builder{},
+			Err:    &strings.Builder{},
+			Client: client,
+		},
+		Source: "native",
+		Output: output,
+		Color:  "never",
+	}
+}
+
+func TestGPURunNativeTable(t *testing.T) {
+	var out strings.Builder
+	cmd := newGPUCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListNodes").Return([]corev1.Node{gpuNode}, nil)
+	client.On("ListActivePods").Return([]corev1.Pod{}, nil)
+
+	ctx := newNativeGPUContext(client, "table")
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "FREE/TOTAL")
+	assert.Contains(t, out.String(), "8/8")
+	assert.Contains(t, out.String(), "40 GiB")
+
+	client.AssertExpectations(t)
+}
+
+func TestGPURunNativeJSON(t *testing.T) {
+	var out strings.Builder
+	cmd := newGPUCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListNodes").Return([]corev1.Node{gpuNode}, nil)
+	client.On("ListActivePods").Return([]corev1.Pod{}, nil)
+
+	ctx := newNativeGPUContext(client, "json")
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), `"free": 8`)
+
+	client.AssertExpectations(t)
+}
+
+func TestGPURenderProducts(t *testing.T) {
+	availability := &gpu.Availability{Nodes: []gpu.Node{
+		{Name: "a", Product: "A100", Total: 8, Free: 2},
+		{Name: "b", Product: "A100", Total: 8, Free: 6},
+		{Name: "c", Product: "V100", Total: 4, Free: 0},
+	}}
+
+	var out strings.Builder
+	err := renderGPUAvailability(&out, availability, nil, "table", false, 0)
+	assert.NoError(t, err)
+
+	lines := strings.Split(out.String(), "\n")
+	assert.Regexp(t, `^PRODUCT\s+NODES\s+FREE/TOTAL\s+MAX FREE\s+MEMORY`, lines[0])
+	assert.Regexp(t, `^A100\s+2\s+8/16\s+6\s+-`, lines[1])
+	assert.Regexp(t, `^V100\s+1\s+0/4\s+0\s+-`, lines[2])
+	assert.Equal(t, "", lines[3])
+	assert.Regexp(t, `^NODE\s+PRODUCT`, lines[4])
+
+	out.Reset()
+	err = renderGPUAvailability(&out, availability, nil, "json", false, 0)
+	assert.NoError(t, err)
+
+	var report gpuReport
+	assert.NoError(t, json.Unmarshal([]byte(out.String()), &report))
+	if assert.Len(t, report.Products, 2) {
+		assert.Equal(t, gpu.Product{Name: "A100", Nodes: 2, Total: 16, Free: 8, MaxFree: 6}, report.Products[0])
+	}
+	assert.Len(t, report.Nodes, 3)
+}
+
+func TestGPURunNativeBrokenClient(t *testing.T) {
+	cmd := newGPUCmd()
+
+	client := &fake.Client{}
+	client.On("ListNodes").Return(nil, errors.New("forbidden"))
+
+	ctx := newNativeGPUContext(client, "table")
+	err := ctx.Run(cmd, []string{})
+	assert.EqualError(t, err, "unable to list nodes: forbidden")
+}
+
+func TestGPUNamespaces(t *testing.T) {
+	assert.Equal(t, "-", gpuNamespaces(nil))
+	assert.Equal(t, "a(1),b(2)", gpuNamespaces(map[string]int64{"b": 2, "a": 1}))
+}
+
+func TestGPURunService(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		fmt.Fprint(w, `{"nodes": [{"name": "node-2", "product": "V100", "total": 4, "used": 1, "free": 3}]}`)
+	}))
+	defer server.Close()
+
+	var out strings.Builder
+	cmd := newGPUCmd()
+	cmd.SetOut(&out)
+
+	ctx := newNativeGPUContext(&fake.Client{}, "oneline")
+	ctx.Source = "service"
+	ctx.ServiceURL = server.URL
+	ctx.Timeout = time.Second
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "node-2[V100]:3/4\n", out.String())
+}
+
+func TestGPURunUntilFree(t *testing.T) {
+	var out strings.Builder
+	cmd := newGPUCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListNodes").Return([]corev1.Node{gpuNode}, nil)
+	client.On("ListActivePods").Return([]corev1.Pod{}, nil)
+
+	ctx := newNativeGPUContext(client, "oneline")
+	ctx.UntilFree = 4
+	ctx.Type = "a100"
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, "node-1[A100]:8/8\n", out.String())
+	assert.Contains(t, ctx.Err.(*strings.Builder).String(), "4 free a100 GPUs available")
+}
+
+func TestGPUFreeChanged(t *testing.T) {
+	node := gpu.Node{Name: "a", Total: 8, Free: 3}
+	previous := map[string]gpu.Node{"a": {Name: "a", Total: 8, Free: 5}}
+
+	assert.Equal(t, "3/8", gpuFree(node, nil, false))
+	assert.Equal(t, "3/8 (-2)", gpuFree(node, previous, false))
+	assert.Equal(t, ansiYellow+"3/8 (-2)"+ansiReset, gpuFree(node, previous, true))
+}
+
+func TestNewGPUSourceServiceError(t *testing.T) {
+	ctx := &cli.CommandContext{Config: &cli.Config{Context: "does-not-exist"}}
+
+	source, err := newGPUSource(ctx, "service", "", time.Second, nil)
+	assert.Error(t, err)
+	assert.True(t, source == nil, "source must be an untyped nil, got %#v", source)
+}
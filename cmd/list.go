@@ -1,7 +1,13 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"regexp"
+	"sort"
 	"strings"
 	"text/tabwriter"
 	"time"
@@ -10,13 +16,56 @@ import (
 	"github.com/hako/durafmt"
 	"github.com/spf13/cobra"
 	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
+	"github.com/uitml/frink/internal/k8s"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 type listContext struct {
 	cli.CommandContext
 
-	ShowAll bool
+	ShowAll   bool
+	Cron      bool
+	Watch     bool
+	WatchPods bool
+	Output    string
+
+	SortBy    string
+	Reverse   bool
+	Statuses  []string
+	Since     string
+	NameRegex string
+	Limit     int
+
+	// HideFinishedAfter is how long finished jobs are listed after they finished, unless ShowAll is set.
+	// Zero lists finished jobs until they are deleted.
+	HideFinishedAfter time.Duration
+
+	// RedrawInterval is the time between redrawing the table in watch mode, so that durations keep ticking.
+	RedrawInterval time.Duration
+}
+
+// jobSummary is the representation of a job used by the structured output formats.
+type jobSummary struct {
+	Name           string     `json:"name"`
+	Status         string     `json:"status"`
+	Completions    string     `json:"completions"`
+	Attempt        int32      `json:"attempt"`
+	MaxAttempts    int32      `json:"maxAttempts"`
+	StartTime      *time.Time `json:"startTime,omitempty"`
+	CompletionTime *time.Time `json:"completionTime,omitempty"`
+	Duration       string     `json:"duration"`
+}
+
+// cronJobSummary is the representation of a cron job used by the structured output formats.
+type cronJobSummary struct {
+	Name             string     `json:"name"`
+	Schedule         string     `json:"schedule"`
+	Suspended        bool       `json:"suspended"`
+	Active           int        `json:"active"`
+	LastScheduleTime *time.Time `json:"lastScheduleTime,omitempty"`
 }
 
 func newListCmd() *cobra.Command {
@@ -24,43 +73,520 @@ func newListCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "ls",
 		Short: "List jobs",
+		Long: `List jobs.
+
+Jobs that finished more than a day ago are hidden, unless --all is given; the time can be changed via the
+list.hideFinishedAfter setting, where an empty value shows all jobs. Jobs can be further filtered by
+--status, --since (their age, e.g. 24h or 7d), and --name-regex, and are sorted by --sort-by, where age
+lists the youngest jobs first, and the other keys list the smallest values first. The age of a job, as shown
+in the AGE column, is how long ago it started, or was created if it has not started yet.
+With --limit, only the first jobs after sorting are listed.
+
+With --watch, jobs are watched until interrupted. On a terminal, the table of selected jobs is redrawn
+in place as jobs change, and every second so that durations keep ticking. Otherwise, a line is printed for each
+status transition of a selected job, e.g. "exp-3 Active → Failed (BackoffLimitExceeded)", which is suitable
+for piping into other programs. With --pods, pods are watched as well, so that transitions to Failed show why
+the pods of the job failed, such as OOMKilled.`,
 
 		PreRunE: ctx.PreRun,
 		RunE:    ctx.Run,
 	}
 
 	flags := cmd.Flags()
-	flags.BoolVarP(&ctx.ShowAll, "all", "a", false, "show all jobs; active and terminated")
+	flags.BoolVarP(&ctx.ShowAll, "all", "a", false, "show all jobs, including jobs that finished long ago")
+	flags.StringVar(&ctx.SortBy, "sort-by", "", "sort jobs by age|name|status|duration|gpu")
+	flags.BoolVar(&ctx.Reverse, "reverse", false, "reverse the sort order")
+	flags.StringSliceVar(&ctx.Statuses, "status", nil, "only show jobs with these statuses: active|succeeded|failed|stopped|suspended")
+	flags.StringVar(&ctx.Since, "since", "", "only show jobs younger than this duration, e.g. 24h")
+	flags.StringVar(&ctx.NameRegex, "name-regex", "", "only show jobs whose names match this regular expression")
+	flags.IntVar(&ctx.Limit, "limit", 0, "show at most this many jobs; 0 shows all")
+	flags.BoolVar(&ctx.Cron, "cron", false, "list cron jobs instead of jobs")
+	flags.BoolVarP(&ctx.Watch, "watch", "w", false, "watch jobs, updating the table or printing status transitions")
+	flags.BoolVar(&ctx.WatchPods, "pods", false, "also watch pods with --watch, to show why jobs failed")
+	flags.StringVarP(&ctx.Output, "output", "o", "", "output format: table|json|yaml")
 
 	return cmd
 }
 
 func (ctx *listContext) PreRun(cmd *cobra.Command, args []string) error {
-	return ctx.Initialize(cmd)
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	if ctx.Output == "" {
+		ctx.Output = ctx.Config.Output
+	}
+	ctx.HideFinishedAfter = ctx.Config.List.HideFinished()
+
+	return nil
 }
 
 func (ctx *listContext) Run(cmd *cobra.Command, args []string) error {
+	if ctx.Cron {
+		return ctx.listCronJobs(cmd)
+	}
+
+	selection, err := ctx.selection()
+	if err != nil {
+		return err
+	}
+
+	if ctx.Watch {
+		if ctx.Output != "" && ctx.Output != "table" {
+			return fmt.Errorf("--watch only supports table output")
+		}
+
+		c, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		out := cmd.OutOrStdout()
+		return ctx.watchJobs(c, out, cli.IsTerminal(out), selection)
+	}
+
 	jobs, err := ctx.Client.ListJobs()
 	if err != nil {
 		return fmt.Errorf("could not list jobs: %w", err)
 	}
+	jobs = selection.apply(jobs, time.Now())
 
-	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
-	defer w.Flush()
+	switch ctx.Output {
+	case "", "table":
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		defer w.Flush()
 
-	fmt.Fprintln(w, header())
-	for _, job := range jobs {
-		fmt.Fprintln(w, row(job))
+		fmt.Fprintln(w, header())
+		for _, job := range jobs {
+			fmt.Fprintln(w, row(job))
+		}
+	case "json", "yaml":
+		summaries := make([]jobSummary, 0, len(jobs))
+		for _, job := range jobs {
+			summaries = append(summaries, summary(job))
+		}
+
+		return printStructured(cmd.OutOrStdout(), ctx.Output, summaries)
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", ctx.Output)
 	}
 
 	return nil
 }
 
+func (ctx *listContext) listCronJobs(cmd *cobra.Command) error {
+	cronJobs, err := ctx.Client.ListCronJobs()
+	if err != nil {
+		return fmt.Errorf("could not list cron jobs: %w", err)
+	}
+
+	summaries := make([]cronJobSummary, 0, len(cronJobs))
+	for _, cronJob := range cronJobs {
+		s := cronJobSummary{
+			Name:      cronJob.Name,
+			Schedule:  cronJob.Spec.Schedule,
+			Suspended: cronJob.Spec.Suspend != nil && *cronJob.Spec.Suspend,
+			Active:    len(cronJob.Status.Active),
+		}
+		if cronJob.Status.LastScheduleTime != nil {
+			s.LastScheduleTime = &cronJob.Status.LastScheduleTime.Time
+		}
+		summaries = append(summaries, s)
+	}
+
+	switch ctx.Output {
+	case "", "table":
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		defer w.Flush()
+
+		fmt.Fprintln(w, "NAME\tSCHEDULE\tSUSPENDED\tACTIVE\tLAST SCHEDULE\t")
+		for _, s := range summaries {
+			last := "-"
+			if s.LastScheduleTime != nil {
+				last = humanize.Time(*s.LastScheduleTime)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t\n", s.Name, s.Schedule, s.Suspended, s.Active, last)
+		}
+	case "json", "yaml":
+		return printStructured(cmd.OutOrStdout(), ctx.Output, summaries)
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", ctx.Output)
+	}
+
+	return nil
+}
+
+// jobSelection filters, sorts, and limits the jobs listed, as described by the flags of ls.
+// The zero value selects all jobs, in the order they are given.
+type jobSelection struct {
+	statuses  map[string]bool
+	since     time.Duration
+	name      *regexp.Regexp
+	hideAfter time.Duration
+	sortBy    string
+	reverse   bool
+	limit     int
+}
+
+// sortKeys are the valid values of --sort-by.
+var sortKeys = []string{"age", "name", "status", "duration", "gpu"}
+
+// selection returns the job selection described by the flags.
+func (ctx *listContext) selection() (*jobSelection, error) {
+	s := &jobSelection{sortBy: ctx.SortBy, reverse: ctx.Reverse, limit: ctx.Limit}
+	if !ctx.ShowAll {
+		s.hideAfter = ctx.HideFinishedAfter
+	}
+
+	if s.sortBy != "" && !containsName(sortKeys, s.sortBy) {
+		return nil, fmt.Errorf("invalid sort key %q: must be one of %v", s.sortBy, sortKeys)
+	}
+
+	if s.limit < 0 {
+		return nil, fmt.Errorf("--limit must not be negative")
+	}
+
+	if len(ctx.Statuses) > 0 {
+		s.statuses = map[string]bool{}
+		for _, value := range ctx.Statuses {
+			status, err := parseStatus(value)
+			if err != nil {
+				return nil, err
+			}
+			s.statuses[status] = true
+		}
+	}
+
+	if ctx.Since != "" {
+		since, err := k8s.ParseDuration(ctx.Since)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --since: %w", err)
+		}
+		s.since = since
+	}
+
+	if ctx.NameRegex != "" {
+		name, err := regexp.Compile(ctx.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --name-regex: %w", err)
+		}
+		s.name = name
+	}
+
+	return s, nil
+}
+
+// matches reports whether the job passes the filters of the selection, as of now.
+// Sorting and the limit only apply to lists of jobs; see apply.
+func (s *jobSelection) matches(job batchv1.Job, now time.Time) bool {
+	if s.statuses != nil && !s.statuses[status(job)] {
+		return false
+	}
+	if s.since > 0 && startTime(job).Before(now.Add(-s.since)) {
+		return false
+	}
+	if s.name != nil && !s.name.MatchString(job.Name) {
+		return false
+	}
+	if finished, ok := k8s.JobFinishTime(job); ok && s.hideAfter > 0 && finished.Before(now.Add(-s.hideAfter)) {
+		return false
+	}
+
+	return true
+}
+
+// apply returns the selected jobs, as of now.
+func (s *jobSelection) apply(jobs []batchv1.Job, now time.Time) []batchv1.Job {
+	selected := make([]batchv1.Job, 0, len(jobs))
+	for _, job := range jobs {
+		if s.matches(job, now) {
+			selected = append(selected, job)
+		}
+	}
+
+	if less := jobOrder(s.sortBy); less != nil {
+		sort.SliceStable(selected, func(i, j int) bool {
+			if s.reverse {
+				return less(selected[j], selected[i])
+			}
+			return less(selected[i], selected[j])
+		})
+	} else if s.reverse {
+		for i, j := 0, len(selected)-1; i < j; i, j = i+1, j-1 {
+			selected[i], selected[j] = selected[j], selected[i]
+		}
+	}
+
+	if s.limit > 0 && len(selected) > s.limit {
+		selected = selected[:s.limit]
+	}
+
+	return selected
+}
+
+// jobOrder returns the ordering of jobs described by the sort key, with ties ordered by name,
+// or nil if the key is empty.
+func jobOrder(key string) func(a, b batchv1.Job) bool {
+	var compare func(a, b batchv1.Job) int
+	switch key {
+	case "age":
+		// Newer jobs are younger, so they come first.
+		compare = func(a, b batchv1.Job) int { return -compareTimes(startTime(a), startTime(b)) }
+	case "name":
+		compare = func(a, b batchv1.Job) int { return 0 }
+	case "status":
+		compare = func(a, b batchv1.Job) int { return strings.Compare(status(a), status(b)) }
+	case "duration":
+		compare = func(a, b batchv1.Job) int {
+			_, da := timing(a)
+			_, db := timing(b)
+			return compareInts(int64(da), int64(db))
+		}
+	case "gpu":
+		compare = func(a, b batchv1.Job) int { return compareInts(jobGPUs(a), jobGPUs(b)) }
+	default:
+		return nil
+	}
+
+	return func(a, b batchv1.Job) bool {
+		if c := compare(a, b); c != 0 {
+			return c < 0
+		}
+		return a.Name < b.Name
+	}
+}
+
+// jobGPUs returns the number of GPUs requested by the pods of the job that may run in parallel.
+func jobGPUs(job batchv1.Job) int64 {
+	perPod, _ := gpu.PodSpecRequest(job.Spec.Template.Spec)
+	if job.Spec.Parallelism != nil {
+		return perPod * int64(*job.Spec.Parallelism)
+	}
+
+	return perPod
+}
+
+func compareTimes(a, b time.Time) int {
+	switch {
+	case a.Before(b):
+		return -1
+	case a.After(b):
+		return 1
+	}
+
+	return 0
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+
+	return 0
+}
+
+// jobWatch holds the jobs seen by watchJobs, along with the latest pod failure reason of each job.
+type jobWatch struct {
+	jobs    map[string]batchv1.Job
+	reasons map[string]string
+
+	// selection selects the jobs shown in the table, and whose transitions are printed.
+	selection *jobSelection
+}
+
+// watchJobs watches jobs until c is done. If tty is set, the table of the jobs in the selection is redrawn on every
+// change and every RedrawInterval; otherwise, status transitions of those jobs are printed. Jobs are listed again
+// whenever the watch ends, so that transitions are not missed when the API server closes the watch.
+func (ctx *listContext) watchJobs(c context.Context, w io.Writer, tty bool, selection *jobSelection) error {
+	interval := ctx.RedrawInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var redraw <-chan time.Time
+	if tty {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		redraw = ticker.C
+	}
+
+	state := &jobWatch{jobs: map[string]batchv1.Job{}, reasons: map[string]string{}, selection: selection}
+	for initial := true; ; initial = false {
+		jobs, err := ctx.Client.ListJobs()
+		if err != nil {
+			return fmt.Errorf("could not list jobs: %w", err)
+		}
+
+		listed := map[string]bool{}
+		for _, job := range jobs {
+			listed[job.Name] = true
+			state.update(w, job, !tty && !initial)
+		}
+		for name := range state.jobs {
+			if !listed[name] {
+				state.remove(w, name, !tty)
+			}
+		}
+		if tty {
+			state.draw(w)
+		}
+
+		done, err := ctx.watchOnce(c, w, tty, state, redraw)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// watchOnce processes events until c is done, returning true, or the watch ends.
+func (ctx *listContext) watchOnce(c context.Context, w io.Writer, tty bool, state *jobWatch, redraw <-chan time.Time) (bool, error) {
+	jobWatcher, err := ctx.Client.WatchJobs()
+	if err != nil {
+		return false, fmt.Errorf("could not watch jobs: %w", err)
+	}
+	defer jobWatcher.Stop()
+
+	var podEvents <-chan watch.Event
+	if ctx.WatchPods {
+		podWatcher, err := ctx.Client.WatchPods("job-name")
+		if err != nil {
+			return false, fmt.Errorf("could not watch pods: %w", err)
+		}
+		defer podWatcher.Stop()
+		podEvents = podWatcher.ResultChan()
+	}
+
+	for {
+		select {
+		case <-c.Done():
+			return true, nil
+		case <-redraw:
+			state.draw(w)
+		case event, ok := <-podEvents:
+			if !ok || event.Type == watch.Error {
+				return false, nil
+			}
+			if pod, ok := event.Object.(*corev1.Pod); ok {
+				if reason := k8s.PodFailureReason(*pod); reason != "" {
+					state.reasons[pod.Labels["job-name"]] = reason
+				}
+			}
+		case event, ok := <-jobWatcher.ResultChan():
+			if !ok || event.Type == watch.Error {
+				return false, nil
+			}
+			job, ok := event.Object.(*batchv1.Job)
+			if !ok {
+				continue
+			}
+
+			if event.Type == watch.Deleted {
+				state.remove(w, job.Name, !tty)
+			} else {
+				state.update(w, *job, !tty)
+			}
+			if tty {
+				state.draw(w)
+			}
+		}
+	}
+}
+
+// update records the job, printing its status transition, if any, if print is set and the job is selected.
+func (s *jobWatch) update(w io.Writer, job batchv1.Job, print bool) {
+	previous, seen := s.jobs[job.Name]
+	s.jobs[job.Name] = job
+	if !print || !s.selected(job) {
+		return
+	}
+
+	current := status(job)
+	switch {
+	case !seen:
+		fmt.Fprintf(w, "%s created (%s)\n", job.Name, current)
+	case status(previous) != current:
+		fmt.Fprintf(w, "%s %s → %s", job.Name, status(previous), current)
+		if reason := s.failureReason(job); current == k8s.StatusFailed && reason != "" {
+			fmt.Fprintf(w, " (%s)", reason)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// remove forgets the job, printing that it was deleted if print is set and the job was selected.
+func (s *jobWatch) remove(w io.Writer, name string, print bool) {
+	job, seen := s.jobs[name]
+	if !seen {
+		return
+	}
+
+	delete(s.jobs, name)
+	delete(s.reasons, name)
+	if print && s.selected(job) {
+		fmt.Fprintf(w, "%s deleted\n", name)
+	}
+}
+
+func (s *jobWatch) selected(job batchv1.Job) bool {
+	return s.selection == nil || s.selection.matches(job, time.Now())
+}
+
+// failureReason returns why the pods of the job failed, if known, and otherwise why the job failed.
+func (s *jobWatch) failureReason(job batchv1.Job) string {
+	if reason := s.reasons[job.Name]; reason != "" {
+		return reason
+	}
+
+	return k8s.JobFailureReason(job)
+}
+
+// draw clears the terminal, and writes the table of the selected jobs, sorted by name unless sorted otherwise.
+func (s *jobWatch) draw(w io.Writer) {
+	names := make([]string, 0, len(s.jobs))
+	for name := range s.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	jobs := make([]batchv1.Job, 0, len(names))
+	for _, name := range names {
+		jobs = append(jobs, s.jobs[name])
+	}
+
+	fmt.Fprint(w, "\033[H\033[2J")
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, header())
+	for _, job := range s.selection.apply(jobs, time.Now()) {
+		fmt.Fprintln(tw, row(job))
+	}
+	tw.Flush()
+}
+
+func summary(job batchv1.Job) jobSummary {
+	s := jobSummary{
+		Name:        job.Name,
+		Status:      status(job),
+		Completions: completions(job),
+		Duration:    duration(job),
+	}
+	s.Attempt, s.MaxAttempts = k8s.JobAttempts(job)
+	if job.Status.StartTime != nil {
+		s.StartTime = &job.Status.StartTime.Time
+	}
+	if job.Status.CompletionTime != nil {
+		s.CompletionTime = &job.Status.CompletionTime.Time
+	}
+
+	return s
+}
+
 func header() string {
 	columnNames := []string{
 		"NAME",
 		"STATUS",
 		"COMPLETIONS",
+		"ATTEMPT",
 		"DURATION",
 		"AGE",
 	}
@@ -73,6 +599,7 @@ func row(job batchv1.Job) string {
 		job.Name,
 		status(job),
 		completions(job),
+		attempts(job),
 		duration(job),
 		age(job),
 	}
@@ -81,16 +608,7 @@ func row(job batchv1.Job) string {
 }
 
 func status(job batchv1.Job) string {
-	switch {
-	case job.Status.Active > 0:
-		return "Active"
-	case job.Status.Failed > 0:
-		return "Failed"
-	case job.Spec.Completions == nil && job.Status.Succeeded > 0 || job.Spec.Completions != nil && *job.Spec.Completions == job.Status.Succeeded:
-		return "Succeeded"
-	}
-
-	return "Stopped"
+	return k8s.JobStatus(job)
 }
 
 func completions(job batchv1.Job) string {
@@ -100,6 +618,12 @@ func completions(job batchv1.Job) string {
 	return fmt.Sprintf("%d/%d", succeeded, total)
 }
 
+func attempts(job batchv1.Job) string {
+	attempt, max := k8s.JobAttempts(job)
+
+	return fmt.Sprintf("%d/%d", attempt, max)
+}
+
 func duration(job batchv1.Job) string {
 	_, duration := timing(job)
 	// TODO: Implement "smart" truncation scheme.
@@ -109,12 +633,21 @@ func duration(job batchv1.Job) string {
 }
 
 func age(job batchv1.Job) string {
-	start, _ := timing(job)
-	humanized := humanize.Time(start)
+	humanized := humanize.Time(startTime(job))
 
 	return humanized
 }
 
+// startTime returns when the job started, or when it was created if it has not started yet, e.g. while suspended.
+// The age of jobs is based on it, both when shown and when filtering with --since or sorting by age.
+func startTime(job batchv1.Job) time.Time {
+	if job.Status.StartTime != nil {
+		return job.Status.StartTime.Time
+	}
+
+	return job.CreationTimestamp.Time
+}
+
 func timing(job batchv1.Job) (time.Time, time.Duration) {
 	var start time.Time
 	duration := time.Duration(0)
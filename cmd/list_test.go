@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"strings"
 	"testing"
@@ -9,8 +10,12 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/uitml/frink/internal/cli"
 	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/util"
 	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
 )
 
 var (
@@ -190,6 +195,22 @@ func TestComplectionsSuccessfulJob(t *testing.T) {
 	assert.Equal(t, "1/1", out)
 }
 
+func TestAttemptsRetriedJob(t *testing.T) {
+	job := activeJob
+	job.Spec.BackoffLimit = util.Int32Ptr(3)
+	job.Status.Failed = 1
+
+	assert.Equal(t, "Active", status(job))
+	assert.Equal(t, "2/4", attempts(job))
+}
+
+func TestAttemptsDefaultBackoffLimit(t *testing.T) {
+	// Jobs without a backoff limit are not retried, as reflected by their status.
+	job := failedJob
+	assert.Equal(t, "Failed", status(job))
+	assert.Equal(t, "1/1", attempts(job))
+}
+
 func TestComplectionsMultiplePods(t *testing.T) {
 	job := activeJob
 	job.Status.Succeeded = 1
@@ -234,3 +255,254 @@ func TestAgeSuccessfulJob(t *testing.T) {
 	out := age(job)
 	assert.Equal(t, "1 hour ago", out)
 }
+
+func TestListRunCron(t *testing.T) {
+	var out strings.Builder
+	cmd := newListCmd()
+	cmd.SetOut(&out)
+
+	suspend := true
+	cronJobs := []batchv1.CronJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nightly"}, Spec: batchv1.CronJobSpec{Schedule: "0 2 * * *"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "hourly"}, Spec: batchv1.CronJobSpec{Schedule: "@hourly", Suspend: &suspend}},
+	}
+
+	client := &fake.Client{}
+	client.On("ListCronJobs").Return(cronJobs, nil)
+
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: client}, Cron: true}
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 3)
+	assert.Equal(t, []string{"NAME", "SCHEDULE", "SUSPENDED", "ACTIVE", "LAST", "SCHEDULE"}, strings.Fields(lines[0]))
+	assert.Equal(t, []string{"nightly", "0", "2", "*", "*", "*", "false", "0", "-"}, strings.Fields(lines[1]))
+	assert.Equal(t, []string{"hourly", "@hourly", "true", "0", "-"}, strings.Fields(lines[2]))
+}
+
+func TestListWatchJobs(t *testing.T) {
+	var out strings.Builder
+	jobWatcher := watch.NewFake()
+	podWatcher := watch.NewFake()
+
+	running := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-3"}, Status: batchv1.JobStatus{Active: 1}}
+	failed := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-3"}, Status: batchv1.JobStatus{
+		Failed:     1,
+		Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+	}}
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "exp-3-abcde", Labels: map[string]string{"job-name": "exp-3"}},
+		Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+		}}},
+	}
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{running}, nil)
+	client.On("WatchJobs").Return(jobWatcher, nil)
+	client.On("WatchPods", "job-name").Return(podWatcher, nil)
+
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: client}, WatchPods: true}
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctx.watchJobs(c, &out, false, &jobSelection{}) }()
+
+	// Existing jobs are reported by the watch as added, which is not a transition.
+	jobWatcher.Add(running.DeepCopy())
+	podWatcher.Modify(pod.DeepCopy())
+	jobWatcher.Modify(failed.DeepCopy())
+	jobWatcher.Add(successfulJob.DeepCopy())
+	jobWatcher.Delete(failed.DeepCopy())
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, "exp-3 Active → Failed (OOMKilled)\nfoo created (Succeeded)\nexp-3 deleted\n", out.String())
+}
+
+func TestListWatchJobsSelection(t *testing.T) {
+	var out strings.Builder
+	jobWatcher := watch.NewFake()
+
+	running := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-3"}, Status: batchv1.JobStatus{Active: 1}}
+	failed := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-3"}, Status: batchv1.JobStatus{Failed: 1}}
+	other := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other"}, Status: batchv1.JobStatus{Failed: 1}}
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{running}, nil)
+	client.On("WatchJobs").Return(jobWatcher, nil)
+
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: client}, Statuses: []string{"failed"}, NameRegex: "^exp-"}
+	selection, err := ctx.selection()
+	assert.NoError(t, err)
+
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctx.watchJobs(c, &out, false, selection) }()
+
+	// Only transitions of jobs matching both the status and the name are printed.
+	jobWatcher.Add(successfulJob.DeepCopy())
+	jobWatcher.Add(other.DeepCopy())
+	jobWatcher.Modify(failed.DeepCopy())
+	jobWatcher.Delete(other.DeepCopy())
+	jobWatcher.Delete(failed.DeepCopy())
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, "exp-3 Active → Failed\nexp-3 deleted\n", out.String())
+}
+
+func TestListWatchJobsRelistsWhenWatchEnds(t *testing.T) {
+	var out strings.Builder
+	first, second := watch.NewFake(), watch.NewFake()
+
+	running := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-1"}, Status: batchv1.JobStatus{Active: 1}}
+	succeeded := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-1"}, Status: batchv1.JobStatus{Succeeded: 1}}
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{running}, nil).Once()
+	client.On("ListJobs").Return([]batchv1.Job{succeeded}, nil)
+	client.On("WatchJobs").Return(first, nil).Once()
+	client.On("WatchJobs").Return(second, nil)
+
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: client}}
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctx.watchJobs(c, &out, false, &jobSelection{}) }()
+
+	// The job finishes while the watch is being reestablished.
+	first.Stop()
+	second.Add(succeeded.DeepCopy())
+	cancel()
+
+	assert.NoError(t, <-done)
+	assert.Equal(t, "exp-1 Active → Succeeded\n", out.String())
+}
+
+func TestListWatchJobsTerminal(t *testing.T) {
+	var out strings.Builder
+	jobWatcher := watch.NewFake()
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{activeJob}, nil)
+	client.On("WatchJobs").Return(jobWatcher, nil)
+
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: client}, RedrawInterval: time.Hour}
+	c, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- ctx.watchJobs(c, &out, true, &jobSelection{}) }()
+
+	jobWatcher.Modify(successfulJob.DeepCopy())
+	cancel()
+
+	assert.NoError(t, <-done)
+	screens := strings.Split(out.String(), "\033[H\033[2J")
+	assert.Len(t, screens, 3)
+	assert.Contains(t, screens[1], "Active")
+	assert.Contains(t, screens[2], "Succeeded")
+}
+
+func TestListRunWatchStructuredOutput(t *testing.T) {
+	ctx := &listContext{CommandContext: cli.CommandContext{Client: &fake.Client{}}, Watch: true, Output: "json"}
+
+	err := ctx.Run(newListCmd(), []string{})
+	assert.EqualError(t, err, "--watch only supports table output")
+}
+
+func selectionJobs(now time.Time) []batchv1.Job {
+	job := func(name string, created time.Duration, gpus string) batchv1.Job {
+		j := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(now.Add(-created))}}
+		j.Spec.Template.Spec.Containers = []corev1.Container{{Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse(gpus)},
+		}}}
+		return j
+	}
+
+	running := job("exp-2", time.Hour, "4")
+	running.Status.Active = 1
+
+	finished := job("exp-1", 72*time.Hour, "1")
+	finished.Status.Succeeded = 1
+	finished.Status.CompletionTime = &metav1.Time{Time: now.Add(-48 * time.Hour)}
+
+	failed := job("other", 2*time.Hour, "2")
+	failed.Status.Conditions = []batchv1.JobCondition{{
+		Type:               batchv1.JobFailed,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.NewTime(now.Add(-time.Hour)),
+	}}
+
+	return []batchv1.Job{running, finished, failed}
+}
+
+func selectedNames(t *testing.T, ctx *listContext, jobs []batchv1.Job, now time.Time) []string {
+	selection, err := ctx.selection()
+	assert.NoError(t, err)
+
+	var names []string
+	for _, job := range selection.apply(jobs, now) {
+		names = append(names, job.Name)
+	}
+
+	return names
+}
+
+func TestListSelection(t *testing.T) {
+	now := time.Now()
+	jobs := selectionJobs(now)
+
+	tests := []struct {
+		name string
+		ctx  listContext
+		want []string
+	}{
+		{"api order", listContext{}, []string{"exp-2", "exp-1", "other"}},
+		{"hide finished", listContext{HideFinishedAfter: 24 * time.Hour}, []string{"exp-2", "other"}},
+		{"show all", listContext{HideFinishedAfter: 24 * time.Hour, ShowAll: true}, []string{"exp-2", "exp-1", "other"}},
+		{"sort by name", listContext{SortBy: "name"}, []string{"exp-1", "exp-2", "other"}},
+		{"sort by age", listContext{SortBy: "age"}, []string{"exp-2", "other", "exp-1"}},
+		{"sort by gpu reversed", listContext{SortBy: "gpu", Reverse: true}, []string{"exp-2", "other", "exp-1"}},
+		{"sort by status", listContext{SortBy: "status"}, []string{"exp-2", "other", "exp-1"}},
+		{"reverse", listContext{Reverse: true}, []string{"other", "exp-1", "exp-2"}},
+		{"status", listContext{Statuses: []string{"active", "failed"}}, []string{"exp-2", "other"}},
+		{"since", listContext{Since: "1d"}, []string{"exp-2", "other"}},
+		{"name regex", listContext{NameRegex: "^exp-"}, []string{"exp-2", "exp-1"}},
+		{"limit", listContext{SortBy: "name", Limit: 2}, []string{"exp-1", "exp-2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, selectedNames(t, &tt.ctx, jobs, now))
+		})
+	}
+}
+
+func TestListSelectionStartTime(t *testing.T) {
+	// A job that was created long ago, but only started recently, e.g. after being suspended, is young.
+	now := time.Now()
+	jobs := selectionJobs(now)
+	jobs[1].Status.StartTime = &metav1.Time{Time: now.Add(-30 * time.Minute)}
+	assert.Equal(t, "30 minutes ago", age(jobs[1]))
+
+	ctx := &listContext{Since: "1d", SortBy: "age"}
+	assert.Equal(t, []string{"exp-1", "exp-2", "other"}, selectedNames(t, ctx, jobs, now))
+}
+
+func TestListSelectionInvalid(t *testing.T) {
+	for _, ctx := range []listContext{
+		{SortBy: "size"},
+		{Statuses: []string{"done"}},
+		{Since: "yesterday"},
+		{NameRegex: "("},
+		{Limit: -1},
+	} {
+		_, err := ctx.selection()
+		assert.Error(t, err)
+	}
+}
+
+func TestListPreRunHideFinished(t *testing.T) {
+	ctx := &listContext{}
+	assert.NoError(t, ctx.PreRun(newListCmd(), []string{}))
+	assert.Equal(t, 24*time.Hour, ctx.HideFinishedAfter)
+}
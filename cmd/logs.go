@@ -11,10 +11,13 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/uitml/frink/internal/cli"
 	"github.com/uitml/frink/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
 )
 
 type logsContext struct {
 	cli.CommandContext
+
+	LogOptions *corev1.PodLogOptions
 }
 
 func newLogsCmd() *cobra.Command {
@@ -24,6 +27,8 @@ func newLogsCmd() *cobra.Command {
 		Short:   "Fetch the logs of a job",
 		Aliases: []string{"watch"}, // TODO(thomasjo): Remove alias?
 
+		ValidArgsFunction: completeJobName,
+
 		PreRunE: ctx.PreRun,
 		RunE:    ctx.Run,
 	}
@@ -32,7 +37,13 @@ func newLogsCmd() *cobra.Command {
 }
 
 func (ctx *logsContext) PreRun(cmd *cobra.Command, args []string) error {
-	return ctx.Initialize(cmd)
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	ctx.LogOptions = ctx.Config.Logs.PodLogOptions()
+
+	return nil
 }
 
 func (ctx *logsContext) Run(cmd *cobra.Command, args []string) error {
@@ -41,7 +52,12 @@ func (ctx *logsContext) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	name := args[0]
-	req, err := ctx.Client.GetJobLogs(name, k8s.DefaultLogOptions)
+	opts := ctx.LogOptions
+	if opts == nil {
+		opts = k8s.DefaultLogOptions
+	}
+
+	req, err := ctx.Client.GetJobLogs(name, opts)
 	if err != nil {
 		return fmt.Errorf("unable to get logs: %w", errors.Unwrap(err))
 	}
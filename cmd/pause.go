@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+)
+
+type pauseContext struct {
+	cli.CommandContext
+
+	// Suspend is true when pausing jobs, and false when unpausing them.
+	Suspend bool
+}
+
+func newPauseCmd() *cobra.Command {
+	ctx := &pauseContext{Suspend: true}
+	cmd := &cobra.Command{
+		Use:   "pause <name>...",
+		Short: "Suspend jobs, releasing their pods",
+		Long: `Suspend jobs, releasing their pods.
+
+Paused jobs keep their definition, but their active pods are terminated, freeing the resources
+they use. Use "frink unpause" to continue them, which creates new pods; jobs should therefore
+be able to resume from checkpoints. Use "frink run --suspended" to submit a job that is paused.
+
+Suspending jobs requires Kubernetes 1.21 with the SuspendJob feature gate enabled, or 1.22 or later.`,
+		Args: cobra.MinimumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	return cmd
+}
+
+func newUnpauseCmd() *cobra.Command {
+	ctx := &pauseContext{Suspend: false}
+	cmd := &cobra.Command{
+		Use:   "unpause <name>...",
+		Short: "Continue suspended jobs",
+		Args:  cobra.MinimumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	return cmd
+}
+
+func (ctx *pauseContext) PreRun(cmd *cobra.Command, args []string) error {
+	return ctx.Initialize(cmd)
+}
+
+func (ctx *pauseContext) Run(cmd *cobra.Command, args []string) error {
+	out := cmd.OutOrStdout()
+	verb, state := "Paused", "paused"
+	if !ctx.Suspend {
+		verb, state = "Unpaused", "running"
+	}
+
+	for _, name := range args {
+		job, err := ctx.Client.GetJob(name)
+		if err != nil {
+			return fmt.Errorf("unable to get job: %w", err)
+		}
+		if job == nil {
+			return fmt.Errorf("job %s not found", name)
+		}
+
+		switch status := k8s.JobStatus(*job); {
+		case status == k8s.StatusSucceeded || status == k8s.StatusFailed:
+			return fmt.Errorf("job %s has already finished", name)
+		case (status == k8s.StatusSuspended) == ctx.Suspend:
+			fmt.Fprintf(out, "Job %s is already %s\n", name, state)
+			continue
+		}
+
+		if err := ctx.Client.SuspendJob(name, ctx.Suspend); err != nil {
+			return fmt.Errorf("unable to update job %s: %w", name, err)
+		}
+		fmt.Fprintf(out, "%s job %s\n", verb, name)
+	}
+
+	return nil
+}
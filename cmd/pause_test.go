@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPauseRun(t *testing.T) {
+	var out strings.Builder
+	cmd := newPauseCmd()
+	cmd.SetOut(&out)
+
+	suspended := true
+	paused := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "bar"}, Spec: batchv1.JobSpec{Suspend: &suspended}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&activeJob, nil)
+	client.On("GetJob", "bar").Return(&paused, nil)
+	client.On("SuspendJob", "foo", true).Return(nil)
+
+	ctx := &pauseContext{CommandContext: cli.CommandContext{Client: client}, Suspend: true}
+	err := ctx.Run(cmd, []string{"foo", "bar"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Paused job foo\nJob bar is already paused\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestUnpauseRun(t *testing.T) {
+	var out strings.Builder
+	cmd := newUnpauseCmd()
+	cmd.SetOut(&out)
+
+	suspended := true
+	paused := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo"}, Spec: batchv1.JobSpec{Suspend: &suspended}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&paused, nil)
+	client.On("SuspendJob", "foo", false).Return(nil)
+
+	ctx := &pauseContext{CommandContext: cli.CommandContext{Client: client}, Suspend: false}
+	err := ctx.Run(cmd, []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Unpaused job foo\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestPauseRunFinishedJob(t *testing.T) {
+	finished := successfulJob
+	finished.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&finished, nil)
+
+	ctx := &pauseContext{CommandContext: cli.CommandContext{Client: client}, Suspend: true}
+	err := ctx.Run(newPauseCmd(), []string{"foo"})
+	assert.EqualError(t, err, "job foo has already finished")
+}
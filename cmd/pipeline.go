@@ -0,0 +1,258 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/pipeline"
+)
+
+type pipelineContext struct {
+	cli.CommandContext
+	Fs afero.Fs
+
+	Assignments []string
+	Template    bool
+	Profile     string
+	Resume      bool
+	Interval    time.Duration
+
+	DeleteTimeout time.Duration
+	OnConflict    string
+	User          string
+}
+
+func newPipelineCmd() *cobra.Command {
+	ctx := &pipelineContext{
+		Fs: afero.NewOsFs(),
+	}
+	cmd := &cobra.Command{
+		Use:   "pipeline",
+		Short: "Run pipelines of dependent jobs",
+		Long: `Run pipelines of dependent jobs.
+
+A pipeline file lists jobs, each read from a job specification file (relative to the pipeline file)
+or specified inline, along with the jobs they depend on:
+
+  name: exp1
+  jobs:
+  - name: preprocess
+    file: preprocess.yaml
+  - name: train
+    file: train.yaml
+    dependsOn: [preprocess]
+  - name: evaluate
+    job: {image: "...", command: ["python", "eval.py"]}
+    dependsOn: [train]
+
+Each job is created once the jobs it depends on have succeeded, and is named "<pipeline>-<job>".
+Existing jobs from previous runs of the pipeline are replaced; other jobs with the same name are
+handled according to the onConflict setting. Jobs are kept until the pipeline is removed, regardless
+of any TTL. If a job fails, no further jobs are created, and frink waits for jobs that are still
+running before the pipeline is considered failed. The state of the pipeline is stored in the config
+map frink-pipeline-<name>, so its status can be shown after frink exits, and an interrupted run can
+be continued with "frink pipeline run --resume".`,
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run <file>",
+		Short: "Run a pipeline, waiting for it to finish",
+		Args:  cobra.ExactArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+	flags := runCmd.Flags()
+	flags.StringArrayVar(&ctx.Assignments, "set", nil, "set a variable used in the job specifications (key=value)")
+	flags.BoolVar(&ctx.Template, "template", false, "render the job specifications as Go templates")
+	flags.StringVar(&ctx.Profile, "profile", "", "name of the profile the job specifications extend")
+	flags.BoolVar(&ctx.Resume, "resume", false, "continue an interrupted run of the pipeline")
+	flags.DurationVar(&ctx.Interval, "interval", 2*time.Second, "time between checking the status of jobs")
+	cmd.AddCommand(runCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status [name]",
+		Short: "Show the status of a pipeline, or list pipelines",
+		Args:  cobra.MaximumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Status,
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rm <name>",
+		Short: "Remove a pipeline and its jobs",
+		Args:  cobra.ExactArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Remove,
+	})
+
+	return cmd
+}
+
+func (ctx *pipelineContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	ctx.DeleteTimeout = ctx.Config.Timeouts.Delete
+	ctx.OnConflict = ctx.Config.OnConflict
+	ctx.User, _ = k8s.CurrentUser()
+
+	return nil
+}
+
+func (ctx *pipelineContext) runner() *pipeline.Runner {
+	return &pipeline.Runner{
+		Client:        ctx.Client,
+		Out:           ctx.Out,
+		Interval:      ctx.Interval,
+		DeleteTimeout: ctx.DeleteTimeout,
+		OnConflict:    ctx.OnConflict,
+	}
+}
+
+func (ctx *pipelineContext) Run(cmd *cobra.Command, args []string) error {
+	cfg := ctx.Config
+	if cfg == nil {
+		cfg = cli.DefaultConfig()
+	}
+
+	parser, err := newJobParser(cfg, ctx.Assignments, ctx.Template, ctx.Profile)
+	if err != nil {
+		return err
+	}
+
+	p, err := pipeline.Parse(ctx.Fs, args[0], parser)
+	if err != nil {
+		return fmt.Errorf("unable to parse pipeline: %w", err)
+	}
+
+	runner := ctx.runner()
+	var state *pipeline.State
+	if ctx.Resume {
+		// The persisted pipeline is used, so that changes to the file do not affect the jobs of the run.
+		if p, state, err = runner.Resume(p.Name); err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Out, "Resuming pipeline %s...\n", p.Name)
+	} else {
+		for _, step := range p.Steps {
+			changes, err := k8s.MutateJob(step.Job, cfg.Mutators.MutateOptions())
+			if err != nil {
+				return fmt.Errorf("unable to mutate job %s: %w", step.Job.Name, err)
+			}
+			printChanges(ctx.Out, step.Job.Name, changes)
+			k8s.SetUser(step.Job, ctx.User)
+		}
+
+		if state, err = runner.Start(p); err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Out, "Starting pipeline %s...\n", p.Name)
+	}
+
+	return runner.Run(p, state)
+}
+
+func (ctx *pipelineContext) Status(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return ctx.list(cmd.OutOrStdout())
+	}
+
+	configMap, err := ctx.Client.GetConfigMap(pipeline.ConfigMapName(args[0]))
+	if err != nil {
+		return fmt.Errorf("unable to get pipeline state: %w", err)
+	}
+	if configMap == nil {
+		return fmt.Errorf("pipeline %s not found", args[0])
+	}
+
+	p, state, err := pipeline.Decode(configMap)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+	defer w.Flush()
+
+	fmt.Fprintf(w, "Pipeline %s: %s (started %s)\n\n", p.Name, state.Status, humanize.Time(state.Started))
+	fmt.Fprintln(w, "STEP\tJOB\tSTATUS\tDURATION\tDEPENDS ON\t")
+	for _, step := range p.Steps {
+		status, elapsed := state.Steps[step.Name], "-"
+
+		// Jobs might have changed since the state was last saved, e.g. if frink was interrupted.
+		if status == pipeline.StatusRunning || status == pipeline.StatusSucceeded || status == pipeline.StatusFailed {
+			job, err := ctx.Client.GetJob(step.Job.Name)
+			if err != nil {
+				return fmt.Errorf("unable to get job: %w", err)
+			}
+
+			if job == nil {
+				status += " (job deleted)"
+			} else {
+				if status == pipeline.StatusRunning {
+					status += " (" + k8s.JobStatus(*job) + ")"
+				}
+				elapsed = duration(*job)
+			}
+		}
+
+		dependsOn := "-"
+		if len(step.DependsOn) > 0 {
+			dependsOn = strings.Join(step.DependsOn, ",")
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t\n", step.Name, step.Job.Name, status, elapsed, dependsOn)
+	}
+
+	return nil
+}
+
+// list writes a table of all pipelines in the namespace to w.
+func (ctx *pipelineContext) list(w io.Writer) error {
+	configMaps, err := ctx.Client.ListConfigMaps(pipeline.PipelineLabel)
+	if err != nil {
+		return fmt.Errorf("unable to list pipelines: %w", err)
+	}
+
+	sort.Slice(configMaps, func(i, j int) bool { return configMaps[i].Name < configMaps[j].Name })
+
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintln(tw, "NAME\tSTATUS\tSUCCEEDED\tAGE\t")
+	for i := range configMaps {
+		p, state, err := pipeline.Decode(&configMaps[i])
+		if err != nil {
+			fmt.Fprintln(ctx.Err, err)
+			continue
+		}
+
+		succeeded := 0
+		for _, status := range state.Steps {
+			if status == pipeline.StatusSucceeded {
+				succeeded++
+			}
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%d/%d\t%s\t\n", p.Name, state.Status, succeeded, len(p.Steps), humanize.Time(state.Started))
+	}
+
+	return nil
+}
+
+func (ctx *pipelineContext) Remove(cmd *cobra.Command, args []string) error {
+	fmt.Fprintf(ctx.Out, "Deleting pipeline %s...\n", args[0])
+
+	return ctx.runner().Remove(args[0])
+}
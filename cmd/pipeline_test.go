@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/pipeline"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestPipelineStatus(t *testing.T) {
+	var out strings.Builder
+	cmd := newPipelineCmd()
+	cmd.SetOut(&out)
+
+	p := &pipeline.Pipeline{Name: "exp1", Steps: []pipeline.Step{
+		{Name: "train", Job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp1-train"}}},
+		{Name: "eval", DependsOn: []string{"train"}, Job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp1-eval"}}},
+	}}
+	state := pipeline.NewState(p)
+	state.Steps["train"] = pipeline.StatusRunning
+	configMap, _ := pipeline.Encode(p, state)
+
+	running := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp1-train"}}
+	running.Status.Active = 1
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-pipeline-exp1").Return(configMap, nil)
+	client.On("GetJob", "exp1-train").Return(running, nil)
+
+	ctx := &pipelineContext{CommandContext: cli.CommandContext{Out: &out, Client: client}}
+	err := ctx.Status(cmd, []string{"exp1"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Pipeline exp1: Running")
+	assert.Regexp(t, `train\s+exp1-train\s+Running \(Active\)`, out.String())
+	assert.Regexp(t, `eval\s+exp1-eval\s+Pending\s+-\s+train`, out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestPipelineStatusList(t *testing.T) {
+	var out strings.Builder
+	cmd := newPipelineCmd()
+	cmd.SetOut(&out)
+
+	p := &pipeline.Pipeline{Name: "exp1", Steps: []pipeline.Step{
+		{Name: "train", Job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp1-train"}}},
+	}}
+	state := pipeline.NewState(p)
+	state.Status = pipeline.StatusSucceeded
+	state.Steps["train"] = pipeline.StatusSucceeded
+	configMap, _ := pipeline.Encode(p, state)
+
+	client := &fake.Client{}
+	client.On("ListConfigMaps", pipeline.PipelineLabel).Return([]corev1.ConfigMap{*configMap}, nil)
+
+	ctx := &pipelineContext{CommandContext: cli.CommandContext{Out: &out, Client: client}}
+	err := ctx.Status(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Regexp(t, `exp1\s+Succeeded\s+1/1`, out.String())
+}
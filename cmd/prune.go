@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+type pruneContext struct {
+	cli.CommandContext
+
+	OlderThan string
+	Statuses  []string
+	Yes       bool
+	DryRun    bool
+
+	// Now is used to determine the age of jobs; the zero value means the current time.
+	Now time.Time
+}
+
+func newPruneCmd() *cobra.Command {
+	ctx := &pruneContext{}
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Delete old jobs in bulk",
+		Long: `Delete old jobs in bulk.
+
+Deletes the jobs with one of the given statuses that finished longer ago than --older-than,
+e.g. "12h", "7d" or "2w". Jobs that have not finished are aged by when they were created.
+The matching jobs are listed, and must be confirmed before they are deleted, unless --yes is given.
+
+Finished jobs can also be deleted automatically by setting a TTL, either via "ttl" in simplified job
+specifications, or for all jobs via the jobs.ttl setting.`,
+		Args: cobra.NoArgs,
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&ctx.OlderThan, "older-than", "7d", "only delete jobs older than this")
+	flags.StringSliceVar(&ctx.Statuses, "status", []string{"succeeded", "failed"}, "only delete jobs with these statuses: active|succeeded|failed|stopped|suspended")
+	flags.BoolVarP(&ctx.Yes, "yes", "y", false, "do not ask for confirmation")
+	flags.BoolVar(&ctx.DryRun, "dry-run", false, "only list the jobs that would be deleted")
+
+	return cmd
+}
+
+func (ctx *pruneContext) PreRun(cmd *cobra.Command, args []string) error {
+	return ctx.Initialize(cmd)
+}
+
+func (ctx *pruneContext) Run(cmd *cobra.Command, args []string) error {
+	olderThan, err := k8s.ParseDuration(ctx.OlderThan)
+	if err != nil {
+		return err
+	}
+
+	statuses := map[string]bool{}
+	for _, s := range ctx.Statuses {
+		status, err := parseStatus(s)
+		if err != nil {
+			return err
+		}
+		statuses[status] = true
+	}
+
+	jobs, err := ctx.Client.ListJobs()
+	if err != nil {
+		return fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	now := ctx.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	var matching []batchv1.Job
+	for _, job := range jobs {
+		if statuses[k8s.JobStatus(job)] && now.Sub(pruneTime(job)) > olderThan {
+			matching = append(matching, job)
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	if len(matching) == 0 {
+		fmt.Fprintln(out, "No jobs to delete")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tFINISHED\t")
+	for _, job := range matching {
+		finished := "-"
+		if t, ok := k8s.JobFinishTime(job); ok {
+			finished = humanize.RelTime(t, now, "ago", "from now")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", job.Name, k8s.JobStatus(job), finished)
+	}
+	w.Flush()
+
+	if ctx.DryRun {
+		return nil
+	}
+
+	if !ctx.Yes {
+		ok, err := cli.Confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d jobs?", len(matching)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Aborted")
+			return nil
+		}
+	}
+
+	var failed []string
+	for _, job := range matching {
+		if err := ctx.Client.DeleteJob(job.Name); err != nil {
+			fmt.Fprintf(ctx.Err, "Unable to delete job %s: %v\n", job.Name, err)
+			failed = append(failed, job.Name)
+		}
+	}
+
+	fmt.Fprintf(out, "Deleted %d jobs\n", len(matching)-len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to delete %d jobs: %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// parseStatus returns the job status matching s case-insensitively; see k8s.JobStatus.
+func parseStatus(s string) (string, error) {
+	for _, status := range []string{k8s.StatusActive, k8s.StatusSucceeded, k8s.StatusFailed, k8s.StatusStopped, k8s.StatusSuspended} {
+		if strings.EqualFold(s, status) {
+			return status, nil
+		}
+	}
+
+	return "", fmt.Errorf("unknown status %q (use active, succeeded, failed, stopped or suspended)", s)
+}
+
+// pruneTime returns the time the age of the job is measured from.
+func pruneTime(job batchv1.Job) time.Time {
+	if t, ok := k8s.JobFinishTime(job); ok {
+		return t
+	}
+
+	return job.CreationTimestamp.Time
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var pruneNow = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func pruneJobs() []batchv1.Job {
+	old := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "old"}}
+	old.Status.Succeeded = 1
+	old.Status.CompletionTime = &metav1.Time{Time: pruneNow.Add(-10 * 24 * time.Hour)}
+
+	recent := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "recent"}}
+	recent.Status.Succeeded = 1
+	recent.Status.CompletionTime = &metav1.Time{Time: pruneNow.Add(-time.Hour)}
+
+	active := batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:              "active",
+		CreationTimestamp: metav1.Time{Time: pruneNow.Add(-30 * 24 * time.Hour)},
+	}}
+	active.Status.Active = 1
+
+	return []batchv1.Job{old, recent, active}
+}
+
+func newPruneContext(client *fake.Client) *pruneContext {
+	return &pruneContext{
+		CommandContext: cli.CommandContext{Err: &strings.Builder{}, Client: client},
+		OlderThan:      "7d",
+		Statuses:       []string{"succeeded", "failed"},
+		Now:            pruneNow,
+	}
+}
+
+func TestPruneRunConfirmed(t *testing.T) {
+	var out strings.Builder
+	cmd := newPruneCmd()
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("y\n"))
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(pruneJobs(), nil)
+	client.On("DeleteJob", "old").Return(nil)
+
+	ctx := newPruneContext(client)
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Delete 1 jobs? [y/N]")
+	assert.Contains(t, out.String(), "Deleted 1 jobs")
+
+	client.AssertExpectations(t)
+}
+
+func TestPruneRunDeclined(t *testing.T) {
+	var out strings.Builder
+	cmd := newPruneCmd()
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("\n"))
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(pruneJobs(), nil)
+
+	ctx := newPruneContext(client)
+	ctx.Statuses = []string{"active", "Succeeded"}
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Regexp(t, `old\s+Succeeded\s+1 week ago`, out.String())
+	assert.Regexp(t, `active\s+Active\s+-`, out.String())
+	assert.Contains(t, out.String(), "Aborted")
+
+	client.AssertNotCalled(t, "DeleteJob", "old")
+}
+
+func TestPruneRunInvalidStatus(t *testing.T) {
+	ctx := newPruneContext(&fake.Client{})
+	ctx.Statuses = []string{"done"}
+
+	err := ctx.Run(newPruneCmd(), []string{})
+	assert.EqualError(t, err, `unknown status "done" (use active, succeeded, failed, stopped or suspended)`)
+}
@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/queue"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+type queueContext struct {
+	cli.CommandContext
+	Fs afero.Fs
+
+	// User is the name of the user owning the queue, which is also used to label the queued jobs.
+	User string
+
+	Assignments []string
+	Template    bool
+	Profile     string
+
+	MaxActive int
+	Interval  time.Duration
+	Output    string
+}
+
+type queueSummary struct {
+	Position int       `json:"position"`
+	Name     string    `json:"name"`
+	Added    time.Time `json:"added"`
+}
+
+func newQueueCmd() *cobra.Command {
+	ctx := &queueContext{
+		Fs: afero.NewOsFs(),
+	}
+	cmd := &cobra.Command{
+		Use:   "queue",
+		Short: "Queue jobs, keeping a limited number of them active",
+		Long: `Queue jobs, keeping a limited number of them active.
+
+Jobs added with "frink queue add" wait in your queue until "frink queue run" submits them, keeping at
+most --max-active of your jobs active in the namespace at a time, and submitting the next job when one
+finishes. Jobs submitted by other means count towards the limit as well.
+
+The queue is stored in the config map frink-queue-<user>, so it can be shared by several terminals:
+jobs can be added while "frink queue run" is running, and several runners can process the same queue
+without submitting a job twice. If a job with the same name exists when a queued job is submitted,
+a random suffix is appended to the name of the queued job.`,
+	}
+
+	addCmd := &cobra.Command{
+		Use:   "add <file>...",
+		Short: "Add jobs to the end of the queue",
+		Args:  cobra.MinimumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Add,
+	}
+	flags := addCmd.Flags()
+	flags.StringArrayVar(&ctx.Assignments, "set", nil, "set a variable used in the job specifications (key=value)")
+	flags.BoolVar(&ctx.Template, "template", false, "render the job specifications as Go templates")
+	flags.StringVar(&ctx.Profile, "profile", "", "name of the profile the job specifications extend")
+	cmd.AddCommand(addCmd)
+
+	lsCmd := &cobra.Command{
+		Use:   "ls",
+		Short: "List the jobs in the queue",
+		Args:  cobra.NoArgs,
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.List,
+	}
+	lsCmd.Flags().StringVarP(&ctx.Output, "output", "o", "", "output format: table|json|yaml")
+	cmd.AddCommand(lsCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "rm <name>...",
+		Short: "Remove jobs from the queue",
+		Args:  cobra.MinimumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Remove,
+	})
+
+	runCmd := &cobra.Command{
+		Use:   "run",
+		Short: "Submit the jobs in the queue, until it is empty",
+		Args:  cobra.NoArgs,
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+	flags = runCmd.Flags()
+	flags.IntVar(&ctx.MaxActive, "max-active", 1, "maximum number of your jobs active at a time")
+	flags.DurationVar(&ctx.Interval, "interval", 10*time.Second, "time between checking the status of active jobs")
+	cmd.AddCommand(runCmd)
+
+	return cmd
+}
+
+func (ctx *queueContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	if ctx.Output == "" {
+		ctx.Output = ctx.Config.Output
+	}
+	// The queue of unknown users is shared, which is not worth failing for.
+	ctx.User, _ = k8s.CurrentUser()
+
+	return nil
+}
+
+func (ctx *queueContext) store() *queue.Store {
+	return &queue.Store{Client: ctx.Client, User: ctx.User}
+}
+
+func (ctx *queueContext) Add(cmd *cobra.Command, args []string) error {
+	cfg := ctx.Config
+	if cfg == nil {
+		cfg = cli.DefaultConfig()
+	}
+
+	stdin := 0
+	for _, filename := range args {
+		if filename == k8s.StdinSource {
+			stdin++
+		}
+	}
+	if stdin > 1 {
+		return fmt.Errorf("stdin (%s) can only be given once", k8s.StdinSource)
+	}
+
+	// All jobs are parsed before any is added, so that an invalid specification leaves the queue unchanged.
+	var jobs []*batchv1.Job
+	for _, filename := range args {
+		// Each job gets its own parser, so that built-in variables such as RANDOM_SUFFIX differ between jobs.
+		parser, err := newJobParser(cfg, ctx.Assignments, ctx.Template, ctx.Profile)
+		if err != nil {
+			return err
+		}
+
+		job, err := ctx.parseJob(cmd, parser, filename)
+		if err != nil {
+			return fmt.Errorf("unable to parse job %s: %w", filename, err)
+		}
+
+		changes, err := k8s.MutateJob(job, cfg.Mutators.MutateOptions())
+		if err != nil {
+			return fmt.Errorf("unable to mutate job %s: %w", job.Name, err)
+		}
+		printChanges(ctx.Out, job.Name, changes)
+		k8s.SetUser(job, ctx.User)
+		k8s.SetRun(job, k8s.NewRunID())
+
+		jobs = append(jobs, job)
+	}
+
+	var size int
+	err := ctx.store().Update(func(q *queue.Queue) error {
+		now := time.Now()
+		for _, job := range jobs {
+			q.Entries = append(q.Entries, queue.Entry{Job: job, Added: now})
+		}
+		size = len(q.Entries)
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to add jobs to queue: %w", err)
+	}
+
+	for _, job := range jobs {
+		fmt.Fprintf(ctx.Out, "Queued job %s\n", job.Name)
+	}
+	fmt.Fprintf(ctx.Out, "%d jobs in queue\n", size)
+
+	return nil
+}
+
+func (ctx *queueContext) parseJob(cmd *cobra.Command, parser k8s.JobParser, filename string) (*batchv1.Job, error) {
+	r, err := k8s.OpenSpec(ctx.Fs, cmd.InOrStdin(), filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	source := filename
+	if filename == k8s.StdinSource {
+		source = "stdin"
+	}
+
+	return parser.Parse(r, source)
+}
+
+func (ctx *queueContext) List(cmd *cobra.Command, args []string) error {
+	q, err := ctx.store().Load()
+	if err != nil {
+		return err
+	}
+
+	summaries := make([]queueSummary, 0, len(q.Entries))
+	for i, entry := range q.Entries {
+		summaries = append(summaries, queueSummary{Position: i + 1, Name: entry.Job.Name, Added: entry.Added})
+	}
+
+	switch ctx.Output {
+	case "", "table":
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 3, ' ', 0)
+		defer w.Flush()
+
+		fmt.Fprintln(w, "#\tNAME\tADDED\t")
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%d\t%s\t%s\t\n", s.Position, s.Name, humanize.Time(s.Added))
+		}
+	case "json", "yaml":
+		return printStructured(cmd.OutOrStdout(), ctx.Output, summaries)
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", ctx.Output)
+	}
+
+	return nil
+}
+
+func (ctx *queueContext) Remove(cmd *cobra.Command, args []string) error {
+	var missing []string
+	err := ctx.store().Update(func(q *queue.Queue) error {
+		missing = nil
+		for _, name := range args {
+			if q.Remove(name) == 0 {
+				missing = append(missing, name)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("unable to remove jobs from queue: %w", err)
+	}
+
+	for _, name := range args {
+		if containsName(missing, name) {
+			fmt.Fprintf(ctx.Out, "Nothing to remove: no job named %s in queue\n", name)
+			continue
+		}
+		fmt.Fprintf(ctx.Out, "Removed job %s from queue\n", name)
+	}
+
+	return nil
+}
+
+func (ctx *queueContext) Run(cmd *cobra.Command, args []string) error {
+	if ctx.MaxActive < 1 {
+		return fmt.Errorf("--max-active must be at least 1")
+	}
+
+	runner := &queue.Runner{
+		Client:    ctx.Client,
+		Store:     ctx.store(),
+		Out:       ctx.Out,
+		MaxActive: ctx.MaxActive,
+		Interval:  ctx.Interval,
+	}
+
+	return runner.Run()
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,161 @@
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/queue"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func queueConfigMap(t *testing.T, names ...string) *corev1.ConfigMap {
+	q := queue.Queue{}
+	for _, name := range names {
+		q.Entries = append(q.Entries, queue.Entry{Job: &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name}}})
+	}
+
+	b, err := json.Marshal(q)
+	assert.NoError(t, err)
+
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: queue.ConfigMapName("jane")},
+		Data:       map[string]string{"queue.json": string(b)},
+	}
+}
+
+func TestQueueAdd(t *testing.T) {
+	var out strings.Builder
+	cmd := newQueueCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-queue-jane").Return(queueConfigMap(t, "first"), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(nil)
+
+	ctx := &queueContext{
+		CommandContext: cli.CommandContext{Out: cmd.OutOrStderr(), Client: client},
+		Fs:             afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		User:           "jane",
+	}
+	err := ctx.Add(cmd, []string{"job.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Queued job foo\n2 jobs in queue\n")
+
+	updated := client.Calls[1].Arguments.Get(0).(*corev1.ConfigMap)
+	var q queue.Queue
+	assert.NoError(t, json.Unmarshal([]byte(updated.Data["queue.json"]), &q))
+	assert.Len(t, q.Entries, 2)
+	assert.Equal(t, "foo", q.Entries[1].Job.Name)
+	assert.Equal(t, "jane", q.Entries[1].Job.Labels["frink/user"])
+	assert.NotEmpty(t, q.Entries[1].Job.Labels["frink/run-id"])
+
+	client.AssertExpectations(t)
+}
+
+func TestQueueAddInvalidSpec(t *testing.T) {
+	client := &fake.Client{}
+	ctx := &queueContext{
+		CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client},
+		Fs:             afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		User:           "jane",
+	}
+
+	err := ctx.Add(newQueueCmd(), []string{"job.yaml", "missing.yaml"})
+	assert.Error(t, err)
+
+	// Nothing is added if any specification is invalid.
+	client.AssertNotCalled(t, "UpdateConfigMap", mock.Anything)
+	client.AssertNotCalled(t, "CreateConfigMap", mock.Anything)
+}
+
+func TestQueueAddRandomSuffixPerJob(t *testing.T) {
+	fs := afero.NewMemMapFs()
+	for _, name := range []string{"a.yaml", "b.yaml"} {
+		assert.NoError(t, afero.WriteFile(fs, name, []byte("name: ${prefix}-${RANDOM_SUFFIX}\n"), 0644))
+	}
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-queue-jane").Return(nil, nil)
+	client.On("CreateConfigMap", mock.Anything).Return(nil)
+
+	ctx := &queueContext{
+		CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client},
+		Fs:             fs,
+		User:           "jane",
+		Assignments:    []string{"prefix=exp"},
+	}
+	err := ctx.Add(newQueueCmd(), []string{"a.yaml", "b.yaml"})
+	assert.NoError(t, err)
+
+	created := client.Calls[1].Arguments.Get(0).(*corev1.ConfigMap)
+	var q queue.Queue
+	assert.NoError(t, json.Unmarshal([]byte(created.Data["queue.json"]), &q))
+	if assert.Len(t, q.Entries, 2) {
+		assert.Regexp(t, `^exp-[a-z0-9]{5}$`, q.Entries[0].Job.Name)
+		assert.NotEqual(t, q.Entries[0].Job.Name, q.Entries[1].Job.Name)
+	}
+}
+
+func TestQueueAddStdinTwice(t *testing.T) {
+	client := &fake.Client{}
+	ctx := &queueContext{
+		CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client},
+		Fs:             afero.NewMemMapFs(),
+		User:           "jane",
+	}
+
+	err := ctx.Add(newQueueCmd(), []string{"-", "-"})
+	assert.EqualError(t, err, "stdin (-) can only be given once")
+	client.AssertNotCalled(t, "GetConfigMap", mock.Anything)
+}
+
+func TestQueueList(t *testing.T) {
+	var out strings.Builder
+	cmd := newQueueCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-queue-jane").Return(queueConfigMap(t, "a", "b"), nil)
+
+	ctx := &queueContext{CommandContext: cli.CommandContext{Client: client}, User: "jane", Output: "json"}
+	err := ctx.List(cmd, nil)
+	assert.NoError(t, err)
+
+	var summaries []queueSummary
+	assert.NoError(t, json.Unmarshal([]byte(out.String()), &summaries))
+	assert.Len(t, summaries, 2)
+	assert.Equal(t, 2, summaries[1].Position)
+	assert.Equal(t, "b", summaries[1].Name)
+}
+
+func TestQueueRemove(t *testing.T) {
+	var out strings.Builder
+	cmd := newQueueCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-queue-jane").Return(queueConfigMap(t, "a", "b"), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(nil)
+
+	ctx := &queueContext{CommandContext: cli.CommandContext{Out: cmd.OutOrStderr(), Client: client}, User: "jane"}
+	err := ctx.Remove(cmd, []string{"a", "c"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Removed job a from queue\nNothing to remove: no job named c in queue\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestQueueRunInvalidMaxActive(t *testing.T) {
+	ctx := &queueContext{CommandContext: cli.CommandContext{Client: &fake.Client{}}, MaxActive: 0}
+
+	err := ctx.Run(newQueueCmd(), nil)
+	assert.EqualError(t, err, "--max-active must be at least 1")
+}
@@ -2,66 +2,369 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
 type removeContext struct {
 	cli.CommandContext
 
+	Selector    string
+	Statuses    []string
+	Cron        bool
+	All         bool
+	Yes         bool
+	Concurrency int
+
 	WaitForDelete bool
+	DeleteTimeout time.Duration
 }
 
 func newRemoveCmd() *cobra.Command {
 	ctx := &removeContext{}
 	cmd := &cobra.Command{
-		Use:   "rm <name>",
-		Short: "Remove job from cluster",
+		Use:   "rm [name...]",
+		Short: "Remove jobs from cluster",
+		Long: `Remove jobs from cluster.
+
+Jobs are selected by name, where names can be glob patterns such as "exp-lr-*", and optionally
+narrowed down by a label selector (-l) and statuses (--status). Use --all to select all jobs, or
+all jobs matching the selector and statuses.
+
+A single name that does not match a job removes the cron job with that name, if any. With --cron,
+cron jobs are selected instead of jobs. Removing a cron job also removes the jobs it created.
+
+Removing more than a single job by its exact name lists the selected jobs, which must be confirmed
+before they are deleted, unless --yes is given. Jobs are deleted concurrently, and --wait waits
+until all of them have been deleted.`,
+		ValidArgsFunction: ctx.Complete,
 
 		PreRunE: ctx.PreRun,
 		RunE:    ctx.Run,
 	}
 
 	flags := cmd.Flags()
-	flags.BoolVarP(&ctx.WaitForDelete, "wait", "w", false, "wait for job to be deleted")
+	flags.StringVarP(&ctx.Selector, "selector", "l", "", "only remove jobs matching the label selector, e.g. frink/user=jane")
+	flags.StringSliceVar(&ctx.Statuses, "status", nil, "only remove jobs with these statuses: active|succeeded|failed|stopped|suspended")
+	flags.BoolVar(&ctx.Cron, "cron", false, "remove cron jobs instead of jobs")
+	flags.BoolVar(&ctx.All, "all", false, "remove all jobs (matching the selector and statuses)")
+	flags.BoolVarP(&ctx.Yes, "yes", "y", false, "do not ask for confirmation")
+	flags.IntVar(&ctx.Concurrency, "concurrency", 8, "maximum number of jobs deleted at the same time")
+	flags.BoolVarP(&ctx.WaitForDelete, "wait", "w", false, "wait for jobs to be deleted")
 
 	return cmd
 }
 
 func (ctx *removeContext) PreRun(cmd *cobra.Command, args []string) error {
-	return ctx.Initialize(cmd)
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	ctx.DeleteTimeout = ctx.Config.Timeouts.Delete
+
+	return nil
+}
+
+// Complete completes the names of jobs, or cron jobs with --cron.
+func (ctx *removeContext) Complete(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if ctx.Cron {
+		return completeCronJobNames(cmd, args, toComplete)
+	}
+
+	return completeJobNames(cmd, args, toComplete)
 }
 
 func (ctx *removeContext) Run(cmd *cobra.Command, args []string) error {
-	if len(args) == 0 {
+	out := cmd.OutOrStdout()
+	if ctx.All && len(args) > 0 {
+		return fmt.Errorf("job names cannot be combined with --all")
+	}
+
+	if len(args) == 0 && !ctx.All {
+		if ctx.Selector != "" || len(ctx.Statuses) > 0 {
+			return fmt.Errorf("job names or --all must be specified")
+		}
 		return fmt.Errorf("job name must be specified")
 	}
 
-	name := args[0]
+	if ctx.Cron && len(ctx.Statuses) > 0 {
+		return fmt.Errorf("--status cannot be combined with --cron")
+	}
+
+	if len(args) == 1 && !isPattern(args[0]) && ctx.Selector == "" && len(ctx.Statuses) == 0 {
+		if ctx.Cron {
+			return ctx.removeCronJob(out, args[0])
+		}
+		return ctx.removeJob(out, args[0])
+	}
 
+	if ctx.Cron {
+		return ctx.removeCronJobs(cmd, args)
+	}
+
+	jobs, err := ctx.matchingJobs(args)
+	if err != nil {
+		return err
+	}
+
+	// As when removing a single job, names that are not patterns are expected to exist.
+	unmatched := unmatchedNames(args, jobs)
+	for _, name := range unmatched {
+		if ctx.Selector != "" || len(ctx.Statuses) > 0 {
+			fmt.Fprintf(out, "Nothing to delete: no matching job named %s found\n", name)
+		} else {
+			fmt.Fprintf(out, "Nothing to delete: no job named %s found\n", name)
+		}
+	}
+
+	if len(jobs) == 0 {
+		if len(unmatched) == 0 {
+			fmt.Fprintln(out, "Nothing to delete: no matching jobs found")
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSTATUS\tAGE\t")
+	for _, job := range jobs {
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", job.Name, status(job), age(job))
+	}
+	w.Flush()
+
+	if !ctx.Yes {
+		ok, err := cli.Confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d jobs?", len(jobs)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Aborted")
+			return nil
+		}
+	}
+
+	return ctx.removeJobs(out, jobs)
+}
+
+// removeJob removes a single job identified by its exact name, which is not an error if it does not exist.
+func (ctx *removeContext) removeJob(out io.Writer, name string) error {
 	job, err := ctx.Client.GetJob(name)
 	if err != nil {
 		return fmt.Errorf("unable to get job: %w", err)
 	}
 
 	if job == nil {
-		fmt.Printf("Nothing to delete: no job named %s found\n", name)
+		// Cron jobs are only looked up if there is no job, since the jobs they create have different names.
+		return ctx.removeCronJob(out, name)
+	}
+
+	fmt.Fprintf(out, "Deleting job %s...\n", name)
+	return ctx.deleteJob(*job)
+}
+
+// removeCronJob removes a single cron job identified by its exact name, which is not an error if it does not exist.
+func (ctx *removeContext) removeCronJob(out io.Writer, name string) error {
+	cronJob, err := ctx.Client.GetCronJob(name)
+	if err != nil {
+		return fmt.Errorf("unable to get cron job: %w", err)
+	}
+
+	if cronJob == nil {
+		fmt.Fprintf(out, "Nothing to delete: no job named %s found\n", name)
 		return nil
 	}
 
-	fmt.Printf("Deleting job %s...\n", name)
-	if err := ctx.Client.DeleteJob(name); err != nil {
+	fmt.Fprintf(out, "Deleting cron job %s...\n", name)
+	if err := ctx.Client.DeleteCronJob(name); err != nil {
+		return fmt.Errorf("unable to delete cron job: %w", err)
+	}
+
+	return nil
+}
+
+// removeCronJobs removes the cron jobs matching any of the name patterns and the selector, after confirmation.
+// There are few cron jobs, so they are deleted sequentially.
+func (ctx *removeContext) removeCronJobs(cmd *cobra.Command, patterns []string) error {
+	out := cmd.OutOrStdout()
+	selector, err := ctx.parsePatterns(patterns)
+	if err != nil {
+		return err
+	}
+
+	cronJobs, err := ctx.Client.ListCronJobs()
+	if err != nil {
+		return fmt.Errorf("could not list cron jobs: %w", err)
+	}
+
+	var matching []batchv1.CronJob
+	for _, cronJob := range cronJobs {
+		if !selector.Matches(labels.Set(cronJob.Labels)) {
+			continue
+		}
+		if len(patterns) > 0 && !matchesAny(patterns, cronJob.Name) {
+			continue
+		}
+
+		matching = append(matching, cronJob)
+	}
+
+	if len(matching) == 0 {
+		fmt.Fprintln(out, "Nothing to delete: no matching cron jobs found")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\t")
+	for _, cronJob := range matching {
+		fmt.Fprintf(w, "%s\t%s\t\n", cronJob.Name, cronJob.Spec.Schedule)
+	}
+	w.Flush()
+
+	if !ctx.Yes {
+		ok, err := cli.Confirm(cmd.InOrStdin(), out, fmt.Sprintf("Delete %d cron jobs?", len(matching)))
+		if err != nil {
+			return err
+		}
+		if !ok {
+			fmt.Fprintln(out, "Aborted")
+			return nil
+		}
+	}
+
+	for _, cronJob := range matching {
+		if err := ctx.Client.DeleteCronJob(cronJob.Name); err != nil {
+			return fmt.Errorf("unable to delete cron job %s: %w", cronJob.Name, err)
+		}
+		fmt.Fprintf(out, "Deleted cron job %s\n", cronJob.Name)
+	}
+
+	return nil
+}
+
+// parsePatterns validates the name patterns, and returns the parsed selector.
+func (ctx *removeContext) parsePatterns(patterns []string) (labels.Selector, error) {
+	selector, err := labels.Parse(ctx.Selector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid selector: %w", err)
+	}
+
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+
+	return selector, nil
+}
+
+// matchingJobs returns the jobs matching any of the name patterns, the selector, and the statuses.
+func (ctx *removeContext) matchingJobs(patterns []string) ([]batchv1.Job, error) {
+	selector, err := ctx.parsePatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := map[string]bool{}
+	for _, s := range ctx.Statuses {
+		status, err := parseStatus(s)
+		if err != nil {
+			return nil, err
+		}
+		statuses[status] = true
+	}
+
+	jobs, err := ctx.Client.ListJobs()
+	if err != nil {
+		return nil, fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	var matching []batchv1.Job
+	for _, job := range jobs {
+		if !selector.Matches(labels.Set(job.Labels)) {
+			continue
+		}
+		if len(statuses) > 0 && !statuses[k8s.JobStatus(job)] {
+			continue
+		}
+		if len(patterns) > 0 && !matchesAny(patterns, job.Name) {
+			continue
+		}
+
+		matching = append(matching, job)
+	}
+
+	return matching, nil
+}
+
+// removeJobs deletes the jobs concurrently, with at most Concurrency deletions in progress.
+func (ctx *removeContext) removeJobs(out io.Writer, jobs []batchv1.Job) error {
+	concurrency := ctx.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		failed []string
+	)
+	sem := make(chan struct{}, concurrency)
+	for _, job := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(job batchv1.Job) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			err := ctx.deleteJob(job)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				fmt.Fprintf(ctx.Err, "Unable to delete job %s: %v\n", job.Name, err)
+				failed = append(failed, job.Name)
+				return
+			}
+			fmt.Fprintf(out, "Deleted job %s\n", job.Name)
+		}(job)
+	}
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to delete %d jobs: %s", len(failed), strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// deleteJob deletes the job along with its service, if any, waiting for the job to be deleted if WaitForDelete is set.
+func (ctx *removeContext) deleteJob(job batchv1.Job) error {
+	if err := ctx.Client.DeleteJob(job.Name); err != nil {
 		return fmt.Errorf("unable to delete job: %w", err)
 	}
 
+	// The service of a distributed job is owned by the job, but is deleted explicitly in case it is orphaned.
+	if service := k8s.HeadlessService(job); service != nil {
+		if err := ctx.Client.DeleteService(service.Name); err != nil {
+			return fmt.Errorf("unable to delete service: %w", err)
+		}
+	}
+
 	if !ctx.WaitForDelete {
 		return nil
 	}
 
-	if err := ctx.WaitUntilJobDeleted(name); err != nil {
+	if err := ctx.WaitUntilJobDeleted(job.Name); err != nil {
 		return fmt.Errorf("timed out waiting for job to be deleted: %w", err)
 	}
 
@@ -69,7 +372,7 @@ func (ctx *removeContext) Run(cmd *cobra.Command, args []string) error {
 }
 
 func (ctx *removeContext) WaitUntilJobDeleted(name string) error {
-	err := wait.Poll(100*time.Millisecond, 120*time.Second, func() (bool, error) {
+	err := wait.Poll(100*time.Millisecond, ctx.DeleteTimeout, func() (bool, error) {
 		job, err := ctx.Client.GetJob(name)
 		if err != nil {
 			return false, err
@@ -80,3 +383,35 @@ func (ctx *removeContext) WaitUntilJobDeleted(name string) error {
 
 	return err
 }
+
+// isPattern reports whether s contains glob metacharacters; see path.Match.
+func isPattern(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
+
+// unmatchedNames returns the exact names among patterns that none of the jobs are named.
+func unmatchedNames(patterns []string, jobs []batchv1.Job) []string {
+	found := map[string]bool{}
+	for _, job := range jobs {
+		found[job.Name] = true
+	}
+
+	var unmatched []string
+	for _, pattern := range patterns {
+		if !isPattern(pattern) && !found[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	return unmatched
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,220 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func removeJobs() []batchv1.Job {
+	lr1 := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-lr-1", Labels: map[string]string{"sweep": "lr"}}}
+	lr1.Status.Failed = 1
+	lr2 := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-lr-2", Labels: map[string]string{"sweep": "lr"}}}
+	lr2.Status.Active = 1
+	other := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "other"}}
+	other.Status.Failed = 1
+
+	return []batchv1.Job{lr1, lr2, other}
+}
+
+func newRemoveContext(client *fake.Client) *removeContext {
+	return &removeContext{
+		CommandContext: cli.CommandContext{Err: &strings.Builder{}, Client: client},
+		Concurrency:    2,
+	}
+}
+
+func TestRemoveRunSingleJob(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	job := removeJobs()[0]
+	client := &fake.Client{}
+	client.On("GetJob", "exp-lr-1").Return(&job, nil)
+	client.On("DeleteJob", "exp-lr-1").Return(nil)
+
+	err := newRemoveContext(client).Run(cmd, []string{"exp-lr-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleting job exp-lr-1...\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestRemoveRunMissingJob(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(nil, nil)
+	client.On("GetCronJob", "foo").Return(nil, nil)
+
+	err := newRemoveContext(client).Run(cmd, []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Nothing to delete: no job named foo found\n", out.String())
+}
+
+func TestRemoveRunCronJob(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	cronJob := batchv1.CronJob{ObjectMeta: metav1.ObjectMeta{Name: "nightly"}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "nightly").Return(nil, nil)
+	client.On("GetCronJob", "nightly").Return(&cronJob, nil)
+	client.On("DeleteCronJob", "nightly").Return(nil)
+
+	err := newRemoveContext(client).Run(cmd, []string{"nightly"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Deleting cron job nightly...\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestRemoveRunCronJobPattern(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	cronJobs := []batchv1.CronJob{
+		{ObjectMeta: metav1.ObjectMeta{Name: "nightly-eval"}, Spec: batchv1.CronJobSpec{Schedule: "0 2 * * *"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "hourly-sync"}, Spec: batchv1.CronJobSpec{Schedule: "@hourly"}},
+	}
+
+	client := &fake.Client{}
+	client.On("ListCronJobs").Return(cronJobs, nil)
+	client.On("DeleteCronJob", "nightly-eval").Return(nil)
+
+	ctx := newRemoveContext(client)
+	ctx.Cron = true
+	ctx.Yes = true
+	err := ctx.Run(cmd, []string{"nightly-*"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Deleted cron job nightly-eval\n")
+	assert.NotContains(t, out.String(), "hourly-sync")
+
+	client.AssertExpectations(t)
+}
+
+func TestRemoveRunPattern(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("y\n"))
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(removeJobs(), nil)
+	client.On("DeleteJob", "exp-lr-1").Return(nil)
+	client.On("DeleteJob", "exp-lr-2").Return(nil)
+
+	err := newRemoveContext(client).Run(cmd, []string{"exp-lr-*"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Delete 2 jobs? [y/N]")
+	assert.Contains(t, out.String(), "Deleted job exp-lr-1\n")
+	assert.Contains(t, out.String(), "Deleted job exp-lr-2\n")
+
+	client.AssertExpectations(t)
+}
+
+func TestRemoveRunMultipleNames(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(removeJobs(), nil)
+	client.On("DeleteJob", "other").Return(nil)
+
+	ctx := newRemoveContext(client)
+	ctx.Yes = true
+
+	err := ctx.Run(cmd, []string{"other", "missing", "nope-*"})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "Nothing to delete: no job named missing found\n"))
+	assert.Contains(t, out.String(), "Deleted job other\n")
+	assert.NotContains(t, out.String(), "nope-*")
+
+	client.AssertExpectations(t)
+
+	// Names that match nothing are reported instead of the generic message.
+	out.Reset()
+	err = ctx.Run(cmd, []string{"missing", "gone"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Nothing to delete: no job named missing found\nNothing to delete: no job named gone found\n", out.String())
+}
+
+func TestRemoveRunSelectorAndStatus(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(removeJobs(), nil)
+	client.On("DeleteJob", "exp-lr-1").Return(nil)
+
+	ctx := newRemoveContext(client)
+	ctx.All = true
+	ctx.Selector = "sweep=lr"
+	ctx.Statuses = []string{"failed"}
+	ctx.Yes = true
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.NotContains(t, out.String(), "[y/N]")
+
+	client.AssertExpectations(t)
+}
+
+func TestRemoveRunDeclined(t *testing.T) {
+	var out strings.Builder
+	cmd := newRemoveCmd()
+	cmd.SetOut(&out)
+	cmd.SetIn(strings.NewReader("n\n"))
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(removeJobs(), nil)
+
+	err := newRemoveContext(client).Run(cmd, []string{"exp-lr-1", "other"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Aborted")
+
+	client.AssertNotCalled(t, "DeleteJob", "exp-lr-1")
+}
+
+func TestRemoveRunPartialFailure(t *testing.T) {
+	cmd := newRemoveCmd()
+	cmd.SetOut(&strings.Builder{})
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return(removeJobs(), nil)
+	client.On("DeleteJob", "exp-lr-1").Return(errors.New("baz"))
+	client.On("DeleteJob", "exp-lr-2").Return(nil)
+	client.On("DeleteJob", "other").Return(nil)
+
+	ctx := newRemoveContext(client)
+	ctx.All = true
+	ctx.Yes = true
+
+	err := ctx.Run(cmd, []string{})
+	assert.EqualError(t, err, "unable to delete 1 jobs: exp-lr-1")
+}
+
+func TestRemoveRunInvalidArguments(t *testing.T) {
+	ctx := newRemoveContext(&fake.Client{})
+
+	err := ctx.Run(newRemoveCmd(), []string{})
+	assert.EqualError(t, err, "job name must be specified")
+
+	ctx.All = true
+	err = ctx.Run(newRemoveCmd(), []string{"foo"})
+	assert.EqualError(t, err, "job names cannot be combined with --all")
+}
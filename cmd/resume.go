@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+type resumeContext struct {
+	cli.CommandContext
+}
+
+func newResumeCmd() *cobra.Command {
+	ctx := &resumeContext{}
+	cmd := &cobra.Command{
+		Use:   "resume <job>",
+		Short: "Resubmit a job as the next attempt of its run",
+		Long: `Resubmit a job as the next attempt of its run.
+
+Jobs submitted with "frink run" start a run, which spans the job and any jobs created to resume it.
+The next attempt is created from the specification of the latest attempt of the run, and is named
+"<job>-attempt-<n>", leaving previous attempts and their logs in place until they are deleted.
+
+The containers of every attempt have FRINK_RUN_ID set to an identifier shared by all attempts of the
+run, and FRINK_ATTEMPT set to the number of the attempt. Resumed attempts also have FRINK_RESUME=1,
+so that e.g. training scripts know to load their latest checkpoint.`,
+		Args: cobra.ExactArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	return cmd
+}
+
+func (ctx *resumeContext) PreRun(cmd *cobra.Command, args []string) error {
+	return ctx.Initialize(cmd)
+}
+
+func (ctx *resumeContext) Run(cmd *cobra.Command, args []string) error {
+	name := args[0]
+	job, err := ctx.Client.GetJob(name)
+	if err != nil {
+		return fmt.Errorf("unable to get job: %w", err)
+	}
+
+	if job == nil {
+		return fmt.Errorf("job %s not found", name)
+	}
+
+	_, err = resumeJob(&ctx.CommandContext, job)
+
+	return err
+}
+
+// resumeJob creates the next attempt of the run of job, based on the latest attempt of the run.
+func resumeJob(ctx *cli.CommandContext, job *batchv1.Job) (*batchv1.Job, error) {
+	latest, err := latestAttempt(ctx.Client, job)
+	if err != nil {
+		return nil, err
+	}
+
+	if k8s.JobStatus(*latest) == k8s.StatusActive {
+		return nil, fmt.Errorf("job %s is still active", latest.Name)
+	}
+
+	resumed, err := k8s.ResumeJob(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(ctx.Out, "Resuming job %s as %s (attempt %d)...\n", latest.Name, resumed.Name, k8s.RunAttempt(*resumed))
+	if err := ctx.Client.CreateJob(resumed); err != nil {
+		return nil, fmt.Errorf("unable to create job: %w", err)
+	}
+
+	if err := k8s.CreateJobService(ctx.Client, resumed); err != nil {
+		return nil, fmt.Errorf("unable to create service: %w", err)
+	}
+
+	return resumed, nil
+}
+
+// latestAttempt returns the attempt of the run of job with the highest attempt number, which may be job itself.
+// Jobs created before runs were recorded are matched with their attempts by UID; see k8s.RunID.
+func latestAttempt(client k8s.Client, job *batchv1.Job) (*batchv1.Job, error) {
+	runID := k8s.RunID(*job)
+	if runID == "" {
+		return job, nil
+	}
+
+	jobs, err := client.ListJobs()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	latest := job
+	for i := range jobs {
+		if k8s.RunID(jobs[i]) == runID && k8s.RunAttempt(jobs[i]) > k8s.RunAttempt(*latest) {
+			latest = &jobs[i]
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/util"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newFailedRunJob(name, attempt string) batchv1.Job {
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:   name,
+		Labels: map[string]string{k8s.RunIDLabel: "abc", k8s.AttemptLabel: attempt},
+	}}
+	job.Status.Failed = 1
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+
+	return job
+}
+
+func TestResumeRunResumesLatestAttempt(t *testing.T) {
+	first := newFailedRunJob("foo", "1")
+	second := newFailedRunJob("foo-attempt-2", "2")
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&first, nil)
+	client.On("ListJobs").Return([]batchv1.Job{first, second}, nil)
+	client.On("CreateJob", mock.MatchedBy(func(job *batchv1.Job) bool {
+		return job.Name == "foo-attempt-3" && job.Labels[k8s.AttemptLabel] == "3"
+	})).Return(nil)
+
+	var out strings.Builder
+	ctx := &resumeContext{CommandContext: cli.CommandContext{Out: &out, Client: client}}
+	err := ctx.Run(newResumeCmd(), []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Resuming job foo-attempt-2 as foo-attempt-3 (attempt 3)...\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestResumeRunUnlabeledJobTwice(t *testing.T) {
+	// A job created before runs were recorded, and its first resumed attempt.
+	first := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "1234"}}
+	first.Status.Failed = 1
+	first.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	second := newFailedRunJob("foo-attempt-2", "2")
+	second.Labels[k8s.RunIDLabel] = "1234"
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&first, nil)
+	client.On("ListJobs").Return([]batchv1.Job{first, second}, nil)
+	client.On("CreateJob", mock.MatchedBy(func(job *batchv1.Job) bool {
+		return job.Name == "foo-attempt-3" && job.Labels[k8s.RunIDLabel] == "1234"
+	})).Return(nil)
+
+	var out strings.Builder
+	ctx := &resumeContext{CommandContext: cli.CommandContext{Out: &out, Client: client}}
+	err := ctx.Run(newResumeCmd(), []string{"foo"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Resuming job foo-attempt-2 as foo-attempt-3 (attempt 3)...\n", out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestResumeRunActiveJob(t *testing.T) {
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	job.Status.Active = 1
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&job, nil)
+
+	ctx := &resumeContext{CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client}}
+	err := ctx.Run(newResumeCmd(), []string{"foo"})
+	assert.EqualError(t, err, "job foo is still active")
+}
+
+func TestResumeRunMissingJob(t *testing.T) {
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(nil, nil)
+
+	ctx := &resumeContext{CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client}}
+	err := ctx.Run(newResumeCmd(), []string{"foo"})
+	assert.EqualError(t, err, "job foo not found")
+}
+
+func TestRunAutoResumeJob(t *testing.T) {
+	first := newFailedRunJob("foo", "1")
+	second := newFailedRunJob("foo-attempt-2", "2")
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&first, nil)
+	client.On("GetJob", "foo-attempt-2").Return(&second, nil)
+	client.On("ListJobs").Return([]batchv1.Job{first}, nil)
+	client.On("CreateJob", mock.MatchedBy(func(job *batchv1.Job) bool { return job.Name == "foo-attempt-2" })).Return(nil)
+
+	var out strings.Builder
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{Out: &out, Client: client},
+		AutoResume:     1,
+		PollInterval:   time.Millisecond,
+	}
+
+	_, err := ctx.AutoResumeJob(&first)
+	assert.EqualError(t, err, "job foo-attempt-2 failed after 2 attempts")
+	assert.Contains(t, out.String(), "Job foo failed\nResuming job foo as foo-attempt-2 (attempt 2)...\n")
+
+	client.AssertExpectations(t)
+}
+
+func TestRunAutoResumeJobFailedFast(t *testing.T) {
+	active := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	active.Spec.BackoffLimit = util.Int32Ptr(3)
+	active.Status.Failed = 1
+	k8s.SetRetryPolicy(&active, k8s.RetryPolicy{Disruptions: true})
+
+	failed := newFailedRunJob("foo", "1")
+	failed.Annotations = map[string]string{k8s.FailedFastAnnotation: "pod foo-a exited with code 1, which is not retried"}
+
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "foo-a"}}
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}},
+	}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "foo").Return(&active, nil).Once()
+	client.On("GetJob", "foo").Return(&failed, nil)
+	client.On("ListJobPods", "foo").Return([]corev1.Pod{pod}, nil)
+	client.On("FailJob", "foo", "pod foo-a exited with code 1, which is not retried").Return(nil)
+
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{Out: &strings.Builder{}, Client: client},
+		AutoResume:     1,
+		PollInterval:   time.Millisecond,
+	}
+
+	// The failure is not retried by the retry policy, so the job is failed and not resumed.
+	_, err := ctx.AutoResumeJob(&active)
+	assert.EqualError(t, err, "job foo failed: pod foo-a exited with code 1, which is not retried")
+
+	client.AssertExpectations(t)
+	client.AssertNotCalled(t, "CreateJob", mock.Anything)
+}
@@ -20,6 +20,7 @@ func newRootCmd() *cobra.Command {
 	pflags := cmd.PersistentFlags()
 	pflags.String("context", "", "name of the kubeconfig context to use")
 	pflags.StringP("namespace", "n", "", "cluster namespace to use")
+	registerFlagCompletions(cmd)
 
 	cmd.AddCommand(newListCmd())
 	cmd.AddCommand(newLogsCmd())
@@ -28,6 +29,17 @@ func newRootCmd() *cobra.Command {
 	cmd.AddCommand(newVersionCmd())
 	cmd.AddCommand(newDebugCmd())
 	cmd.AddCommand(newGPUCmd())
+	cmd.AddCommand(newConfigCmd())
+	cmd.AddCommand(newUsageCmd())
+	cmd.AddCommand(newTopCmd())
+	cmd.AddCommand(newPipelineCmd())
+	cmd.AddCommand(newPruneCmd())
+	cmd.AddCommand(newResumeCmd())
+	cmd.AddCommand(newPauseCmd())
+	cmd.AddCommand(newUnpauseCmd())
+	cmd.AddCommand(newQueueCmd())
+	cmd.AddCommand(newWatchNotifyCmd())
+	cmd.AddCommand(newCompletionCmd())
 	cli.DisableFlagsInUseLine(cmd)
 
 	return cmd
@@ -12,9 +12,14 @@ import (
 	"github.com/spf13/afero"
 	"github.com/spf13/cobra"
 	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
 	"github.com/uitml/frink/internal/k8s"
 	"github.com/uitml/frink/internal/k8s/retry"
+	"github.com/uitml/frink/internal/notify"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
@@ -26,32 +31,234 @@ var backoff = wait.Backoff{
 
 type runContext struct {
 	cli.CommandContext
+	Fs        afero.Fs
 	JobParser k8s.JobParser
 
-	Follow bool
+	// User is the name of the user submitting jobs, used to label them; see k8s.SetUser.
+	User string
+
+	// GPUSource is used to warn about jobs requesting more GPUs than currently free; nil disables the check.
+	GPUSource gpu.Source
+
+	// RunID identifies the run started by the job; see k8s.SetRun. An empty RunID leaves the job unlabeled.
+	RunID string
+
+	// AutoResume is how many times a failed job is resumed; see k8s.ResumeJob.
+	AutoResume   int
+	PollInterval time.Duration
+
+	// Notify sends a notification to Sinks when the job finishes; see notifyJob.
+	Notify   bool
+	Sinks    []notify.Sink
+	LogLines int64
+
+	// Mutate configures the mutators applied to the job before it is submitted.
+	Mutate k8s.MutateOptions
+
+	CheckGPUs     bool
+	WhenAvailable bool
+	WaitInterval  time.Duration
+
+	// Schedule is the cron schedule on which the job is run; see k8s.NewCronJob. An empty Schedule runs the job once.
+	Schedule string
+
+	Suspended   bool
+	Follow      bool
+	Assignments []string
+	Template    bool
+	Profile     string
+	OnConflict  string
+
+	StartTimeout  time.Duration
+	DeleteTimeout time.Duration
+	LogOptions    *corev1.PodLogOptions
 }
 
 func newRunCmd() *cobra.Command {
-	fs := afero.NewOsFs()
-	parser := k8s.NewJobParser(fs)
-	ctx := &runContext{JobParser: parser}
+	ctx := &runContext{
+		Fs:        afero.NewOsFs(),
+		JobParser: k8s.NewJobParser(),
+	}
 
 	cmd := &cobra.Command{
 		Use:   "run <file>",
 		Short: "Schedule a job on the cluster",
+		Long: `Schedule a job on the cluster.
+
+The job specification can be either a full k8s job or a simplified job, in YAML or JSON format.
+Use "-" as the file name to read the specification from stdin.
+
+With --set or --template, references such as ${VAR} and ${VAR:-default} in the specification are
+expanded using values given via --set and the built-in variables USER, DATE, GIT_SHA and RANDOM_SUFFIX.
+Unknown references are left untouched; use $${VAR} to prevent expansion. Without either flag, the
+specification is used as is. With --template, the specification is also rendered as a Go text/template,
+e.g. {{ .lr }}, with the functions default, lower, upper, replace, trim and quote.
+
+The environment is used neither by references nor by templates, since references such as ${HOME} are
+usually meant for the container. Pass values from the environment explicitly, e.g. --set home=$HOME.
+
+Simplified job specifications can extend a profile defined in the "profiles" section of the
+configuration file, either via "extends: <profile>" or --profile, which takes precedence.
+Fields set in the specification override the profile, while env and volumes are merged by name.
+
+If a job with the same name already exists, the --on-conflict policy decides what happens:
+"replace" deletes the existing job, "fail" aborts, and "suffix" appends a random suffix to the name.
+
+With --suspended, the job is submitted paused, and can be started later with "frink unpause".
+
+With --schedule, the job is instead created as a cron job, which runs it on the given schedule, such as
+"0 2 * * *" (every night at 2:00) or "@hourly". Each run creates a job named "<name>-<scheduled time>",
+and a run is skipped while the previous job is still active. Cron jobs are listed with "frink ls --cron",
+and removed, along with their jobs, with "frink rm". With --suspended, the schedule is paused.
+
+With --notify, frink waits for the job (or its last attempt, with --auto-resume) to finish, and sends a
+notification to the sinks configured in the "notify" section of the configuration; see "frink watch-notify".
+
+The job starts a run, and its containers have FRINK_RUN_ID and FRINK_ATTEMPT set; see "frink resume".
+With --auto-resume N, frink waits for the job to finish, and resumes it as the next attempt of the
+run if it fails, up to N times. With --follow, the logs of every attempt are streamed.
+
+While frink waits for a job, with --notify or --auto-resume, the retryOn rules of the job are enforced:
+a pod that fails in a way the rules do not retry fails the job right away, and the job is not resumed.
+
+Before the job is submitted, frink adjusts its specification using the following mutators, and
+prints a summary of each change made: remove-zero-resources, termination-message-policy,
+backoff-limit, and restart-policy. Mutators only set fields that are not already set, unless
+they are forced via --force-mutator, the mutators.force setting, or the frink/force-mutators
+annotation. Likewise, they can be disabled via --disable-mutator, the mutators.disabled setting,
+the frink/disable-mutators annotation, or the disableMutators field of simplified jobs.
+
+With --check-gpus (or the gpu.check setting), a warning is shown if no node currently has as many free
+GPUs as the job requests. The check only asks the gpu-viewer service, unless gpu.source is "native",
+and is skipped if the service cannot be reached. With --when-available, submission is instead held
+locally until a single node has enough free GPUs (of the product in the job's node selector, if any),
+which avoids pending pods clogging the scheduler. GPU availability is then determined as for
+"frink gpu", using the gpu.source setting.`,
+		ValidArgsFunction: completeJobFile,
 
 		PreRunE: ctx.PreRun,
 		RunE:    ctx.Run,
 	}
 
 	flags := cmd.Flags()
+	flags.BoolVar(&ctx.CheckGPUs, "check-gpus", false, "warn if no node currently has enough free GPUs for the job (default from gpu.check)")
+	flags.BoolVar(&ctx.WhenAvailable, "when-available", false, "wait until a node has enough free GPUs before submitting the job")
+	flags.DurationVar(&ctx.WaitInterval, "wait-interval", 30*time.Second, "time between GPU availability checks with --when-available")
+	flags.StringSliceVar(&ctx.Mutate.Disabled, "disable-mutator", nil, "name of a mutator not to apply to the job")
+	flags.StringSliceVar(&ctx.Mutate.Force, "force-mutator", nil, "name of a mutator to apply even to fields set in the job")
+	flags.IntVar(&ctx.AutoResume, "auto-resume", 0, "resume the job if it fails, up to the given number of times")
+	flags.DurationVar(&ctx.PollInterval, "poll-interval", 10*time.Second, "time between checking the status of the job with --auto-resume")
+	flags.StringVar(&ctx.Schedule, "schedule", "", "run the job on a cron schedule, e.g. \"0 2 * * *\"")
+	flags.BoolVar(&ctx.Notify, "notify", false, "wait for the job to finish, and send a notification")
+	flags.BoolVar(&ctx.Suspended, "suspended", false, "submit the job paused, to be started later with \"frink unpause\"")
 	flags.BoolVarP(&ctx.Follow, "follow", "f", false, "wait for job to start, then stream logs")
+	flags.StringArrayVar(&ctx.Assignments, "set", nil, "set a variable used in the job specification (key=value)")
+	flags.BoolVar(&ctx.Template, "template", false, "render the job specification as a Go template")
+	flags.StringVar(&ctx.Profile, "profile", "", "name of the profile the job specification extends")
+	flags.StringVar(&ctx.OnConflict, "on-conflict", "", "what to do if the job already exists: replace|fail|suffix")
 
 	return cmd
 }
 
 func (ctx *runContext) PreRun(cmd *cobra.Command, args []string) error {
-	return ctx.Initialize(cmd)
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	if ctx.OnConflict == "" {
+		ctx.OnConflict = ctx.Config.OnConflict
+	}
+	ctx.StartTimeout = ctx.Config.Timeouts.Start
+	ctx.DeleteTimeout = ctx.Config.Timeouts.Delete
+	ctx.LogOptions = ctx.Config.Logs.PodLogOptions()
+	ctx.Mutate.Disabled = append(ctx.Mutate.Disabled, ctx.Config.Mutators.Disabled...)
+	ctx.Mutate.Force = append(ctx.Mutate.Force, ctx.Config.Mutators.Force...)
+	ctx.Sinks = ctx.Config.Notify.Sinks()
+	ctx.LogLines = ctx.Config.Notify.LogLines
+	// Jobs are submitted unlabeled if the user cannot be determined, which is not worth failing for.
+	ctx.User, _ = k8s.CurrentUser()
+	ctx.RunID = k8s.NewRunID()
+
+	parser, err := ctx.newJobParser()
+	if err != nil {
+		return err
+	}
+	ctx.JobParser = parser
+
+	if !cmd.Flags().Changed("check-gpus") {
+		ctx.CheckGPUs = ctx.Config.GPU.Check
+	}
+	if ctx.CheckGPUs || ctx.WhenAvailable {
+		url := gpuServiceURL("", ctx.Config)
+		name := ctx.Config.GPU.Source
+		if !ctx.WhenAvailable && (name == "" || name == "auto") {
+			// The check is advisory, so it is skipped rather than listing every node and pod in the cluster
+			// when the service cannot be reached.
+			name = "service"
+		}
+		source, err := newGPUSource(&ctx.CommandContext, name, url, 5*time.Second, nil)
+		if err != nil && ctx.WhenAvailable {
+			return err
+		}
+		if err == nil {
+			ctx.GPUSource = source
+		}
+	}
+
+	return nil
+}
+
+// printChanges prints a summary of the changes made to the job by mutators.
+func printChanges(w io.Writer, name string, changes []k8s.Change) {
+	if len(changes) == 0 {
+		return
+	}
+
+	fmt.Fprintf(w, "Adjusted job %s:\n", name)
+	for _, change := range changes {
+		fmt.Fprintf(w, "  - %s\n", change)
+	}
+}
+
+func (ctx *runContext) newJobParser() (k8s.JobParser, error) {
+	return newJobParser(ctx.Config, ctx.Assignments, ctx.Template, ctx.Profile)
+}
+
+// newJobParser returns a JobParser expanding the variables set by assignments, as well as templates if enabled,
+// and applying the profiles and defaults of the configuration.
+func newJobParser(cfg *cli.Config, assignments []string, template bool, profile string) (k8s.JobParser, error) {
+	set, err := k8s.ParseAssignments(assignments)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := k8s.NewVariables(set)
+	if err != nil {
+		return nil, err
+	}
+
+	// Expansion is opt-in, since specifications written before it existed may contain ${VAR} or $$ meant for the shell.
+	var expanders []k8s.Expander
+	if len(set) > 0 || template {
+		expanders = append(expanders, k8s.VariableExpander(vars))
+	}
+	if template {
+		expanders = append(expanders, k8s.TemplateExpander(vars))
+	}
+
+	opts := k8s.ParserOptions{
+		Expanders:      expanders,
+		Profiles:       cfg.Profiles,
+		Profile:        profile,
+		DefaultProfile: cfg.DefaultProfile,
+		DefaultImage:   cfg.DefaultImage,
+
+		DefaultTTL:      cfg.Jobs.DefaultTTL(),
+		DefaultDeadline: cfg.Jobs.DefaultDeadline(),
+		DefaultRetries:  cfg.Jobs.Retries,
+	}
+
+	return k8s.NewJobParserWithOptions(opts), nil
 }
 
 func (ctx *runContext) Run(cmd *cobra.Command, args []string) error {
@@ -59,16 +266,53 @@ func (ctx *runContext) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("job specification file must be specified")
 	}
 
-	job, err := ctx.JobParser.Parse(args[0])
+	if ctx.Suspended && (ctx.Follow || ctx.AutoResume > 0 || ctx.WhenAvailable) {
+		return fmt.Errorf("--suspended cannot be combined with --follow, --auto-resume or --when-available")
+	}
+
+	if ctx.Schedule != "" && (ctx.Follow || ctx.AutoResume > 0 || ctx.WhenAvailable) {
+		return fmt.Errorf("--schedule cannot be combined with --follow, --auto-resume or --when-available")
+	}
+
+	if ctx.Notify && (ctx.Suspended || ctx.Schedule != "") {
+		return fmt.Errorf("--notify cannot be combined with --suspended or --schedule")
+	}
+
+	if ctx.Notify && len(ctx.Sinks) == 0 {
+		return errNoSinks
+	}
+
+	job, err := ctx.ParseJob(cmd, args[0])
 	if err != nil {
 		return fmt.Errorf("unable to parse job: %w", err)
 	}
 
-	// TODO: Reconsider this? Many reasons to avoid this; should be challenged.
-	k8s.OverrideJobSpec(job)
+	changes, err := k8s.MutateJob(job, ctx.Mutate)
+	if err != nil {
+		return fmt.Errorf("unable to mutate job: %w", err)
+	}
+	printChanges(ctx.Out, job.Name, changes)
+	k8s.SetUser(job, ctx.User)
+	if ctx.Suspended {
+		suspend := true
+		job.Spec.Suspend = &suspend
+	}
 
-	if err := ctx.DeletePreviousJob(job.Name); err != nil {
-		return fmt.Errorf("unable to delete previous job: %w", err)
+	if ctx.Schedule != "" {
+		return ctx.ScheduleJob(job)
+	}
+	k8s.SetRun(job, ctx.RunID)
+
+	if ctx.WhenAvailable {
+		if err := ctx.WaitUntilGPUsAvailable(job); err != nil {
+			return fmt.Errorf("unable to wait for GPUs: %w", err)
+		}
+	} else if !ctx.Suspended {
+		ctx.WarnIfGPUsUnavailable(job)
+	}
+
+	if err := ctx.ResolveConflict(job); err != nil {
+		return err
 	}
 
 	// Try to create the job using retry.
@@ -79,17 +323,80 @@ func (ctx *runContext) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("unable to create job: %w", err)
 	}
 
-	if !ctx.Follow {
-		return nil
+	if err := k8s.CreateJobService(ctx.Client, job); err != nil {
+		return fmt.Errorf("unable to create service: %w", err)
+	}
+
+	var finished *batchv1.Job
+	switch {
+	case ctx.AutoResume > 0:
+		finished, err = ctx.AutoResumeJob(job)
+	case ctx.Follow:
+		err = ctx.FollowLogs(job.Name)
+		if err == nil && ctx.Notify {
+			finished, err = ctx.WaitUntilJobFinished(job.Name)
+		}
+	case ctx.Notify:
+		finished, err = ctx.WaitUntilJobFinished(job.Name)
+	}
+
+	// A notification is also sent if the last attempt of an auto-resumed job failed.
+	if ctx.Notify && finished != nil {
+		if notifyErr := notifyJob(&ctx.CommandContext, ctx.Sinks, ctx.LogLines, finished); err == nil {
+			err = notifyErr
+		}
+	}
+
+	return err
+}
+
+// ScheduleJob creates a cron job running the job on the Schedule, handling an existing cron job with the same name
+// according to the OnConflict policy. Jobs created by the cron job are independent runs, so job is not labeled with a run.
+func (ctx *runContext) ScheduleJob(job *batchv1.Job) error {
+	cronJob, err := k8s.NewCronJob(job, ctx.Schedule)
+	if err != nil {
+		return err
+	}
+
+	existing, err := ctx.Client.GetCronJob(cronJob.Name)
+	if err != nil {
+		return fmt.Errorf("unable to get cron job: %w", err)
+	}
+
+	if existing != nil {
+		switch ctx.OnConflict {
+		case "", "replace":
+			if err := ctx.Client.DeleteCronJob(existing.Name); err != nil {
+				return fmt.Errorf("unable to delete previous cron job: %w", err)
+			}
+		case "fail":
+			return fmt.Errorf("cron job %s already exists", cronJob.Name)
+		case "suffix":
+			cronJob.Name = fmt.Sprintf("%s-%s", cronJob.Name, rand.String(5))
+			fmt.Fprintf(ctx.Out, "Cron job already exists; using name %s\n", cronJob.Name)
+		default:
+			return fmt.Errorf("unknown conflict policy %q (use replace, fail or suffix)", ctx.OnConflict)
+		}
+	}
+
+	fmt.Fprintf(ctx.Out, "Creating cron job %s with schedule %q...\n", cronJob.Name, cronJob.Spec.Schedule)
+	err = retry.OnExists(backoff, func() error { return ctx.Client.CreateCronJob(cronJob) })
+	if err != nil {
+		return fmt.Errorf("unable to create cron job: %w", err)
 	}
 
-	if err := ctx.WaitUntilJobStarted(job.Name); err != nil {
+	return nil
+}
+
+// FollowLogs waits for the job to start, and then streams its logs.
+func (ctx *runContext) FollowLogs(name string) error {
+	if err := ctx.WaitUntilJobStarted(name); err != nil {
 		return fmt.Errorf("timed out waiting for job to start: %w", err)
 	}
 
 	// TODO: Ensure nil references are properly handled in this block.
-	err = retry.OnError(backoff, apierrors.IsBadRequest, func() error {
-		req, err := ctx.Client.GetJobLogs(job.Name, k8s.DefaultLogOptions)
+	err := retry.OnError(backoff, apierrors.IsBadRequest, func() error {
+		req, err := ctx.Client.GetJobLogs(name, ctx.logOptions())
 		if err != nil {
 			return errors.Unwrap(err)
 		}
@@ -118,6 +425,231 @@ func (ctx *runContext) Run(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// AutoResumeJob waits for the job to finish, resuming it each time it fails, up to AutoResume times,
+// and returns the last attempt that finished, if any. Logs of each attempt are followed if Follow is set.
+func (ctx *runContext) AutoResumeJob(job *batchv1.Job) (*batchv1.Job, error) {
+	var finished *batchv1.Job
+	for resumes := 0; ; resumes++ {
+		if ctx.Follow {
+			if err := ctx.FollowLogs(job.Name); err != nil {
+				return finished, err
+			}
+		}
+
+		attempt, err := ctx.WaitUntilJobFinished(job.Name)
+		if err != nil {
+			return finished, err
+		}
+		finished = attempt
+
+		if k8s.JobStatus(*finished) != k8s.StatusFailed {
+			fmt.Fprintf(ctx.Out, "Job %s succeeded\n", finished.Name)
+			return finished, nil
+		}
+
+		// Failures that the retry policy of the job does not retry are not resumed either.
+		if reason := k8s.JobFailedFast(*finished); reason != "" {
+			return finished, fmt.Errorf("job %s failed: %s", finished.Name, reason)
+		}
+
+		if resumes == ctx.AutoResume {
+			return finished, fmt.Errorf("job %s failed after %d attempts", finished.Name, k8s.RunAttempt(*finished))
+		}
+
+		fmt.Fprintf(ctx.Out, "Job %s failed\n", finished.Name)
+		if job, err = resumeJob(&ctx.CommandContext, finished); err != nil {
+			return finished, err
+		}
+	}
+}
+
+// WaitUntilJobFinished polls the job until it has succeeded or failed, and returns the finished job.
+func (ctx *runContext) WaitUntilJobFinished(name string) (*batchv1.Job, error) {
+	return waitUntilJobFinished(ctx.Client, name, ctx.PollInterval)
+}
+
+// ParseJob reads and parses the job specification identified by filename, where "-" denotes stdin.
+func (ctx *runContext) ParseJob(cmd *cobra.Command, filename string) (*batchv1.Job, error) {
+	r, err := k8s.OpenSpec(ctx.Fs, cmd.InOrStdin(), filename)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	source := filename
+	if filename == k8s.StdinSource {
+		source = "stdin"
+	}
+
+	return ctx.JobParser.Parse(r, source)
+}
+
+// ResolveConflict handles an existing job with the same name as job, according to the OnConflict policy.
+func (ctx *runContext) ResolveConflict(job *batchv1.Job) error {
+	switch ctx.OnConflict {
+	case "", "replace":
+		if err := ctx.DeletePreviousJob(job.Name); err != nil {
+			return fmt.Errorf("unable to delete previous job: %w", err)
+		}
+	case "fail":
+		existing, err := ctx.Client.GetJob(job.Name)
+		if err != nil {
+			return fmt.Errorf("unable to get job: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("job %s already exists", job.Name)
+		}
+	case "suffix":
+		existing, err := ctx.Client.GetJob(job.Name)
+		if err != nil {
+			return fmt.Errorf("unable to get job: %w", err)
+		}
+		if existing != nil {
+			k8s.Rename(job, fmt.Sprintf("%s-%s", job.Name, rand.String(5)))
+			fmt.Fprintf(ctx.Out, "Job already exists; using name %s\n", job.Name)
+		}
+	default:
+		return fmt.Errorf("unknown conflict policy %q (use replace, fail or suffix)", ctx.OnConflict)
+	}
+
+	return nil
+}
+
+// WarnIfGPUsUnavailable warns if no node currently has as many free GPUs as requested by the job.
+// The check is advisory, so failing to determine GPU availability is not considered an error.
+func (ctx *runContext) WarnIfGPUsUnavailable(job *batchv1.Job) {
+	if ctx.GPUSource == nil {
+		return
+	}
+
+	check, err := ctx.checkGPUs(job)
+	if err != nil || check.Available() {
+		return
+	}
+
+	if check.Pods > 1 {
+		fmt.Fprintf(ctx.Err, "Warning: the job needs %s, but only %d currently do; the job will be pending until enough GPUs are free\n", check.Nodes(), check.Fitting)
+		return
+	}
+
+	fmt.Fprintf(ctx.Err, "Warning: no node currently has %s (at most %d on a single node); the job will be pending until enough GPUs are free\n", check, check.MaxFree)
+}
+
+// maxGPUCheckFailures is how many times in a row determining GPU availability may fail before waiting is given up.
+const maxGPUCheckFailures = 10
+
+// WaitUntilGPUsAvailable blocks until enough nodes have as many free GPUs as requested by the pods of the job.
+// Failing to determine availability, e.g. due to a timeout, is retried, unless it fails maxGPUCheckFailures times in a row.
+func (ctx *runContext) WaitUntilGPUsAvailable(job *batchv1.Job) error {
+	if ctx.GPUSource == nil {
+		return fmt.Errorf("GPU availability source not configured")
+	}
+
+	interval := ctx.WaitInterval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	for waiting, failures := false, 0; ; waiting = true {
+		check, err := ctx.checkGPUs(job)
+		if err != nil {
+			failures++
+			if failures >= maxGPUCheckFailures {
+				return err
+			}
+
+			fmt.Fprintf(ctx.Err, "Unable to determine GPU availability: %v; retrying in %s\n", err, interval)
+			time.Sleep(interval)
+			continue
+		}
+		failures = 0
+
+		if check.Available() {
+			if waiting {
+				fmt.Fprintf(ctx.Out, "GPUs available; submitting job\n")
+			}
+			return nil
+		}
+
+		if !waiting {
+			fmt.Fprintf(ctx.Out, "Waiting for %s (checking every %s)...\n", check.Nodes(), interval)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// gpuCheck is the result of comparing the GPUs requested by a job with the current GPU availability.
+type gpuCheck struct {
+	// Requested is the number of GPUs requested by each pod.
+	Requested int64
+	Product   string
+
+	// Pods is the number of pods that must run at the same time, each on its own node; see k8s.IsDistributed.
+	Pods int
+
+	// MaxFree is the largest number of free GPUs on a single node, and Fitting the number of nodes with Requested free GPUs.
+	MaxFree int64
+	Fitting int
+}
+
+// Available reports whether enough nodes have as many free GPUs as requested.
+func (c gpuCheck) Available() bool {
+	return c.Fitting >= c.Pods
+}
+
+func (c gpuCheck) String() string {
+	if c.Product != "" {
+		return fmt.Sprintf("%d free %s GPUs", c.Requested, c.Product)
+	}
+
+	return fmt.Sprintf("%d free GPUs", c.Requested)
+}
+
+// Nodes describes the nodes needed by the job, e.g. "a node with 4 free GPUs" or "2 nodes with 4 free GPUs each".
+func (c gpuCheck) Nodes() string {
+	if c.Pods > 1 {
+		return fmt.Sprintf("%d nodes with %s each", c.Pods, c)
+	}
+
+	return fmt.Sprintf("a node with %s", c)
+}
+
+// checkGPUs compares the GPUs requested by the job with the current availability.
+// Jobs not requesting GPUs are always considered available, without determining the availability.
+// The pods of distributed jobs are placed on separate nodes, so each of them needs a node with enough free GPUs.
+func (ctx *runContext) checkGPUs(job *batchv1.Job) (gpuCheck, error) {
+	requested, product := gpu.PodSpecRequest(job.Spec.Template.Spec)
+	check := gpuCheck{Requested: requested, Product: product, Pods: 1}
+	if k8s.IsDistributed(*job) && job.Spec.Parallelism != nil {
+		check.Pods = int(*job.Spec.Parallelism)
+	}
+	if requested == 0 {
+		check.Fitting = check.Pods
+		return check, nil
+	}
+
+	availability, err := ctx.GPUSource.Availability(context.Background())
+	if err != nil {
+		return check, err
+	}
+
+	if product != "" {
+		availability = availability.Filter(product)
+	}
+	check.MaxFree = availability.MaxFree()
+	check.Fitting = availability.NodesWithFree(requested)
+
+	return check, nil
+}
+
+func (ctx *runContext) logOptions() *corev1.PodLogOptions {
+	if ctx.LogOptions == nil {
+		return k8s.DefaultLogOptions
+	}
+
+	return ctx.LogOptions
+}
+
 func (ctx *runContext) DeletePreviousJob(name string) error {
 	job, err := ctx.Client.GetJob(name)
 	if err != nil {
@@ -140,7 +672,7 @@ func (ctx *runContext) DeletePreviousJob(name string) error {
 }
 
 func (ctx *runContext) WaitUntilJobDeleted(name string) error {
-	err := wait.Poll(100*time.Millisecond, 120*time.Second, func() (bool, error) {
+	err := wait.Poll(100*time.Millisecond, ctx.DeleteTimeout, func() (bool, error) {
 		job, err := ctx.Client.GetJob(name)
 		if err != nil {
 			return false, err
@@ -153,7 +685,7 @@ func (ctx *runContext) WaitUntilJobDeleted(name string) error {
 }
 
 func (ctx *runContext) WaitUntilJobStarted(name string) error {
-	err := wait.Poll(100*time.Millisecond, 120*time.Second, func() (bool, error) {
+	err := wait.Poll(100*time.Millisecond, ctx.StartTimeout, func() (bool, error) {
 		job, err := ctx.Client.GetJob(name)
 		if err != nil {
 			return false, err
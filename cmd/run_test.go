@@ -1,15 +1,23 @@
 package cmd
 
 import (
+	"context"
 	"errors"
+	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
 	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
 	"github.com/uitml/frink/internal/k8s"
 	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // Top-level functionality.
@@ -23,6 +31,28 @@ func TestRunPreRun(t *testing.T) {
 	assert.NotNil(t, ctx.Client)
 }
 
+func TestRunPreRunCheckGPUs(t *testing.T) {
+	ctx := &runContext{}
+	cmd := newRunCmd()
+
+	ctx.PreRun(cmd, []string{})
+	assert.False(t, ctx.CheckGPUs)
+	assert.Nil(t, ctx.GPUSource)
+
+	// With the auto source, the check only uses the service, without falling back to listing the cluster.
+	os.Setenv("FRINK_GPU_CHECK", "true")
+	defer os.Unsetenv("FRINK_GPU_CHECK")
+	os.Setenv("FRINK_GPU_URL", "http://127.0.0.1:1")
+	defer os.Unsetenv("FRINK_GPU_URL")
+
+	ctx = &runContext{}
+	cmd = newRunCmd()
+
+	ctx.PreRun(cmd, []string{})
+	assert.True(t, ctx.CheckGPUs)
+	assert.IsType(t, &gpu.ServiceClient{}, ctx.GPUSource)
+}
+
 func TestRunRunNewJob(t *testing.T) {
 	var out strings.Builder
 	cmd := newRunCmd()
@@ -31,7 +61,7 @@ func TestRunRunNewJob(t *testing.T) {
 	client := &fake.Client{}
 
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := k8s.NewJobParser(fs)
+	parser := k8s.NewJobParser()
 
 	ctx := &runContext{
 		CommandContext: cli.CommandContext{
@@ -39,17 +69,19 @@ func TestRunRunNewJob(t *testing.T) {
 			Err:    cmd.ErrOrStderr(),
 			Client: client,
 		},
+		Fs:        fs,
 		JobParser: parser,
 	}
 
 	filename := "job.yaml"
-	job, _ := parser.Parse(filename)
-	k8s.OverrideJobSpec(job)
+	job, _ := ctx.ParseJob(cmd, filename)
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
 
 	client.On("GetJob", job.Name).Return(nil, nil)
 	client.On("CreateJob", job).Return(nil)
 
-	err := ctx.Run(cmd, []string{filename})
+	err = ctx.Run(cmd, []string{filename})
 	assert.NoError(t, err)
 }
 
@@ -61,7 +93,7 @@ func TestRunRunExistingJob(t *testing.T) {
 	client := &fake.Client{}
 
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := k8s.NewJobParser(fs)
+	parser := k8s.NewJobParser()
 
 	ctx := &runContext{
 		CommandContext: cli.CommandContext{
@@ -69,19 +101,21 @@ func TestRunRunExistingJob(t *testing.T) {
 			Err:    cmd.ErrOrStderr(),
 			Client: client,
 		},
+		Fs:        fs,
 		JobParser: parser,
 	}
 
 	filename := "job.yaml"
-	job, _ := parser.Parse(filename)
-	k8s.OverrideJobSpec(job)
+	job, _ := ctx.ParseJob(cmd, filename)
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
 
 	client.On("GetJob", job.Name).Return(job, nil).Once() // Only return once to emulate deletion
 	client.On("GetJob", job.Name).Return(nil, nil)
 	client.On("DeleteJob", job.Name).Return(nil)
 	client.On("CreateJob", job).Return(nil)
 
-	err := ctx.Run(cmd, []string{filename})
+	err = ctx.Run(cmd, []string{filename})
 	assert.NoError(t, err)
 
 	client.AssertExpectations(t)
@@ -95,7 +129,7 @@ func TestRunRunMissingArgument(t *testing.T) {
 	client := &fake.Client{}
 
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := k8s.NewJobParser(fs)
+	parser := k8s.NewJobParser()
 
 	ctx := &runContext{
 		CommandContext: cli.CommandContext{
@@ -103,6 +137,7 @@ func TestRunRunMissingArgument(t *testing.T) {
 			Err:    cmd.ErrOrStderr(),
 			Client: client,
 		},
+		Fs:        fs,
 		JobParser: parser,
 	}
 
@@ -120,7 +155,7 @@ func TestRunRunCreationError(t *testing.T) {
 	client := &fake.Client{}
 
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := k8s.NewJobParser(fs)
+	parser := k8s.NewJobParser()
 
 	ctx := &runContext{
 		CommandContext: cli.CommandContext{
@@ -128,18 +163,357 @@ func TestRunRunCreationError(t *testing.T) {
 			Err:    cmd.ErrOrStderr(),
 			Client: client,
 		},
+		Fs:        fs,
 		JobParser: parser,
 	}
 
 	filename := "job.yaml"
-	job, _ := parser.Parse(filename)
-	k8s.OverrideJobSpec(job)
+	job, _ := ctx.ParseJob(cmd, filename)
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
 
 	client.On("GetJob", job.Name).Return(nil, nil)
 	client.On("CreateJob", job).Return(errors.New("baz"))
 
-	err := ctx.Run(cmd, []string{filename})
+	err = ctx.Run(cmd, []string{filename})
 	assert.EqualError(t, err, "unable to create job: baz")
 
 	client.AssertExpectations(t)
 }
+
+func TestRunRunFromStdin(t *testing.T) {
+	var out strings.Builder
+	cmd := newRunCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+
+	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
+	parser := k8s.NewJobParser()
+
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    cmd.OutOrStderr(),
+			Err:    cmd.ErrOrStderr(),
+			Client: client,
+		},
+		Fs:        fs,
+		JobParser: parser,
+	}
+
+	job, _ := ctx.ParseJob(cmd, "job.yaml")
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
+
+	spec, _ := afero.ReadFile(fs, "job.yaml")
+	cmd.SetIn(strings.NewReader(string(spec)))
+
+	client.On("GetJob", job.Name).Return(nil, nil)
+	client.On("CreateJob", job).Return(nil)
+
+	err = ctx.Run(cmd, []string{"-"})
+	assert.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
+
+func TestRunNewJobParserExpansionOptIn(t *testing.T) {
+	spec := "name: exp-${lr:-1}\nimage: ubuntu:latest\ncommand: [sh, -c, 'echo ${HOME}; kill $$']\n"
+
+	parser, err := newJobParser(&cli.Config{}, nil, false, "")
+	assert.NoError(t, err)
+	job, err := parser.Parse(strings.NewReader(spec), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "exp-${lr:-1}", job.Name)
+	assert.Equal(t, "echo ${HOME}; kill $$", job.Spec.Template.Spec.Containers[0].Command[2])
+
+	parser, err = newJobParser(&cli.Config{}, []string{"lr=3"}, false, "")
+	assert.NoError(t, err)
+	job, err = parser.Parse(strings.NewReader(spec), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "exp-3", job.Name)
+	assert.Equal(t, "echo ${HOME}; kill $", job.Spec.Template.Spec.Containers[0].Command[2])
+}
+
+func TestRunResolveConflict(t *testing.T) {
+	client := &fake.Client{}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    &strings.Builder{},
+			Client: client,
+		},
+	}
+
+	existing := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	client.On("GetJob", "foo").Return(existing, nil)
+
+	ctx.OnConflict = "fail"
+	err := ctx.ResolveConflict(existing.DeepCopy())
+	assert.EqualError(t, err, "job foo already exists")
+
+	ctx.OnConflict = "suffix"
+	job := existing.DeepCopy()
+	err = ctx.ResolveConflict(job)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^foo-[a-z0-9]{5}$", job.Name)
+
+	ctx.OnConflict = "bogus"
+	err = ctx.ResolveConflict(existing.DeepCopy())
+	assert.Error(t, err)
+}
+
+func TestRunWarnIfGPUsUnavailable(t *testing.T) {
+	var errOut strings.Builder
+	client := &fake.Client{}
+	client.On("ListNodes").Return([]corev1.Node{gpuNode}, nil)
+	client.On("ListActivePods").Return([]corev1.Pod{}, nil)
+
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    &strings.Builder{},
+			Err:    &errOut,
+			Client: client,
+		},
+		GPUSource: &gpu.ClusterSource{Client: client},
+	}
+
+	job := &batchv1.Job{}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{gpu.ResourceName: resource.MustParse("4")},
+		},
+	}}
+
+	ctx.WarnIfGPUsUnavailable(job)
+	assert.Empty(t, errOut.String())
+
+	job.Spec.Template.Spec.Containers[0].Resources.Limits[gpu.ResourceName] = resource.MustParse("16")
+	ctx.WarnIfGPUsUnavailable(job)
+	assert.Contains(t, errOut.String(), "no node currently has 16 free GPUs (at most 8 on a single node)")
+
+	errOut.Reset()
+	job.Spec.Template.Spec.Containers[0].Resources.Limits[gpu.ResourceName] = resource.MustParse("1")
+	job.Spec.Template.Spec.NodeSelector = map[string]string{gpu.ProductLabel: "V100"}
+	ctx.WarnIfGPUsUnavailable(job)
+	assert.Contains(t, errOut.String(), "no node currently has 1 free V100 GPUs (at most 0 on a single node)")
+}
+
+// sequenceSource returns the given availabilities in turn, repeating the last one.
+type sequenceSource struct {
+	availabilities []*gpu.Availability
+	calls          int
+}
+
+func (s *sequenceSource) Availability(ctx context.Context) (*gpu.Availability, error) {
+	i := s.calls
+	if i >= len(s.availabilities) {
+		i = len(s.availabilities) - 1
+	}
+	s.calls++
+
+	return s.availabilities[i], nil
+}
+
+func TestRunWaitUntilGPUsAvailable(t *testing.T) {
+	var out strings.Builder
+	source := &sequenceSource{availabilities: []*gpu.Availability{
+		{Nodes: []gpu.Node{{Name: "a", Product: "A100", Total: 8, Free: 1}}},
+		{Nodes: []gpu.Node{{Name: "a", Product: "A100", Total: 8, Free: 2}}},
+		{Nodes: []gpu.Node{{Name: "a", Product: "A100", Total: 8, Free: 4}}},
+	}}
+
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{Out: &out},
+		GPUSource:      source,
+		WaitInterval:   time.Millisecond,
+	}
+
+	job := &batchv1.Job{}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{gpu.ResourceName: resource.MustParse("4")},
+		},
+	}}
+
+	err := ctx.WaitUntilGPUsAvailable(job)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, source.calls)
+	assert.Contains(t, out.String(), "Waiting for a node with 4 free GPUs")
+	assert.Contains(t, out.String(), "GPUs available; submitting job")
+}
+
+// flakySource fails the given number of times before returning availability, or fails forever if failures is negative.
+type flakySource struct {
+	failures     int
+	availability *gpu.Availability
+	calls        int
+}
+
+func (s *flakySource) Availability(ctx context.Context) (*gpu.Availability, error) {
+	s.calls++
+	if s.failures < 0 || s.calls <= s.failures {
+		return nil, errors.New("timeout")
+	}
+
+	return s.availability, nil
+}
+
+func TestRunWaitUntilGPUsAvailableRetriesErrors(t *testing.T) {
+	var errOut strings.Builder
+	source := &flakySource{failures: 2, availability: &gpu.Availability{Nodes: []gpu.Node{{Name: "a", Total: 8, Free: 4}}}}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{Out: &strings.Builder{}, Err: &errOut},
+		GPUSource:      source,
+		WaitInterval:   time.Millisecond,
+	}
+
+	job := &batchv1.Job{}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{gpu.ResourceName: resource.MustParse("4")},
+		},
+	}}
+
+	err := ctx.WaitUntilGPUsAvailable(job)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, source.calls)
+	assert.Contains(t, errOut.String(), "Unable to determine GPU availability: timeout")
+
+	source = &flakySource{failures: -1}
+	ctx.GPUSource = source
+	err = ctx.WaitUntilGPUsAvailable(job)
+	assert.EqualError(t, err, "timeout")
+	assert.Equal(t, maxGPUCheckFailures, source.calls)
+}
+
+func TestRunWaitUntilGPUsAvailableDistributed(t *testing.T) {
+	var out strings.Builder
+	source := &sequenceSource{availabilities: []*gpu.Availability{
+		{Nodes: []gpu.Node{{Name: "a", Total: 8, Free: 8}, {Name: "b", Total: 8, Free: 2}}},
+		{Nodes: []gpu.Node{{Name: "a", Total: 8, Free: 8}, {Name: "b", Total: 8, Free: 4}}},
+	}}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{Out: &out},
+		GPUSource:      source,
+		WaitInterval:   time.Millisecond,
+	}
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "train"}}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{gpu.ResourceName: resource.MustParse("4")},
+		},
+	}}
+	k8s.SetDistributed(job, 2, 4)
+
+	err := ctx.WaitUntilGPUsAvailable(job)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, source.calls, "a single node with 8 free GPUs does not fit two pods")
+	assert.Contains(t, out.String(), "Waiting for 2 nodes with 4 free GPUs each")
+}
+
+func TestRunRunDistributedJob(t *testing.T) {
+	var out strings.Builder
+	cmd := newRunCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    cmd.OutOrStderr(),
+			Err:    cmd.ErrOrStderr(),
+			Client: client,
+		},
+		Fs:        afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		JobParser: k8s.NewJobParser(),
+	}
+
+	job, _ := ctx.ParseJob(cmd, "distributed.yaml")
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
+	created := job.DeepCopy()
+	created.UID = "1234"
+
+	client.On("GetJob", job.Name).Return(nil, nil).Once()
+	client.On("GetJob", job.Name).Return(created, nil)
+	client.On("CreateJob", job).Return(nil)
+	client.On("CreateService", k8s.HeadlessService(*created)).Return(nil)
+
+	err = ctx.Run(cmd, []string{"distributed.yaml"})
+	assert.NoError(t, err)
+
+	client.AssertExpectations(t)
+}
+
+func TestRunRunSuspended(t *testing.T) {
+	cmd := newRunCmd()
+	cmd.SetOut(&strings.Builder{})
+
+	client := &fake.Client{}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    cmd.OutOrStderr(),
+			Err:    cmd.ErrOrStderr(),
+			Client: client,
+		},
+		Fs:        afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		JobParser: k8s.NewJobParser(),
+		Suspended: true,
+	}
+
+	job, _ := ctx.ParseJob(cmd, "job.yaml")
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
+	suspend := true
+	job.Spec.Suspend = &suspend
+
+	client.On("GetJob", job.Name).Return(nil, nil)
+	client.On("CreateJob", job).Return(nil)
+
+	err = ctx.Run(cmd, []string{"job.yaml"})
+	assert.NoError(t, err)
+
+	ctx.Follow = true
+	err = ctx.Run(cmd, []string{"job.yaml"})
+	assert.EqualError(t, err, "--suspended cannot be combined with --follow, --auto-resume or --when-available")
+
+	client.AssertExpectations(t)
+}
+
+func TestRunRunScheduled(t *testing.T) {
+	var out strings.Builder
+	cmd := newRunCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    cmd.OutOrStderr(),
+			Err:    cmd.ErrOrStderr(),
+			Client: client,
+		},
+		Fs:        afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		JobParser: k8s.NewJobParser(),
+		RunID:     "abc",
+		Schedule:  "0 2 * * *",
+	}
+
+	job, _ := ctx.ParseJob(cmd, "job.yaml")
+	_, err := k8s.MutateJob(job, k8s.MutateOptions{})
+	assert.NoError(t, err)
+	cronJob, _ := k8s.NewCronJob(job, "0 2 * * *")
+
+	client.On("GetCronJob", job.Name).Return(nil, nil)
+	client.On("CreateCronJob", cronJob).Return(nil)
+
+	err = ctx.Run(cmd, []string{"job.yaml"})
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "Creating cron job foo with schedule \"0 2 * * *\"...\n")
+
+	ctx.Follow = true
+	err = ctx.Run(cmd, []string{"job.yaml"})
+	assert.EqualError(t, err, "--schedule cannot be combined with --follow, --auto-resume or --when-available")
+
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,305 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/gpu"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/metrics"
+	"github.com/uitml/frink/internal/usage"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type topContext struct {
+	cli.CommandContext
+
+	// Namespace is the namespace of the jobs, used to query metrics.
+	Namespace string
+
+	Resources metrics.ResourceSource
+	GPUs      metrics.GPUSource // nil if GPU metrics are not configured
+
+	Output       string
+	GPUThreshold float64 // jobs using less than this percentage of their GPUs are flagged
+	Timeout      time.Duration
+}
+
+// jobTop is the resource usage of a job and its pods, also used by the structured output formats.
+type jobTop struct {
+	Name string   `json:"name"`
+	Pods []podTop `json:"pods"`
+
+	// GPUs is the number of GPUs requested by each pod.
+	GPUs int64 `json:"gpus"`
+
+	// GPUUtilization is the mean utilization of the GPUs of all pods, if known.
+	GPUUtilization *float64 `json:"gpuUtilization,omitempty"`
+
+	// Underused is true if the job requests GPUs, but uses less than the threshold.
+	Underused bool `json:"underused"`
+
+	cpuRequest    float64
+	memoryRequest float64
+}
+
+type podTop struct {
+	Name  string             `json:"name"`
+	Usage *metrics.PodUsage  `json:"usage,omitempty"`
+	GPUs  []metrics.GPUUsage `json:"gpus,omitempty"`
+}
+
+func newTopCmd() *cobra.Command {
+	ctx := &topContext{}
+	cmd := &cobra.Command{
+		Use:   "top [job]",
+		Short: "Show resource usage of running jobs",
+		Long: `Show resource usage of running jobs.
+
+Shows the CPU and memory usage of each pod of the job, or of all active jobs if no job is given,
+as reported by the metrics.k8s.io API. GPU utilization and memory are shown if a GPU metrics source
+is configured via the metrics.gpu setting: either "prometheus", querying DCGM exporter metrics from
+the Prometheus server at metrics.gpuURL, or "dcgm", scraping the DCGM exporter at metrics.gpuURL.
+
+Jobs that request GPUs, but use less than --gpu-threshold percent of them on average, are flagged.`,
+		Args: cobra.MaximumNArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVarP(&ctx.Output, "output", "o", "", "output format: table|json|yaml")
+	flags.Float64Var(&ctx.GPUThreshold, "gpu-threshold", 10, "flag jobs using less than this percentage of their GPUs")
+	flags.DurationVar(&ctx.Timeout, "timeout", 10*time.Second, "metrics request timeout")
+
+	return cmd
+}
+
+func (ctx *topContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	if ctx.Output == "" {
+		ctx.Output = ctx.Config.Output
+	}
+
+	config, namespace, err := k8s.NewRESTConfig(ctx.Config.Context, ctx.Config.Namespace)
+	if err != nil {
+		return err
+	}
+	ctx.Namespace = namespace
+
+	resources, err := metrics.NewMetricsAPI(config, ctx.Timeout)
+	if err != nil {
+		return err
+	}
+	ctx.Resources = resources
+
+	switch ctx.Config.Metrics.GPU {
+	case "prometheus":
+		ctx.GPUs = metrics.NewPrometheus(ctx.Config.Metrics.GPUURL, ctx.Timeout)
+	case "dcgm":
+		ctx.GPUs = metrics.NewDCGMExporter(ctx.Config.Metrics.GPUURL, ctx.Timeout)
+	}
+
+	return nil
+}
+
+func (ctx *topContext) Run(cmd *cobra.Command, args []string) error {
+	switch ctx.Output {
+	case "", "table", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q (use table, json or yaml)", ctx.Output)
+	}
+
+	jobs, err := ctx.jobs(args)
+	if err != nil {
+		return err
+	}
+
+	tops, err := ctx.collect(jobs)
+	if err != nil {
+		return err
+	}
+
+	switch ctx.Output {
+	case "json", "yaml":
+		return printStructured(cmd.OutOrStdout(), ctx.Output, tops)
+	}
+
+	writeTopTable(cmd.OutOrStdout(), tops)
+	for _, top := range tops {
+		if top.Underused {
+			fmt.Fprintf(ctx.Err, "Warning: job %s requests %d GPUs per pod, but uses only %.0f%% of them on average\n", top.Name, top.GPUs, *top.GPUUtilization)
+		}
+	}
+
+	return nil
+}
+
+// jobs returns the job given by args, or all active jobs if no job is given.
+func (ctx *topContext) jobs(args []string) ([]batchv1.Job, error) {
+	if len(args) > 0 {
+		job, err := ctx.Client.GetJob(args[0])
+		if err != nil {
+			return nil, fmt.Errorf("unable to get job: %w", err)
+		}
+		if job == nil {
+			return nil, fmt.Errorf("job %s not found", args[0])
+		}
+
+		return []batchv1.Job{*job}, nil
+	}
+
+	jobs, err := ctx.Client.ListJobs()
+	if err != nil {
+		return nil, fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	var active []batchv1.Job
+	for _, job := range jobs {
+		if job.Status.Active > 0 {
+			active = append(active, job)
+		}
+	}
+
+	return active, nil
+}
+
+// collect retrieves the resource usage of the pods of the jobs.
+func (ctx *topContext) collect(jobs []batchv1.Job) ([]jobTop, error) {
+	tops := make([]jobTop, 0, len(jobs))
+	var pods []string
+	for _, job := range jobs {
+		names, err := ctx.Client.GetPodsFromJob(job.Name)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get pods of job %s: %w", job.Name, err)
+		}
+
+		spec := job.Spec.Template.Spec
+		top := jobTop{
+			Name:          job.Name,
+			Pods:          make([]podTop, 0, len(names)),
+			GPUs:          gpu.PodRequest(corev1.Pod{Spec: spec}),
+			cpuRequest:    usage.PodRequest(spec, corev1.ResourceCPU),
+			memoryRequest: usage.PodRequest(spec, corev1.ResourceMemory),
+		}
+		for _, name := range names {
+			top.Pods = append(top.Pods, podTop{Name: name})
+		}
+
+		tops = append(tops, top)
+		pods = append(pods, names...)
+	}
+
+	if len(pods) == 0 {
+		return tops, nil
+	}
+
+	c, cancel := context.WithTimeout(context.Background(), ctx.Timeout)
+	defer cancel()
+
+	resources, err := ctx.Resources.PodUsage(c, ctx.Namespace, pods)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pod metrics: %w", err)
+	}
+
+	gpus := map[string][]metrics.GPUUsage{}
+	if ctx.GPUs != nil {
+		if gpus, err = ctx.GPUs.GPUUsage(c, ctx.Namespace, pods); err != nil {
+			return nil, fmt.Errorf("unable to get GPU metrics: %w", err)
+		}
+	}
+
+	for i := range tops {
+		top := &tops[i]
+		var all []metrics.GPUUsage
+		for j := range top.Pods {
+			pod := &top.Pods[j]
+			if u, ok := resources[pod.Name]; ok {
+				pod.Usage = &u
+			}
+			pod.GPUs = gpus[pod.Name]
+			all = append(all, pod.GPUs...)
+		}
+
+		if utilization, ok := metrics.MeanUtilization(all); ok {
+			top.GPUUtilization = &utilization
+			top.Underused = top.GPUs > 0 && utilization < ctx.GPUThreshold
+		}
+	}
+
+	return tops, nil
+}
+
+func writeTopTable(w io.Writer, tops []jobTop) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	defer tw.Flush()
+
+	columnNames := []string{
+		"JOB",
+		"POD",
+		"CPU",
+		"MEMORY",
+		"GPU-UTIL",
+		"GPU-MEMORY",
+	}
+	fmt.Fprintln(tw, strings.Join(columnNames, "\t")+"\t")
+
+	for _, top := range tops {
+		for _, pod := range top.Pods {
+			fmt.Fprintln(tw, topRow(top, pod))
+		}
+	}
+}
+
+func topRow(top jobTop, pod podTop) string {
+	cpu, memory := "-", "-"
+	if pod.Usage != nil {
+		cpu = fmt.Sprintf("%.2f", float64(pod.Usage.CPUMillicores)/1000)
+		if top.cpuRequest > 0 {
+			cpu += fmt.Sprintf("/%.4g", top.cpuRequest)
+		}
+
+		memory = humanize.IBytes(uint64(pod.Usage.MemoryBytes))
+		if top.memoryRequest > 0 {
+			memory += "/" + humanize.IBytes(uint64(top.memoryRequest))
+		}
+	}
+
+	utilization, memoryGPU := "-", "-"
+	if mean, ok := metrics.MeanUtilization(pod.GPUs); ok {
+		utilization = fmt.Sprintf("%.0f%%", mean)
+		if top.Underused {
+			utilization += " (low)"
+		}
+
+		var used, total float64
+		for _, g := range pod.GPUs {
+			used += g.MemoryUsedMiB
+			total += g.MemoryTotalMiB
+		}
+		memoryGPU = gpuMemory(int64(used)) + "/" + gpuMemory(int64(total))
+	}
+
+	columns := []string{
+		top.Name,
+		pod.Name,
+		cpu,
+		memory,
+		utilization,
+		memoryGPU,
+	}
+
+	return strings.Join(columns, "\t") + "\t"
+}
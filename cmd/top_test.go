@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/metrics"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type fakeResources map[string]metrics.PodUsage
+
+func (f fakeResources) PodUsage(ctx context.Context, namespace string, pods []string) (map[string]metrics.PodUsage, error) {
+	return f, nil
+}
+
+type fakeGPUs map[string][]metrics.GPUUsage
+
+func (f fakeGPUs) GPUUsage(ctx context.Context, namespace string, pods []string) (map[string][]metrics.GPUUsage, error) {
+	return f, nil
+}
+
+func TestTopRun(t *testing.T) {
+	var out, errOut strings.Builder
+	cmd := newTopCmd()
+	cmd.SetOut(&out)
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "train"}}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("4"),
+			corev1.ResourceMemory: resource.MustParse("16Gi"),
+			"nvidia.com/gpu":      resource.MustParse("2"),
+		}},
+	}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "train").Return(job, nil)
+	client.On("GetPodsFromJob", "train").Return([]string{"train-abcde", "train-fghij"}, nil)
+
+	ctx := &topContext{
+		CommandContext: cli.CommandContext{Out: &out, Err: &errOut, Client: client},
+		Namespace:      "jane",
+		Resources:      fakeResources{"train-abcde": {CPUMillicores: 1500, MemoryBytes: 2 << 30}},
+		GPUs: fakeGPUs{"train-abcde": {
+			{ID: "0", Utilization: 4, MemoryUsedMiB: 1024, MemoryTotalMiB: 40960},
+			{ID: "1", Utilization: 6, MemoryUsedMiB: 1024, MemoryTotalMiB: 40960},
+		}},
+		GPUThreshold: 10,
+		Timeout:      time.Second,
+	}
+
+	err := ctx.Run(cmd, []string{"train"})
+	assert.NoError(t, err)
+	assert.Regexp(t, `train\s+train-abcde\s+1\.50/4\s+2\.0 GiB/16 GiB\s+5% \(low\)\s+2\.0 GiB/80 GiB`, out.String())
+	assert.Regexp(t, `train\s+train-fghij\s+-\s+-\s+-\s+-`, out.String())
+	assert.Contains(t, errOut.String(), "job train requests 2 GPUs per pod, but uses only 5% of them on average")
+
+	client.AssertExpectations(t)
+}
+
+func TestTopRunNoActiveJobs(t *testing.T) {
+	var out strings.Builder
+	cmd := newTopCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	client.On("ListJobs").Return([]batchv1.Job{{ObjectMeta: metav1.ObjectMeta{Name: "done"}}}, nil)
+
+	ctx := &topContext{
+		CommandContext: cli.CommandContext{Out: &out, Client: client},
+		Resources:      fakeResources{},
+	}
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(out.String(), "\n"))
+
+	client.AssertExpectations(t)
+}
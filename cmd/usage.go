@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/usage"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type usageContext struct {
+	cli.CommandContext
+
+	Since  time.Duration
+	Month  string
+	Label  string
+	Output string
+
+	// Now is the end of the reporting window when Month is not set; the zero value means the current time.
+	Now time.Time
+}
+
+// usageReport is the representation of the usage report used by the structured output formats.
+type usageReport struct {
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	GroupBy string         `json:"groupBy"`
+	Usage   []usage.Record `json:"usage"`
+	Total   usage.Record   `json:"total"`
+	Quotas  []quotaSummary `json:"quotas,omitempty"`
+}
+
+type quotaSummary struct {
+	Name      string       `json:"name"`
+	Resources []quotaUsage `json:"resources"`
+}
+
+type quotaUsage struct {
+	Resource string `json:"resource"`
+	Used     string `json:"used"`
+	Hard     string `json:"hard"`
+}
+
+func newUsageCmd() *cobra.Command {
+	ctx := &usageContext{}
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Show resource usage per user",
+		Long: `Show resource usage per user.
+
+Aggregates the GPU-hours, CPU-hours and memory-hours (GiB) consumed by jobs in the namespace within
+a time window, per user or per value of a label. Jobs run via frink are labeled with the submitting
+user; other jobs are attributed to "unknown". Usage is computed from the start and completion times
+of jobs and the resource requests of their pods, so deleted jobs are not included. Paused jobs are
+charged until they were paused, and unfinished jobs without running pods are not charged.
+
+The current consumption of the resource quotas in the namespace is shown below the usage table.`,
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	flags := cmd.Flags()
+	flags.DurationVar(&ctx.Since, "since", 30*24*time.Hour, "length of the reporting window, ending now")
+	flags.StringVar(&ctx.Month, "month", "", "report usage for a calendar month instead, e.g. 2021-09")
+	flags.StringVar(&ctx.Label, "label", "", "group usage by the value of this job label instead of by user")
+	flags.StringVarP(&ctx.Output, "output", "o", "", "output format: table|csv|json|yaml")
+
+	return cmd
+}
+
+func (ctx *usageContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	if ctx.Output == "" {
+		ctx.Output = ctx.Config.Output
+	}
+
+	return nil
+}
+
+func (ctx *usageContext) Run(cmd *cobra.Command, args []string) error {
+	switch ctx.Output {
+	case "", "table", "csv", "json", "yaml":
+	default:
+		return fmt.Errorf("unknown output format %q (use table, csv, json or yaml)", ctx.Output)
+	}
+
+	from, to, err := ctx.window()
+	if err != nil {
+		return err
+	}
+
+	jobs, err := ctx.Client.ListJobs()
+	if err != nil {
+		return fmt.Errorf("could not list jobs: %w", err)
+	}
+
+	report := usageReport{
+		From:    from,
+		To:      to,
+		GroupBy: ctx.groupBy(),
+		Usage:   usage.Aggregate(jobs, from, to, ctx.key),
+	}
+	report.Total.Key = "TOTAL"
+	for _, record := range report.Usage {
+		report.Total.Add(record)
+	}
+
+	if ctx.Output != "csv" {
+		// Quotas are supplementary, so e.g. lacking permission to read them should not prevent reporting usage.
+		quotas, err := ctx.Client.ListResourceQuotas()
+		if err != nil {
+			fmt.Fprintf(ctx.Err, "Unable to list resource quotas: %v\n", err)
+		}
+		for _, quota := range quotas {
+			report.Quotas = append(report.Quotas, summarizeQuota(quota))
+		}
+	}
+
+	w := cmd.OutOrStdout()
+	switch ctx.Output {
+	case "csv":
+		return writeUsageCSV(w, report)
+	case "json", "yaml":
+		return printStructured(w, ctx.Output, report)
+	}
+
+	writeUsageTable(w, report)
+
+	return nil
+}
+
+// window returns the reporting window, either the calendar month or the duration ending now.
+func (ctx *usageContext) window() (time.Time, time.Time, error) {
+	if ctx.Month != "" {
+		from, err := time.ParseInLocation("2006-01", ctx.Month, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("invalid month %q: expected YYYY-MM", ctx.Month)
+		}
+
+		return from, from.AddDate(0, 1, 0), nil
+	}
+
+	if ctx.Since <= 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid window %s: must be positive", ctx.Since)
+	}
+
+	to := ctx.Now
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	return to.Add(-ctx.Since), to, nil
+}
+
+func (ctx *usageContext) groupBy() string {
+	if ctx.Label != "" {
+		return ctx.Label
+	}
+
+	return "user"
+}
+
+func (ctx *usageContext) key(job batchv1.Job) string {
+	if ctx.Label == "" {
+		return k8s.JobUser(job)
+	}
+
+	if value := job.Labels[ctx.Label]; value != "" {
+		return value
+	}
+
+	return "<none>"
+}
+
+func summarizeQuota(quota corev1.ResourceQuota) quotaSummary {
+	names := make([]string, 0, len(quota.Status.Hard))
+	for name := range quota.Status.Hard {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	summary := quotaSummary{Name: quota.Name, Resources: []quotaUsage{}}
+	for _, name := range names {
+		hard := quota.Status.Hard[corev1.ResourceName(name)]
+		used := quota.Status.Used[corev1.ResourceName(name)]
+		summary.Resources = append(summary.Resources, quotaUsage{
+			Resource: name,
+			Used:     used.String(),
+			Hard:     hard.String(),
+		})
+	}
+
+	return summary
+}
+
+func writeUsageTable(w io.Writer, report usageReport) {
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	defer tw.Flush()
+
+	fmt.Fprintf(tw, "Usage from %s to %s\n\n", report.From.Format("2006-01-02 15:04"), report.To.Format("2006-01-02 15:04"))
+
+	columnNames := []string{
+		strings.ToUpper(report.GroupBy),
+		"JOBS",
+		"GPU-HOURS",
+		"CPU-HOURS",
+		"MEMORY-HOURS",
+	}
+	fmt.Fprintln(tw, strings.Join(columnNames, "\t")+"\t")
+	for _, record := range append(report.Usage, report.Total) {
+		fmt.Fprintln(tw, usageRow(record))
+	}
+
+	for _, quota := range report.Quotas {
+		fmt.Fprintf(tw, "\nQuota %s\n\n", quota.Name)
+		fmt.Fprintln(tw, "RESOURCE\tUSED\tHARD\t")
+		for _, resource := range quota.Resources {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", resource.Resource, resource.Used, resource.Hard)
+		}
+	}
+}
+
+func usageRow(record usage.Record) string {
+	columns := []string{
+		record.Key,
+		fmt.Sprint(record.Jobs),
+		fmt.Sprintf("%.1f", record.GPUHours),
+		fmt.Sprintf("%.1f", record.CPUHours),
+		fmt.Sprintf("%.1f GiB", record.MemoryGiBHours),
+	}
+
+	return strings.Join(columns, "\t") + "\t"
+}
+
+func writeUsageCSV(w io.Writer, report usageReport) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{report.GroupBy, "jobs", "gpu_hours", "cpu_hours", "memory_gib_hours", "from", "to"})
+	for _, record := range report.Usage {
+		cw.Write([]string{
+			record.Key,
+			fmt.Sprint(record.Jobs),
+			fmt.Sprintf("%.3f", record.GPUHours),
+			fmt.Sprintf("%.3f", record.CPUHours),
+			fmt.Sprintf("%.3f", record.MemoryGiBHours),
+			report.From.Format(time.RFC3339),
+			report.To.Format(time.RFC3339),
+		})
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
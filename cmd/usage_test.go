@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newUsageContext(client *fake.Client, output string) *usageContext {
+	return &usageContext{
+		CommandContext: cli.CommandContext{
+			Out:    &strings.Builder{},
+			Err:    &strings.Builder{},
+			Client: client,
+		},
+		Since:  24 * time.Hour,
+		Output: output,
+		Now:    time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func usageJobs(now time.Time) []batchv1.Job {
+	job := batchv1.Job{ObjectMeta: metav1.ObjectMeta{
+		Name:   "train",
+		Labels: map[string]string{k8s.UserLabel: "jane", "team": "vision"},
+	}}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{"nvidia.com/gpu": resource.MustParse("2")},
+		},
+	}}
+	job.Status.StartTime = &metav1.Time{Time: now.Add(-3 * time.Hour)}
+	job.Status.Active = 1
+
+	return []batchv1.Job{job}
+}
+
+func TestUsageRunTable(t *testing.T) {
+	var out strings.Builder
+	cmd := newUsageCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	ctx := newUsageContext(client, "table")
+	client.On("ListJobs").Return(usageJobs(ctx.Now), nil)
+	client.On("ListResourceQuotas").Return([]corev1.ResourceQuota{{
+		ObjectMeta: metav1.ObjectMeta{Name: "compute"},
+		Status: corev1.ResourceQuotaStatus{
+			Hard: corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("8")},
+			Used: corev1.ResourceList{"requests.nvidia.com/gpu": resource.MustParse("2")},
+		},
+	}}, nil)
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Regexp(t, `jane\s+1\s+6\.0\s+0\.0\s+0\.0 GiB`, out.String())
+	assert.Regexp(t, `TOTAL\s+1\s+6\.0`, out.String())
+	assert.Regexp(t, `requests.nvidia.com/gpu\s+2\s+8`, out.String())
+
+	client.AssertExpectations(t)
+}
+
+func TestUsageRunCSVByLabel(t *testing.T) {
+	var out strings.Builder
+	cmd := newUsageCmd()
+	cmd.SetOut(&out)
+
+	client := &fake.Client{}
+	ctx := newUsageContext(client, "csv")
+	ctx.Label = "team"
+	client.On("ListJobs").Return(usageJobs(ctx.Now), nil)
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, "team,jobs,gpu_hours,cpu_hours,memory_gib_hours,from,to", lines[0])
+		assert.True(t, strings.HasPrefix(lines[1], "vision,1,6.000,0.000,0.000,"))
+	}
+
+	client.AssertExpectations(t)
+}
+
+func TestUsageRunQuotaError(t *testing.T) {
+	cmd := newUsageCmd()
+	cmd.SetOut(&strings.Builder{})
+
+	client := &fake.Client{}
+	ctx := newUsageContext(client, "table")
+	client.On("ListJobs").Return([]batchv1.Job{}, nil)
+	client.On("ListResourceQuotas").Return(nil, errors.New("forbidden"))
+
+	err := ctx.Run(cmd, []string{})
+	assert.NoError(t, err)
+	assert.Contains(t, ctx.Err.(*strings.Builder).String(), "Unable to list resource quotas: forbidden")
+}
+
+func TestUsageWindowMonth(t *testing.T) {
+	ctx := &usageContext{Month: "2021-09"}
+	from, to, err := ctx.window()
+	assert.NoError(t, err)
+	assert.Equal(t, "2021-09-01", from.Format("2006-01-02"))
+	assert.Equal(t, "2021-10-01", to.Format("2006-01-02"))
+
+	ctx.Month = "september"
+	_, _, err = ctx.window()
+	assert.Error(t, err)
+}
@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/notify"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+type watchNotifyContext struct {
+	cli.CommandContext
+
+	// Sinks receive the notification; see cli.NotifyConfig.
+	Sinks    []notify.Sink
+	LogLines int64
+
+	PollInterval time.Duration
+}
+
+func newWatchNotifyCmd() *cobra.Command {
+	ctx := &watchNotifyContext{}
+	cmd := &cobra.Command{
+		Use:   "watch-notify <job>",
+		Short: "Wait for a job to finish, and send a notification",
+		Long: `Wait for a job to finish, and send a notification.
+
+Notifications are sent to the sinks configured in the "notify" section of the configuration: a webhook
+receiving a JSON payload (notify.webhook), a Slack or Mattermost incoming webhook (notify.slack), the
+desktop via notify-send (notify.desktop), or email (notify.email). They include the status, duration and
+failure reason of the job, along with the last notify.logLines lines of its logs.
+
+A notification is sent right away if the job has already finished. Use "frink run --notify" to be
+notified when a job submitted with frink finishes.`,
+		Args: cobra.ExactArgs(1),
+
+		PreRunE: ctx.PreRun,
+		RunE:    ctx.Run,
+	}
+
+	cmd.Flags().DurationVar(&ctx.PollInterval, "poll-interval", 10*time.Second, "time between checking the status of the job")
+
+	return cmd
+}
+
+func (ctx *watchNotifyContext) PreRun(cmd *cobra.Command, args []string) error {
+	if err := ctx.Initialize(cmd); err != nil {
+		return err
+	}
+
+	ctx.Sinks = ctx.Config.Notify.Sinks()
+	ctx.LogLines = ctx.Config.Notify.LogLines
+
+	return nil
+}
+
+func (ctx *watchNotifyContext) Run(cmd *cobra.Command, args []string) error {
+	if len(ctx.Sinks) == 0 {
+		return errNoSinks
+	}
+
+	name := args[0]
+	job, err := ctx.Client.GetJob(name)
+	if err != nil {
+		return fmt.Errorf("unable to get job: %w", err)
+	}
+	if job == nil {
+		return fmt.Errorf("job %s not found", name)
+	}
+
+	finished, err := waitUntilJobFinished(ctx.Client, name, ctx.PollInterval)
+	if err != nil {
+		return err
+	}
+
+	return notifyJob(&ctx.CommandContext, ctx.Sinks, ctx.LogLines, finished)
+}
+
+var errNoSinks = fmt.Errorf("no notification sinks configured; set notify.webhook, notify.slack, notify.desktop or notify.email.to")
+
+// waitUntilJobFinished polls the job until it has succeeded or failed, and returns the finished job.
+// It is shared by "frink watch-notify" and "frink run", which waits via (*runContext).WaitUntilJobFinished.
+// While waiting, the retry policy of the job is enforced, failing it fast on failures that are not retried.
+func waitUntilJobFinished(client k8s.Client, name string, interval time.Duration) (*batchv1.Job, error) {
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	var finished *batchv1.Job
+	err := wait.PollImmediateInfinite(interval, func() (bool, error) {
+		job, err := client.GetJob(name)
+		if err != nil {
+			return false, err
+		}
+
+		if job == nil {
+			return false, fmt.Errorf("job %s was deleted", name)
+		}
+
+		if status := k8s.JobStatus(*job); status == k8s.StatusSucceeded || status == k8s.StatusFailed {
+			finished = job
+			return true, nil
+		}
+
+		// The job fails once Kubernetes notices its deadline has passed, which is picked up by a later poll.
+		_, err = k8s.EnforceRetryPolicy(client, *job)
+		return false, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to wait for job to finish: %w", err)
+	}
+
+	return finished, nil
+}
+
+// notifyJob sends a notification about the finished job to the sinks, including the last logLines lines of its logs.
+func notifyJob(ctx *cli.CommandContext, sinks []notify.Sink, logLines int64, job *batchv1.Job) error {
+	n := notify.Notification{
+		Job:      job.Name,
+		Status:   status(*job),
+		Duration: duration(*job),
+		Logs:     lastLogLines(ctx.Client, job.Name, logLines),
+	}
+	if n.Status == k8s.StatusFailed {
+		n.Reason = failureReason(ctx.Client, *job)
+	}
+
+	fmt.Fprintf(ctx.Out, "Sending notification: %s\n", n.Title())
+
+	return notify.Send(sinks, n)
+}
+
+// failureReason returns why the pods of the job failed, such as "OOMKilled", falling back to why the job failed.
+// Pods are looked up on a best-effort basis, since they might have been deleted already.
+// Jobs that were failed fast by their retry policy report the failure that was not retried.
+func failureReason(client k8s.Client, job batchv1.Job) string {
+	if reason := k8s.JobFailedFast(job); reason != "" {
+		return reason
+	}
+
+	pods, _ := client.ListJobPods(job.Name)
+	for _, pod := range pods {
+		if reason := k8s.PodFailureReason(pod); reason != "" {
+			return reason
+		}
+	}
+
+	return k8s.JobFailureReason(job)
+}
+
+// lastLogLines returns the last lines of the logs of the job. The logs are optional, so errors are ignored.
+func lastLogLines(client k8s.Client, name string, lines int64) []string {
+	if lines <= 0 {
+		return nil
+	}
+
+	req, err := client.GetJobLogs(name, &corev1.PodLogOptions{TailLines: &lines})
+	if err != nil || req == nil {
+		return nil
+	}
+
+	stream, err := req.Stream(context.TODO())
+	if err != nil {
+		return nil
+	}
+	defer stream.Close()
+
+	b, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return nil
+	}
+
+	logs := strings.TrimRight(string(b), "\n")
+	if logs == "" {
+		return nil
+	}
+
+	return strings.Split(logs, "\n")
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uitml/frink/internal/cli"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	"github.com/uitml/frink/internal/notify"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// notificationServer returns a webhook server sending the notifications it receives to the returned channel.
+func notificationServer(t *testing.T) (*httptest.Server, <-chan notify.Notification) {
+	received := make(chan notify.Notification, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n notify.Notification
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&n))
+		received <- n
+	}))
+
+	return server, received
+}
+
+func TestWatchNotifyRun(t *testing.T) {
+	var out strings.Builder
+	server, received := notificationServer(t)
+	defer server.Close()
+
+	start := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	failed := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "exp-3"}, Status: batchv1.JobStatus{
+		StartTime:  &start,
+		Failed:     1,
+		Conditions: []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "BackoffLimitExceeded"}},
+	}}
+	oomKilled := corev1.Pod{Status: corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 137, Reason: "OOMKilled"}},
+	}}}}
+
+	client := &fake.Client{}
+	client.On("GetJob", "exp-3").Return(&activeJob, nil).Twice()
+	client.On("GetJob", "exp-3").Return(&failed, nil)
+	client.On("ListJobPods", "exp-3").Return([]corev1.Pod{oomKilled}, nil)
+	client.On("GetJobLogs", "exp-3", mock.Anything).Return(nil, nil)
+
+	ctx := &watchNotifyContext{
+		CommandContext: cli.CommandContext{Out: &out, Client: client},
+		Sinks:          []notify.Sink{&notify.Webhook{URL: server.URL}},
+		LogLines:       20,
+		PollInterval:   time.Millisecond,
+	}
+	err := ctx.Run(newWatchNotifyCmd(), []string{"exp-3"})
+	assert.NoError(t, err)
+	assert.Equal(t, "Sending notification: Job exp-3 Failed (OOMKilled)\n", out.String())
+
+	n := <-received
+	assert.Equal(t, "exp-3", n.Job)
+	assert.Equal(t, k8s.StatusFailed, n.Status)
+	assert.Equal(t, "OOMKilled", n.Reason)
+	assert.Contains(t, n.Duration, "2 hours")
+
+	client.AssertExpectations(t)
+}
+
+func TestWatchNotifyRunWithoutSinks(t *testing.T) {
+	ctx := &watchNotifyContext{CommandContext: cli.CommandContext{Client: &fake.Client{}}}
+
+	err := ctx.Run(newWatchNotifyCmd(), []string{"exp-3"})
+	assert.Equal(t, errNoSinks, err)
+}
+
+func TestRunRunNotify(t *testing.T) {
+	server, received := notificationServer(t)
+	defer server.Close()
+
+	cmd := newRunCmd()
+	cmd.SetOut(&strings.Builder{})
+
+	client := &fake.Client{}
+	ctx := &runContext{
+		CommandContext: cli.CommandContext{
+			Out:    cmd.OutOrStderr(),
+			Err:    cmd.ErrOrStderr(),
+			Client: client,
+		},
+		Fs:           afero.NewBasePathFs(afero.NewOsFs(), "testdata"),
+		JobParser:    k8s.NewJobParser(),
+		Notify:       true,
+		Sinks:        []notify.Sink{&notify.Webhook{URL: server.URL}},
+		PollInterval: time.Millisecond,
+	}
+
+	client.On("GetJob", "foo").Return(nil, nil).Once()
+	client.On("GetJob", "foo").Return(&successfulJob, nil)
+	client.On("CreateJob", mock.Anything).Return(nil)
+
+	err := ctx.Run(cmd, []string{"job.yaml"})
+	assert.NoError(t, err)
+
+	n := <-received
+	assert.Equal(t, "foo", n.Job)
+	assert.Equal(t, k8s.StatusSucceeded, n.Status)
+	assert.Empty(t, n.Reason)
+
+	ctx.Suspended = true
+	err = ctx.Run(cmd, []string{"job.yaml"})
+	assert.EqualError(t, err, "--notify cannot be combined with --suspended or --schedule")
+
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,93 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cache caches lists of strings, such as job names, in files for a short time.
+// It is used to keep shell completion fast when completing repeatedly.
+type Cache struct {
+	// Dir is the directory holding the cached lists. Nothing is cached if empty.
+	Dir string
+
+	// TTL is how long cached lists are used before they are fetched again.
+	TTL time.Duration
+}
+
+// NewCache returns a cache in the frink directory of the user cache directory, e.g. ~/.cache/frink.
+func NewCache(ttl time.Duration) *Cache {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return &Cache{TTL: ttl}
+	}
+
+	return &Cache{Dir: filepath.Join(dir, "frink"), TTL: ttl}
+}
+
+// Strings returns the list cached under key if it is younger than the TTL, and otherwise calls fetch and caches its result.
+// Failing to read or write the cache is not an error, since fetch can always be used instead.
+func (c *Cache) Strings(key string, fetch func() ([]string, error)) ([]string, error) {
+	if c.Dir == "" {
+		return fetch()
+	}
+
+	path := filepath.Join(c.Dir, cacheFileName(key))
+	if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) < c.TTL {
+		if b, err := ioutil.ReadFile(path); err == nil {
+			return splitLines(string(b)), nil
+		}
+	}
+
+	values, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+
+	c.write(path, values)
+
+	return values, nil
+}
+
+// write writes the values to path via a temporary file, so that concurrent readers never see a partial list.
+func (c *Cache) write(path string, values []string) {
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return
+	}
+
+	f, err := ioutil.TempFile(c.Dir, ".tmp-")
+	if err != nil {
+		return
+	}
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(strings.Join(values, "\n"))
+	if closeErr := f.Close(); err != nil || closeErr != nil {
+		return
+	}
+
+	_ = os.Rename(f.Name(), path)
+}
+
+// cacheFileName returns key with characters that are not safe in file names, such as "/" in context names, replaced.
+func cacheFileName(key string) string {
+	return "completion-" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '.':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheStrings(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		return []string{"exp-1", "exp-2"}, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		values, err := cache.Strings("jobs-ctx/a-default", fetch)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"exp-1", "exp-2"}, values)
+	}
+	assert.Equal(t, 1, calls)
+
+	// Other keys are cached separately.
+	_, err := cache.Strings("jobs-ctx/b-default", fetch)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCacheStringsExpired(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: 0}
+
+	calls := 0
+	fetch := func() ([]string, error) {
+		calls++
+		return nil, nil
+	}
+
+	for i := 0; i < 2; i++ {
+		values, err := cache.Strings("jobs", fetch)
+		assert.NoError(t, err)
+		assert.Empty(t, values)
+	}
+	assert.Equal(t, 2, calls)
+}
+
+func TestCacheStringsError(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+
+	_, err := cache.Strings("jobs", func() ([]string, error) { return nil, errors.New("foo") })
+	assert.EqualError(t, err, "foo")
+
+	// Errors are not cached.
+	values, err := cache.Strings("jobs", func() ([]string, error) { return []string{"exp-1"}, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"exp-1"}, values)
+}
@@ -4,45 +4,594 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/notify"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
 )
 
 // Config holds user settings, some of which might be overridable by command-line flags.
 type Config struct {
 	Context   string
 	Namespace string
+
+	// DefaultProfile is the profile simplified job specifications extend when they do not specify one.
+	DefaultProfile string
+
+	// DefaultImage is the image used by simplified job specifications that do not specify one.
+	DefaultImage string
+
+	// Output is the default output format of commands that support multiple formats.
+	Output string
+
+	// Color controls when to use ANSI colors; one of "auto", "always", or "never".
+	Color string
+
+	// OnConflict controls what happens when a job with the same name already exists; see ConflictPolicies.
+	OnConflict string
+
+	Jobs     JobsConfig
+	List     ListConfig
+	Mutators MutatorsConfig
+	Logs     LogsConfig
+	Timeouts TimeoutsConfig
+	GPU      GPUConfig
+	Metrics  MetricsConfig
+	Notify   NotifyConfig
+
+	// Profiles holds the job profiles defined in the "profiles" section; see k8s.Profiles.
+	Profiles k8s.Profiles `mapstructure:"-"`
+}
+
+// JobsConfig holds defaults for jobs that do not specify them, as human-friendly durations such as "7d"; see k8s.ParseDuration.
+// Empty values, or "none", leave jobs without a default.
+type JobsConfig struct {
+	// TTL is how long finished jobs are kept before they are deleted automatically.
+	TTL string
+
+	// Deadline is how long jobs may run before they are terminated.
+	Deadline string
+
+	// Retries is how many times failed pods are replaced before jobs are considered failed.
+	Retries int32
+}
+
+// ListConfig holds settings used when listing jobs.
+type ListConfig struct {
+	// HideFinishedAfter is how long finished jobs are listed after they finished, as a human-friendly duration;
+	// see k8s.ParseDuration. Empty, or "none", lists finished jobs until they are deleted.
+	HideFinishedAfter string
+}
+
+// MutatorsConfig controls the mutators applied to jobs before they are submitted; see k8s.MutateJob.
+type MutatorsConfig struct {
+	// Disabled are the names of mutators that are not applied.
+	Disabled []string
+
+	// Force are the names of mutators that also change fields set explicitly in job specifications.
+	Force []string
+}
+
+// MutateOptions returns the mutate options described by the mutators settings.
+func (cfg MutatorsConfig) MutateOptions() k8s.MutateOptions {
+	return k8s.MutateOptions{Disabled: cfg.Disabled, Force: cfg.Force}
+}
+
+// LogsConfig holds the default options used when retrieving job logs.
+type LogsConfig struct {
+	Follow     bool
+	Timestamps bool
+
+	// Tail is the number of lines to show from the end of the logs; negative values show all lines.
+	Tail int64
+}
+
+// TimeoutsConfig holds the timeouts used when waiting for jobs to change state. Zero disables the timeout.
+type TimeoutsConfig struct {
+	Start  time.Duration
+	Delete time.Duration
+}
+
+// GPUConfig holds settings used to determine GPU availability.
+type GPUConfig struct {
+	// Source is where availability is obtained from; one of "auto", "service", or "native".
+	Source string
+
+	// Check enables warning about jobs requesting more GPUs than currently free; see "frink run --check-gpus".
+	Check bool
+
+	// URL of the gpu-viewer service; if empty, the service is reached via the API server proxy.
+	URL string
+
+	Namespace string
+	Service   string
+	Scheme    string
+	Port      string
+}
+
+// MetricsConfig holds settings used to retrieve the resource usage of jobs.
+type MetricsConfig struct {
+	// GPU is where GPU usage is obtained from; one of "none", "prometheus", or "dcgm".
+	GPU string
+
+	// GPUURL is the URL of the Prometheus server, or the metrics endpoint of the DCGM exporter.
+	GPUURL string
+}
+
+// NotifyConfig holds the sinks that notifications about finished jobs are sent to; empty values disable a sink.
+type NotifyConfig struct {
+	// Webhook is a URL that notifications are posted to as JSON.
+	Webhook string
+
+	// Slack is the URL of an incoming webhook of Slack, or of a compatible service such as Mattermost.
+	Slack string
+
+	// Desktop shows notifications on the desktop using notify-send.
+	Desktop bool
+
+	Email EmailConfig
+
+	// LogLines is the number of lines from the end of the logs of the job included in notifications.
+	LogLines int64
+}
+
+// EmailConfig holds the settings used to send notifications by email; see notify.Email.
+type EmailConfig struct {
+	To       []string
+	From     string
+	SMTP     string
+	Username string
+
+	// Password can only be set via the FRINK_NOTIFY_EMAIL_PASSWORD environment variable, to keep it out of files.
+	Password string
+
+	// PasswordCommand is a shell command printing the password, such as "pass show smtp", used if Password is not set.
+	PasswordCommand string
+}
+
+// Sinks returns the notification sinks described by the notify settings.
+func (cfg NotifyConfig) Sinks() []notify.Sink {
+	var sinks []notify.Sink
+	if cfg.Webhook != "" {
+		sinks = append(sinks, &notify.Webhook{URL: cfg.Webhook})
+	}
+	if cfg.Slack != "" {
+		sinks = append(sinks, &notify.Slack{URL: cfg.Slack})
+	}
+	if cfg.Desktop {
+		sinks = append(sinks, &notify.Desktop{})
+	}
+	if len(cfg.Email.To) > 0 {
+		sinks = append(sinks, &notify.Email{
+			Addr:     cfg.Email.SMTP,
+			From:     cfg.Email.From,
+			To:       cfg.Email.To,
+			Username: cfg.Email.Username,
+			Password: cfg.Email.Password,
+
+			PasswordCommand: cfg.Email.PasswordCommand,
+		})
+	}
+
+	return sinks
+}
+
+// Valid values of the enumerated settings.
+var (
+	OutputFormats    = []string{"table", "json", "yaml"}
+	ColorModes       = []string{"auto", "always", "never"}
+	ConflictPolicies = []string{"replace", "fail", "suffix"}
+	GPUSources       = []string{"auto", "service", "native"}
+	GPUMetrics       = []string{"none", "prometheus", "dcgm"}
+)
+
+// boundFlags are the command-line flags that override the setting with the same name.
+// Other flags, such as --output, have command-specific values and are handled by the commands themselves.
+var boundFlags = []string{"context", "namespace", "color"}
+
+// configDefaults holds the default value of every known setting, keyed by its canonical name.
+var configDefaults = map[string]interface{}{
+	"context":                      "",
+	"namespace":                    "",
+	"defaultProfile":               "",
+	"defaultImage":                 "",
+	"output":                       "table",
+	"color":                        "auto",
+	"onConflict":                   "replace",
+	"jobs.ttl":                     "",
+	"jobs.deadline":                "",
+	"jobs.retries":                 0,
+	"list.hideFinishedAfter":       "24h",
+	"mutators.disabled":            []string{},
+	"mutators.force":               []string{},
+	"logs.follow":                  true,
+	"logs.timestamps":              false,
+	"logs.tail":                    -1,
+	"timeouts.start":               "120s",
+	"timeouts.delete":              "120s",
+	"gpu.source":                   "auto",
+	"gpu.check":                    false,
+	"gpu.url":                      "",
+	"gpu.namespace":                "gpu-availability",
+	"gpu.service":                  "gpu-viewer",
+	"gpu.scheme":                   "http",
+	"gpu.port":                     "http",
+	"metrics.gpu":                  "none",
+	"metrics.gpuURL":               "",
+	"notify.webhook":               "",
+	"notify.slack":                 "",
+	"notify.desktop":               false,
+	"notify.email.to":              []string{},
+	"notify.email.from":            "",
+	"notify.email.smtp":            "",
+	"notify.email.username":        "",
+	"notify.email.password":        "",
+	"notify.email.passwordCommand": "",
+	"notify.logLines":              20,
+}
+
+// userOnlySettings are the settings that project configuration files cannot set, since they decide where data is sent.
+// A repository could otherwise make frink send job names and logs to an endpoint the user never configured.
+// Sections, such as "notify.email", cover all of their settings.
+var userOnlySettings = []string{"context", "gpu.url", "metrics.gpuURL", "notify.webhook", "notify.slack", "notify.desktop", "notify.email"}
+
+// envOnlySettings are the settings that cannot be set in any configuration file, since they are secrets.
+var envOnlySettings = []string{"notify.email.password"}
+
+// redactedSettings are the settings whose values are hidden when the effective configuration is shown.
+var redactedSettings = []string{"notify.email.password"}
+
+// DefaultConfig returns the configuration used when no settings have been specified.
+func DefaultConfig() *Config {
+	cfg, err := decodeConfig(newViper(), nil)
+	if err != nil {
+		panic(err)
+	}
+
+	return cfg
 }
 
 // ParseConfig reads in user configuration from files, with some settings optionally being overridable via command-line flags.
 func ParseConfig(cmd *cobra.Command) (*Config, error) {
+	v, layers, err := readConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return decodeConfig(v, layers)
+}
+
+// Validate returns an error if any of the settings are invalid.
+func (cfg *Config) Validate() error {
+	if err := oneOf("output", cfg.Output, OutputFormats); err != nil {
+		return err
+	}
+	if err := oneOf("color", cfg.Color, ColorModes); err != nil {
+		return err
+	}
+	if err := oneOf("onConflict", cfg.OnConflict, ConflictPolicies); err != nil {
+		return err
+	}
+	if err := oneOf("gpu.source", cfg.GPU.Source, GPUSources); err != nil {
+		return err
+	}
+
+	if err := oneOf("metrics.gpu", cfg.Metrics.GPU, GPUMetrics); err != nil {
+		return err
+	}
+	if cfg.Metrics.GPU != "none" && cfg.Metrics.GPUURL == "" {
+		return fmt.Errorf("invalid metrics.gpuURL: must be set when metrics.gpu is %q", cfg.Metrics.GPU)
+	}
+
+	ttl, err := parseOptionalDuration(cfg.Jobs.TTL)
+	if err != nil {
+		return fmt.Errorf("invalid jobs.ttl: %w", err)
+	}
+	if _, err := k8s.Duration(ttl).TTLSeconds(); err != nil {
+		return fmt.Errorf("invalid jobs.ttl: %w", err)
+	}
+	if _, err := parseOptionalDuration(cfg.Jobs.Deadline); err != nil {
+		return fmt.Errorf("invalid jobs.deadline: %w", err)
+	}
+
+	if _, err := parseOptionalDuration(cfg.List.HideFinishedAfter); err != nil {
+		return fmt.Errorf("invalid list.hideFinishedAfter: %w", err)
+	}
+
+	if cfg.Jobs.Retries < 0 {
+		return fmt.Errorf("invalid jobs.retries: must not be negative")
+	}
+
+	if err := k8s.ValidateMutatorNames(cfg.Mutators.Disabled); err != nil {
+		return fmt.Errorf("invalid mutators.disabled: %w", err)
+	}
+	if err := k8s.ValidateMutatorNames(cfg.Mutators.Force); err != nil {
+		return fmt.Errorf("invalid mutators.force: %w", err)
+	}
+
+	if len(cfg.Notify.Email.To) > 0 && (cfg.Notify.Email.From == "" || cfg.Notify.Email.SMTP == "") {
+		return fmt.Errorf("invalid notify.email: from and smtp must be set when to is set")
+	}
+	if cfg.Notify.LogLines < 0 {
+		return fmt.Errorf("invalid notify.logLines: must not be negative")
+	}
+
+	if cfg.Timeouts.Start < 0 || cfg.Timeouts.Delete < 0 {
+		return fmt.Errorf("invalid timeouts: must not be negative")
+	}
+
+	if cfg.DefaultProfile != "" {
+		if _, ok := cfg.Profiles[cfg.DefaultProfile]; !ok {
+			return fmt.Errorf("invalid defaultProfile: unknown profile %q", cfg.DefaultProfile)
+		}
+	}
+
+	return nil
+}
+
+// PodLogOptions returns the log options described by the logs settings.
+func (cfg LogsConfig) PodLogOptions() *corev1.PodLogOptions {
+	opts := &corev1.PodLogOptions{
+		Follow:     cfg.Follow,
+		Timestamps: cfg.Timestamps,
+	}
+	if cfg.Tail >= 0 {
+		tail := cfg.Tail
+		opts.TailLines = &tail
+	}
+
+	return opts
+}
+
+// DefaultTTL returns the default TTL of jobs, or zero if there is none.
+func (cfg JobsConfig) DefaultTTL() time.Duration {
+	d, _ := parseOptionalDuration(cfg.TTL)
+	return d
+}
+
+// DefaultDeadline returns the default deadline of jobs, or zero if there is none.
+func (cfg JobsConfig) DefaultDeadline() time.Duration {
+	d, _ := parseOptionalDuration(cfg.Deadline)
+	return d
+}
+
+// HideFinished returns how long finished jobs are listed after they finished, or zero if they are not hidden.
+func (cfg ListConfig) HideFinished() time.Duration {
+	d, _ := parseOptionalDuration(cfg.HideFinishedAfter)
+	return d
+}
+
+// parseOptionalDuration parses s as k8s.ParseDuration does, returning zero for empty values and "none".
+func parseOptionalDuration(s string) (time.Duration, error) {
+	if s == "" || s == "none" {
+		return 0, nil
+	}
+
+	d, err := k8s.ParseDuration(s)
+	if err == nil && d < 0 {
+		err = fmt.Errorf("invalid duration %q: must not be negative", s)
+	}
+
+	return d, err
+}
+
+func oneOf(key, value string, valid []string) error {
+	for _, v := range valid {
+		if value == v {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid %s %q: must be one of %v", key, value, valid)
+}
+
+func newViper() *viper.Viper {
 	v := viper.New()
-	v.AddConfigPath(configPath())
-	v.SetConfigName("config")
+	for key, value := range configDefaults {
+		v.SetDefault(key, value)
+	}
+
+	return v
+}
+
+// configLayer is the parsed content of a single configuration file.
+type configLayer struct {
+	// Path is the path of the configuration file.
+	Path string
+
+	// Raw holds the settings in the file, with the original casing of keys preserved.
+	Raw map[string]interface{}
+}
+
+// readConfig returns a viper instance holding the effective settings, as well as the configuration file layers read.
+//
+// The user configuration file is read first, followed by any project configuration files; see ProjectConfigFiles.
+// Settings in later layers take precedence, while environment variables and command-line flags take precedence over all files.
+func readConfig(cmd *cobra.Command) (*viper.Viper, []configLayer, error) {
+	v := newViper()
 	v.SetConfigType("yaml")
 
 	v.SetEnvPrefix("frink")
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	v.AutomaticEnv()
 
-	v.BindPFlags(cmd.Flags())
+	for _, name := range boundFlags {
+		if flag := cmd.Flags().Lookup(name); flag != nil {
+			v.BindPFlag(name, flag)
+		}
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return nil, nil, err
+	}
 
-	if err := v.ReadInConfig(); err != nil {
-		// TODO: Log ConfigFileNotFoundError when/if we implement logging?
-		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
-			return nil, err
+	var layers []configLayer
+	filenames := append([]string{ConfigFile()}, ProjectConfigFiles(cwd)...)
+	for i, filename := range filenames {
+		layer, err := readConfigLayer(filename)
+		if err != nil {
+			return nil, nil, err
+		}
+		if layer == nil {
+			continue
+		}
+
+		if err := checkLayer(*layer, i > 0); err != nil {
+			return nil, nil, err
+		}
+
+		if err := v.MergeConfigMap(copyMap(layer.Raw)); err != nil {
+			return nil, nil, err
 		}
+		layers = append(layers, *layer)
 	}
 
+	return v, layers, nil
+}
+
+// ProjectConfigFileName is the name of project configuration files.
+const ProjectConfigFileName = ".frink.yaml"
+
+// ProjectConfigFiles returns the project configuration files that apply to dir, ordered from least to most specific.
+//
+// Project configuration files are looked for in dir and its parent directories, up to and including the root of the
+// git repository containing dir. If dir is not inside a git repository, only dir itself is considered.
+func ProjectConfigFiles(dir string) []string {
+	var dirs []string
+	for current := filepath.Clean(dir); ; {
+		dirs = append(dirs, current)
+		if _, err := os.Stat(filepath.Join(current, ".git")); err == nil {
+			break
+		}
+
+		parent := filepath.Dir(current)
+		if parent == current {
+			// Reached the file system root without finding a git repository.
+			dirs = dirs[:1]
+			break
+		}
+		current = parent
+	}
+
+	var filenames []string
+	for i := len(dirs) - 1; i >= 0; i-- {
+		filename := filepath.Join(dirs[i], ProjectConfigFileName)
+		if info, err := os.Stat(filename); err == nil && !info.IsDir() {
+			filenames = append(filenames, filename)
+		}
+	}
+
+	return filenames
+}
+
+// readConfigLayer reads the configuration file at filename, returning nil if it does not exist.
+func readConfigLayer(filename string) (*configLayer, error) {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		// TODO: Log missing configuration files when/if we implement logging?
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", filename, err)
+	}
+
+	return &configLayer{Path: filename, Raw: raw}, nil
+}
+
+// checkLayer returns an error if the configuration file layer sets a setting it is not allowed to.
+// Project configuration files cannot set userOnlySettings, and no file can set envOnlySettings.
+func checkLayer(layer configLayer, project bool) error {
+	for _, key := range envOnlySettings {
+		if _, ok := lookupPath(layer.Raw, key); ok {
+			env := "FRINK_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+			return fmt.Errorf("%s cannot be set in %s: use the %s environment variable instead", key, layer.Path, env)
+		}
+	}
+
+	if !project {
+		return nil
+	}
+
+	for _, key := range userOnlySettings {
+		if _, ok := lookupPath(layer.Raw, key); ok {
+			return fmt.Errorf("%s cannot be set in project configuration file %s: set it in %s instead", key, layer.Path, ConfigFile())
+		}
+	}
+
+	return nil
+}
+
+func decodeConfig(v *viper.Viper, layers []configLayer) (*Config, error) {
 	cfg := &Config{}
 	if err := v.Unmarshal(cfg); err != nil {
 		return nil, err
 	}
 
+	profiles, err := k8s.DecodeProfiles(rawProfiles(layers))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Profiles = profiles
+
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+
 	return cfg, nil
 }
 
+// rawProfiles returns the profiles section of the configuration file layers, with later layers taking precedence.
+func rawProfiles(layers []configLayer) map[string]interface{} {
+	profiles := map[string]interface{}{}
+	for _, layer := range layers {
+		section, _ := layer.Raw["profiles"].(map[string]interface{})
+		for name, profile := range section {
+			profiles[name] = profile
+		}
+	}
+
+	return profiles
+}
+
+// copyMap returns a deep copy of m, since viper modifies the maps it is given.
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = copyMap(nested)
+		}
+		c[k] = v
+	}
+
+	return c
+}
+
+// ConfigFile returns the path of the user configuration file.
+// The file does not necessarily exist.
+func ConfigFile() string {
+	for _, name := range []string{"config.yaml", "config.yml"} {
+		filename := path.Join(configPath(), name)
+		if _, err := os.Stat(filename); err == nil {
+			return filename
+		}
+	}
+
+	return path.Join(configPath(), "config.yaml")
+}
+
 func configPath() string {
 	configPath := os.Getenv("XDG_CONFIG_HOME")
 	if configPath == "" {
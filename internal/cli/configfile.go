@@ -0,0 +1,360 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigKeys returns the canonical names of all known settings, in lexicographical order.
+func ConfigKeys() []string {
+	keys := make([]string, 0, len(configDefaults))
+	for key := range configDefaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// canonicalKey returns the canonical name of key, which is matched case-insensitively.
+// Keys in the profiles section are returned as is, since they are validated when the profiles are decoded.
+func canonicalKey(key string) (string, error) {
+	for _, known := range ConfigKeys() {
+		if strings.EqualFold(key, known) {
+			return known, nil
+		}
+	}
+
+	if strings.HasPrefix(strings.ToLower(key), "profiles.") && len(key) > len("profiles.") {
+		return "profiles" + key[len("profiles"):], nil
+	}
+
+	return "", fmt.Errorf("unknown setting %q", key)
+}
+
+// Settings returns the effective settings, nested by key path, including profiles.
+func Settings(cmd *cobra.Command) (map[string]interface{}, error) {
+	v, layers, err := readConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := decodeConfig(v, layers); err != nil {
+		return nil, err
+	}
+
+	settings := map[string]interface{}{}
+	for _, key := range ConfigKeys() {
+		value := v.Get(key)
+		if containsKey(redactedSettings, key) && value != "" {
+			value = "<redacted>"
+		}
+		setPath(settings, strings.Split(key, "."), value)
+	}
+
+	if profiles := rawProfiles(layers); len(profiles) > 0 {
+		settings["profiles"] = profiles
+	}
+
+	return settings, nil
+}
+
+// Setting returns the effective value of the setting identified by key, which may also denote a section such as "logs".
+func Setting(cmd *cobra.Command, key string) (interface{}, error) {
+	settings, err := Settings(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	return LookupSetting(settings, key)
+}
+
+// LookupSetting returns the value identified by key in settings, as returned by Settings.
+func LookupSetting(settings map[string]interface{}, key string) (interface{}, error) {
+	var value interface{} = settings
+	for _, part := range strings.Split(key, ".") {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+
+		value, ok = lookupFold(section, part)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+	}
+
+	return value, nil
+}
+
+// SettingOrigins returns where the effective value of each setting came from, keyed by canonical name.
+//
+// An origin is either "default", "flag --<name>", "env FRINK_<NAME>", or "file <path>".
+// Profiles are keyed by "profiles.<name>", and originate from the last file defining them.
+func SettingOrigins(cmd *cobra.Command) (map[string]string, error) {
+	_, layers, err := readConfig(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	origins := map[string]string{}
+	for _, key := range ConfigKeys() {
+		origins[key] = settingOrigin(cmd, layers, key)
+	}
+
+	for _, layer := range layers {
+		section, _ := layer.Raw["profiles"].(map[string]interface{})
+		for name := range section {
+			origins["profiles."+name] = "file " + layer.Path
+		}
+	}
+
+	return origins, nil
+}
+
+func settingOrigin(cmd *cobra.Command, layers []configLayer, key string) string {
+	var flag string
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		if f.Changed && strings.EqualFold(f.Name, key) {
+			flag = f.Name
+		}
+	})
+	if flag != "" {
+		return "flag --" + flag
+	}
+
+	env := "FRINK_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+	if _, ok := os.LookupEnv(env); ok {
+		return "env " + env
+	}
+
+	for i := len(layers) - 1; i >= 0; i-- {
+		if _, ok := lookupPath(layers[i].Raw, key); ok {
+			return "file " + layers[i].Path
+		}
+	}
+
+	return "default"
+}
+
+// lookupPath returns the value at the dot-separated key path in m, matching keys case-insensitively.
+func lookupPath(m map[string]interface{}, key string) (interface{}, bool) {
+	var value interface{} = m
+	for _, part := range strings.Split(key, ".") {
+		section, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if value, ok = lookupFold(section, part); !ok {
+			return nil, false
+		}
+	}
+
+	return value, true
+}
+
+func containsKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+
+	return false
+}
+
+func lookupFold(m map[string]interface{}, key string) (interface{}, bool) {
+	if value, ok := m[key]; ok {
+		return value, true
+	}
+
+	for k, value := range m {
+		if strings.EqualFold(k, key) {
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+func setPath(m map[string]interface{}, path []string, value interface{}) {
+	for _, part := range path[:len(path)-1] {
+		next, ok := m[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			m[part] = next
+		}
+		m = next
+	}
+	m[path[len(path)-1]] = value
+}
+
+// SetConfigValue sets the setting identified by key in the configuration file at filename.
+//
+// The value is parsed as YAML, so e.g. "true" becomes a boolean and "[a, b]" a list.
+// Comments and the order of existing settings are preserved, and the file is only replaced
+// if the resulting configuration is valid.
+func SetConfigValue(filename, key, value string) error {
+	key, err := canonicalKey(key)
+	if err != nil {
+		return err
+	}
+
+	doc, err := readConfigDocument(filename)
+	if err != nil {
+		return err
+	}
+
+	var valueDoc yaml.Node
+	if err := yaml.Unmarshal([]byte(value), &valueDoc); err != nil {
+		return fmt.Errorf("invalid value %q: %w", value, err)
+	}
+	valueNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: ""}
+	if len(valueDoc.Content) > 0 {
+		valueNode = valueDoc.Content[0]
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("unable to edit %s: top-level value is not a mapping", filename)
+	}
+	if err := setNode(root, strings.Split(key, "."), valueNode); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+
+	if err := validateConfigDocument(buf.Bytes()); err != nil {
+		return err
+	}
+
+	return writeFileAtomic(filename, buf.Bytes())
+}
+
+func readConfigDocument(filename string) (*yaml.Node, error) {
+	doc := &yaml.Node{}
+	b, err := os.ReadFile(filename)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(b, doc); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", filename, err)
+	}
+
+	if len(doc.Content) == 0 {
+		doc.Kind = yaml.DocumentNode
+		doc.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+
+	return doc, nil
+}
+
+// setNode sets the value at path in the mapping node, matching existing keys case-insensitively.
+func setNode(mapping *yaml.Node, path []string, value *yaml.Node) error {
+	for i := 0; i < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		if !strings.EqualFold(keyNode.Value, path[0]) {
+			continue
+		}
+
+		if len(path) == 1 {
+			// Keep comments attached to the value being replaced.
+			value.HeadComment = valueNode.HeadComment
+			value.LineComment = valueNode.LineComment
+			value.FootComment = valueNode.FootComment
+			mapping.Content[i+1] = value
+			return nil
+		}
+
+		if valueNode.Kind != yaml.MappingNode {
+			return fmt.Errorf("unable to set %s: %s is not a mapping", strings.Join(path, "."), keyNode.Value)
+		}
+
+		return setNode(valueNode, path[1:], value)
+	}
+
+	keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: path[0]}
+	if len(path) == 1 {
+		mapping.Content = append(mapping.Content, keyNode, value)
+		return nil
+	}
+
+	child := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	mapping.Content = append(mapping.Content, keyNode, child)
+
+	return setNode(child, path[1:], value)
+}
+
+func validateConfigDocument(b []byte) error {
+	raw := map[string]interface{}{}
+	if err := yaml.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	layer := configLayer{Path: "the configuration file", Raw: raw}
+	if err := checkLayer(layer, false); err != nil {
+		return err
+	}
+
+	v := newViper()
+	if err := v.MergeConfigMap(copyMap(raw)); err != nil {
+		return err
+	}
+
+	if _, err := decodeConfig(v, []configLayer{layer}); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes b to filename by renaming a temporary file, preserving the permissions of any existing file.
+func writeFileAtomic(filename string, b []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filename); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".config-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
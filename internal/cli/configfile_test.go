@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetConfigValuePreservesComments(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(filename, []byte("# settings\nnamespace: foo # team\n"), 0600)
+
+	err := SetConfigValue(filename, "timeouts.start", "5m")
+	assert.NoError(t, err)
+	err = SetConfigValue(filename, "NAMESPACE", "bar")
+	assert.NoError(t, err)
+
+	b, _ := os.ReadFile(filename)
+	assert.Equal(t, "# settings\nnamespace: bar # team\ntimeouts:\n  start: 5m\n", string(b))
+
+	info, _ := os.Stat(filename)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSetConfigValueCreatesFile(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "frink", "config.yaml")
+
+	err := SetConfigValue(filename, "onconflict", "suffix")
+	assert.NoError(t, err)
+
+	b, _ := os.ReadFile(filename)
+	assert.Equal(t, "onConflict: suffix\n", string(b))
+}
+
+func TestSetConfigValueRejectsInvalidConfig(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+	os.WriteFile(filename, []byte("namespace: foo\n"), 0644)
+
+	err := SetConfigValue(filename, "color", "rainbow")
+	assert.Error(t, err)
+
+	err = SetConfigValue(filename, "bogus", "1")
+	assert.EqualError(t, err, `unknown setting "bogus"`)
+
+	err = SetConfigValue(filename, "profiles.gpu.imag", "ubuntu")
+	assert.Error(t, err)
+
+	b, _ := os.ReadFile(filename)
+	assert.Equal(t, "namespace: foo\n", string(b))
+}
+
+func TestDefaultConfig(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.NoError(t, cfg.Validate())
+	assert.Equal(t, "replace", cfg.OnConflict)
+	assert.Equal(t, "gpu-viewer", cfg.GPU.Service)
+	assert.Nil(t, cfg.Logs.PodLogOptions().TailLines)
+	assert.True(t, cfg.Logs.PodLogOptions().Follow)
+}
+
+func TestValidateJobDurations(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Jobs.TTL = "none"
+	cfg.Jobs.Deadline = "none"
+	assert.NoError(t, cfg.Validate())
+	assert.Zero(t, cfg.Jobs.DefaultTTL())
+	assert.Zero(t, cfg.Jobs.DefaultDeadline())
+
+	// TTLs are limited to an int32 number of seconds by Kubernetes.
+	cfg.Jobs.TTL = "10000w"
+	assert.EqualError(t, cfg.Validate(), "invalid jobs.ttl: invalid ttl 70000d: must be at most 2147483647 seconds")
+}
+
+func TestProjectConfigFiles(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a", "b")
+	os.MkdirAll(nested, 0755)
+	os.Mkdir(filepath.Join(root, ".git"), 0755)
+	os.WriteFile(filepath.Join(root, ProjectConfigFileName), []byte("namespace: root\n"), 0644)
+	os.WriteFile(filepath.Join(nested, ProjectConfigFileName), []byte("namespace: nested\n"), 0644)
+
+	files := ProjectConfigFiles(nested)
+	assert.Equal(t, []string{
+		filepath.Join(root, ProjectConfigFileName),
+		filepath.Join(nested, ProjectConfigFileName),
+	}, files)
+}
+
+func TestProjectConfigFilesOutsideRepository(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "a")
+	os.MkdirAll(nested, 0755)
+	os.WriteFile(filepath.Join(root, ProjectConfigFileName), []byte("namespace: root\n"), 0644)
+
+	assert.Empty(t, ProjectConfigFiles(nested))
+}
+
+func TestSetConfigValueNotifyEmail(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := SetConfigValue(filename, "notify.email.to", "jane@example.com")
+	assert.EqualError(t, err, "invalid configuration: invalid notify.email: from and smtp must be set when to is set")
+
+	os.WriteFile(filename, []byte("notify:\n  email:\n    from: frink@example.com\n    smtp: smtp.example.com:587\n"), 0600)
+	err = SetConfigValue(filename, "notify.email.to", "jane@example.com")
+	assert.NoError(t, err)
+}
+
+func TestNotifyConfigSinks(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Empty(t, cfg.Notify.Sinks())
+	assert.Equal(t, int64(20), cfg.Notify.LogLines)
+
+	cfg.Notify.Webhook = "http://localhost/hook"
+	cfg.Notify.Desktop = true
+	cfg.Notify.Email = EmailConfig{To: []string{"jane@example.com"}, From: "frink@example.com", SMTP: "smtp.example.com:587"}
+	assert.Len(t, cfg.Notify.Sinks(), 3)
+}
+
+func TestCheckLayer(t *testing.T) {
+	webhook := configLayer{Path: ".frink.yaml", Raw: map[string]interface{}{
+		"notify": map[string]interface{}{"webhook": "https://example.com/hook"},
+	}}
+	assert.NoError(t, checkLayer(webhook, false))
+	assert.Error(t, checkLayer(webhook, true), "project files cannot choose where notifications are sent")
+
+	email := configLayer{Path: ".frink.yaml", Raw: map[string]interface{}{
+		"notify": map[string]interface{}{"Email": map[string]interface{}{"to": []interface{}{"jane@example.com"}}},
+	}}
+	assert.Error(t, checkLayer(email, true))
+
+	namespace := configLayer{Path: ".frink.yaml", Raw: map[string]interface{}{"namespace": "jane"}}
+	assert.NoError(t, checkLayer(namespace, true))
+
+	password := configLayer{Path: "config.yaml", Raw: map[string]interface{}{
+		"notify": map[string]interface{}{"email": map[string]interface{}{"password": "secret"}},
+	}}
+	assert.EqualError(t, checkLayer(password, false),
+		"notify.email.password cannot be set in config.yaml: use the FRINK_NOTIFY_EMAIL_PASSWORD environment variable instead")
+}
+
+func TestSetConfigValueRejectsPassword(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "config.yaml")
+
+	err := SetConfigValue(filename, "notify.email.password", "secret")
+	assert.Error(t, err)
+	assert.NoFileExists(t, filename)
+}
+
+func TestSettingsRedactsPassword(t *testing.T) {
+	os.Setenv("FRINK_NOTIFY_EMAIL_PASSWORD", "secret")
+	defer os.Unsetenv("FRINK_NOTIFY_EMAIL_PASSWORD")
+
+	settings, err := Settings(&cobra.Command{})
+	assert.NoError(t, err)
+
+	password, err := LookupSetting(settings, "notify.email.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "<redacted>", password)
+}
@@ -19,6 +19,9 @@ type CommandContext struct {
 
 	// Client can be used for interacting with the Kubernetes API.
 	Client k8s.Client
+
+	// Config holds the user configuration the context was initialized with.
+	Config *Config
 }
 
 // CommandInitializer is an interface that is used to initialize a CommandContext.
@@ -48,6 +51,7 @@ func (ctx *CommandContext) Initialize(cmd *cobra.Command) error {
 	ctx.Out = cmd.OutOrStderr()
 	ctx.Err = cmd.ErrOrStderr()
 	ctx.Client = client
+	ctx.Config = cfg
 
 	return nil
 }
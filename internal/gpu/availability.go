@@ -0,0 +1,259 @@
+// Package gpu models GPU availability in the cluster, and provides ways of computing it.
+package gpu
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// ResourceName is the name of the extended resource used to request GPUs.
+const ResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+// Node labels set by GPU feature discovery.
+const (
+	ProductLabel = "nvidia.com/gpu.product"
+	MemoryLabel  = "nvidia.com/gpu.memory" // MiB per GPU
+)
+
+// Availability describes the GPU availability of the nodes in a cluster.
+type Availability struct {
+	Nodes []Node `json:"nodes"`
+}
+
+// Node describes the GPU availability of a single node.
+type Node struct {
+	Name      string `json:"name"`
+	Product   string `json:"product"`
+	MemoryMiB int64  `json:"memoryMiB,omitempty"`
+
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Free  int64 `json:"free"`
+
+	// Unschedulable is true if the node is cordoned or not ready; such nodes have no free GPUs.
+	Unschedulable bool `json:"unschedulable,omitempty"`
+
+	// Namespaces holds the number of GPUs used by pods in each namespace.
+	Namespaces map[string]int64 `json:"namespaces,omitempty"`
+}
+
+// Product summarizes the GPU availability of all nodes with the same GPU product.
+type Product struct {
+	Name      string `json:"name"`
+	MemoryMiB int64  `json:"memoryMiB,omitempty"`
+	Nodes     int    `json:"nodes"`
+
+	Total int64 `json:"total"`
+	Used  int64 `json:"used"`
+	Free  int64 `json:"free"`
+
+	// MaxFree is the largest number of free GPUs on a single node.
+	MaxFree int64 `json:"maxFree"`
+
+	Namespaces map[string]int64 `json:"namespaces,omitempty"`
+}
+
+// FromCluster computes GPU availability from the nodes in a cluster and the non-terminated pods scheduled on them.
+func FromCluster(nodes []corev1.Node, pods []corev1.Pod) *Availability {
+	used := map[string]int64{}
+	namespaces := map[string]map[string]int64{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+
+		n := PodRequest(pod)
+		if n == 0 {
+			continue
+		}
+
+		used[pod.Spec.NodeName] += n
+		if namespaces[pod.Spec.NodeName] == nil {
+			namespaces[pod.Spec.NodeName] = map[string]int64{}
+		}
+		namespaces[pod.Spec.NodeName][pod.Namespace] += n
+	}
+
+	availability := &Availability{Nodes: []Node{}}
+	for _, node := range nodes {
+		qty, ok := node.Status.Allocatable[ResourceName]
+		if !ok || qty.IsZero() {
+			continue
+		}
+
+		memory, _ := strconv.ParseInt(node.Labels[MemoryLabel], 10, 64)
+		n := Node{
+			Name:          node.Name,
+			Product:       node.Labels[ProductLabel],
+			MemoryMiB:     memory,
+			Total:         qty.Value(),
+			Used:          used[node.Name],
+			Unschedulable: !schedulable(node),
+			Namespaces:    namespaces[node.Name],
+		}
+		if n.Product == "" {
+			n.Product = "unknown"
+		}
+		if !n.Unschedulable && n.Total > n.Used {
+			n.Free = n.Total - n.Used
+		}
+
+		availability.Nodes = append(availability.Nodes, n)
+	}
+
+	availability.Sort()
+
+	return availability
+}
+
+// PodRequest returns the number of GPUs requested by the pod.
+//
+// As with other resources, the effective request is the larger of the sum of the container requests,
+// and the largest init container request. Limits are used for containers without requests.
+func PodRequest(pod corev1.Pod) int64 {
+	qty := ResourceRequest(pod.Spec, ResourceName)
+	return qty.Value()
+}
+
+// ResourceRequest returns the effective request of the named resource by pods using the specification,
+// computed as for GPUs by PodRequest.
+func ResourceRequest(spec corev1.PodSpec, name corev1.ResourceName) resource.Quantity {
+	var sum resource.Quantity
+	for _, container := range spec.Containers {
+		sum.Add(containerRequest(container, name))
+	}
+
+	for _, container := range spec.InitContainers {
+		if qty := containerRequest(container, name); qty.Cmp(sum) > 0 {
+			sum = qty
+		}
+	}
+
+	return sum
+}
+
+func containerRequest(container corev1.Container, name corev1.ResourceName) resource.Quantity {
+	if qty, ok := container.Resources.Requests[name]; ok {
+		return qty
+	}
+
+	if qty, ok := container.Resources.Limits[name]; ok {
+		return qty
+	}
+
+	return resource.Quantity{}
+}
+
+func schedulable(node corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+
+	return true
+}
+
+// Sort orders the nodes by product, and then by name.
+func (a *Availability) Sort() {
+	sort.SliceStable(a.Nodes, func(i, j int) bool {
+		if a.Nodes[i].Product != a.Nodes[j].Product {
+			return a.Nodes[i].Product < a.Nodes[j].Product
+		}
+		return a.Nodes[i].Name < a.Nodes[j].Name
+	})
+}
+
+// Products returns the availability of the nodes grouped by GPU product, ordered by product name.
+func (a *Availability) Products() []Product {
+	index := map[string]int{}
+	var products []Product
+	for _, node := range a.Nodes {
+		i, ok := index[node.Product]
+		if !ok {
+			i = len(products)
+			index[node.Product] = i
+			products = append(products, Product{Name: node.Product, MemoryMiB: node.MemoryMiB})
+		}
+
+		p := &products[i]
+		p.Nodes++
+		p.Total += node.Total
+		p.Used += node.Used
+		p.Free += node.Free
+		if node.Free > p.MaxFree {
+			p.MaxFree = node.Free
+		}
+		for ns, n := range node.Namespaces {
+			if p.Namespaces == nil {
+				p.Namespaces = map[string]int64{}
+			}
+			p.Namespaces[ns] += n
+		}
+	}
+
+	sort.Slice(products, func(i, j int) bool { return products[i].Name < products[j].Name })
+
+	return products
+}
+
+// MaxFree returns the largest number of free GPUs on a single node.
+func (a *Availability) MaxFree() int64 {
+	var max int64
+	for _, node := range a.Nodes {
+		if node.Free > max {
+			max = node.Free
+		}
+	}
+
+	return max
+}
+
+// NodesWithFree returns the number of nodes with at least n free GPUs.
+func (a *Availability) NodesWithFree(n int64) int {
+	var count int
+	for _, node := range a.Nodes {
+		if node.Free >= n {
+			count++
+		}
+	}
+
+	return count
+}
+
+// Filter returns the availability of the nodes with the given GPU product; an empty product matches all nodes.
+func (a *Availability) Filter(product string) *Availability {
+	return a.filter(func(node Node) bool { return product == "" || node.Product == product })
+}
+
+// Matching returns the availability of the nodes whose GPU product contains query, ignoring case,
+// such that e.g. "a100" matches "NVIDIA-A100-SXM4-40GB". An empty query matches all nodes.
+func (a *Availability) Matching(query string) *Availability {
+	query = strings.ToLower(query)
+	return a.filter(func(node Node) bool { return strings.Contains(strings.ToLower(node.Product), query) })
+}
+
+func (a *Availability) filter(keep func(Node) bool) *Availability {
+	filtered := &Availability{Nodes: []Node{}}
+	for _, node := range a.Nodes {
+		if keep(node) {
+			filtered.Nodes = append(filtered.Nodes, node)
+		}
+	}
+
+	return filtered
+}
+
+// PodSpecRequest returns the number of GPUs requested by pods using the pod specification,
+// along with the GPU product required by its node selector, if any.
+func PodSpecRequest(spec corev1.PodSpec) (int64, string) {
+	return PodRequest(corev1.Pod{Spec: spec}), spec.NodeSelector[ProductLabel]
+}
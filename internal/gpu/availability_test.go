@@ -0,0 +1,146 @@
+package gpu
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newNode(name, product string, gpus string) corev1.Node {
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{ProductLabel: product, MemoryLabel: "40960"},
+		},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{ResourceName: resource.MustParse(gpus)},
+		},
+	}
+}
+
+func newPod(namespace, node string, gpus string) corev1.Pod {
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace},
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{
+					Limits: corev1.ResourceList{ResourceName: resource.MustParse(gpus)},
+				},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+}
+
+func TestFromCluster(t *testing.T) {
+	cordoned := newNode("c", "Tesla-V100", "4")
+	cordoned.Spec.Unschedulable = true
+
+	nodes := []corev1.Node{
+		newNode("b", "A100", "8"),
+		newNode("a", "A100", "8"),
+		cordoned,
+		{ObjectMeta: metav1.ObjectMeta{Name: "cpu-only"}},
+	}
+
+	finished := newPod("ml", "a", "4")
+	finished.Status.Phase = corev1.PodSucceeded
+
+	pods := []corev1.Pod{
+		newPod("ml", "a", "2"),
+		newPod("vision", "a", "1"),
+		newPod("ml", "c", "1"),
+		newPod("ml", "", "8"), // Pending, not yet scheduled.
+		finished,
+	}
+
+	availability := FromCluster(nodes, pods)
+	assert.Len(t, availability.Nodes, 3)
+
+	a := availability.Nodes[0]
+	assert.Equal(t, "a", a.Name)
+	assert.Equal(t, int64(8), a.Total)
+	assert.Equal(t, int64(3), a.Used)
+	assert.Equal(t, int64(5), a.Free)
+	assert.Equal(t, int64(40960), a.MemoryMiB)
+	assert.Equal(t, map[string]int64{"ml": 2, "vision": 1}, a.Namespaces)
+
+	c := availability.Nodes[2]
+	assert.True(t, c.Unschedulable)
+	assert.Equal(t, int64(0), c.Free)
+
+	products := availability.Products()
+	assert.Len(t, products, 2)
+	assert.Equal(t, "A100", products[0].Name)
+	assert.Equal(t, int64(13), products[0].Free)
+	assert.Equal(t, int64(8), products[0].MaxFree)
+	assert.Equal(t, 2, products[0].Nodes)
+}
+
+func TestPodRequestInitContainers(t *testing.T) {
+	pod := newPod("ml", "a", "1")
+	pod.Spec.InitContainers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{ResourceName: resource.MustParse("2")},
+		},
+	}}
+
+	assert.Equal(t, int64(2), PodRequest(pod))
+}
+
+func TestResourceRequest(t *testing.T) {
+	spec := corev1.PodSpec{
+		Containers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")}}},
+			{Resources: corev1.ResourceRequirements{Limits: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}},
+		},
+		InitContainers: []corev1.Container{
+			{Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}},
+		},
+	}
+
+	qty := ResourceRequest(spec, corev1.ResourceCPU)
+	assert.Equal(t, "1500m", qty.String())
+
+	qty = ResourceRequest(spec, corev1.ResourceMemory)
+	assert.True(t, qty.IsZero())
+}
+
+func TestFilter(t *testing.T) {
+	availability := &Availability{Nodes: []Node{
+		{Name: "a", Product: "A100", Free: 2},
+		{Name: "b", Product: "V100", Free: 4},
+	}}
+
+	assert.Len(t, availability.Filter("").Nodes, 2)
+	assert.Equal(t, int64(2), availability.Filter("A100").MaxFree())
+	assert.Empty(t, availability.Filter("H100").Nodes)
+}
+
+func TestNodesWithFree(t *testing.T) {
+	availability := &Availability{Nodes: []Node{
+		{Name: "a", Free: 2},
+		{Name: "b", Free: 4},
+		{Name: "c", Free: 8},
+	}}
+
+	assert.Equal(t, 3, availability.NodesWithFree(2))
+	assert.Equal(t, 2, availability.NodesWithFree(4))
+	assert.Equal(t, 0, availability.NodesWithFree(16))
+}
+
+func TestMatching(t *testing.T) {
+	availability := &Availability{Nodes: []Node{
+		{Name: "a", Product: "NVIDIA-A100-SXM4-40GB"},
+		{Name: "b", Product: "Tesla-V100-PCIE-32GB"},
+	}}
+
+	assert.Len(t, availability.Matching("").Nodes, 2)
+	if assert.Len(t, availability.Matching("a100").Nodes, 1) {
+		assert.Equal(t, "a", availability.Matching("a100").Nodes[0].Name)
+	}
+}
@@ -0,0 +1,149 @@
+package gpu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/uitml/frink/internal/k8s"
+	"k8s.io/client-go/rest"
+)
+
+// Source provides the current GPU availability of the cluster.
+type Source interface {
+	Availability(ctx context.Context) (*Availability, error)
+}
+
+// ServiceClient fetches GPU availability from the gpu-viewer service.
+//
+// The client is agnostic to how the service is reached, be it directly (port-forward or ingress),
+// or via the API server service proxy; the difference lies in the base URL and the HTTP transport.
+type ServiceClient struct {
+	// BaseURL is the URL of the service root.
+	BaseURL string
+
+	// HTTPClient is used to perform requests; it must be authenticated when using the API server proxy.
+	HTTPClient *http.Client
+}
+
+// NewServiceClient returns a ServiceClient that reaches the service directly at baseURL.
+func NewServiceClient(baseURL string, timeout time.Duration) *ServiceClient {
+	return &ServiceClient{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Timeout: timeout},
+	}
+}
+
+// ProxyOptions identifies the gpu-viewer service when reached via the API server service proxy.
+type ProxyOptions struct {
+	Namespace string
+	Service   string
+	Scheme    string
+	Port      string
+}
+
+// NewProxyServiceClient returns a ServiceClient that reaches the service via the API server service proxy.
+// This requires RBAC permissions to get services/proxy in the service namespace.
+func NewProxyServiceClient(config *rest.Config, opts ProxyOptions, timeout time.Duration) (*ServiceClient, error) {
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("transport build failed: %w", err)
+	}
+
+	host := strings.TrimRight(config.Host, "/")
+	baseURL := fmt.Sprintf("%s/api/v1/namespaces/%s/services/%s:%s:%s/proxy/",
+		host, opts.Namespace, opts.Scheme, opts.Service, opts.Port,
+	)
+
+	client := &ServiceClient{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{Transport: transport, Timeout: timeout},
+	}
+
+	return client, nil
+}
+
+// Availability requests the GPU availability from the service in JSON format.
+func (c *ServiceClient) Availability(ctx context.Context) (*Availability, error) {
+	u, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid service URL: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("format", "json")
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		b, _ := ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("service error: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	availability := &Availability{}
+	if err := json.NewDecoder(resp.Body).Decode(availability); err != nil {
+		return nil, fmt.Errorf("invalid service response: %w", err)
+	}
+	availability.Sort()
+
+	return availability, nil
+}
+
+// ClusterSource computes GPU availability from the nodes and pods in the cluster; see FromCluster.
+type ClusterSource struct {
+	Client k8s.Client
+}
+
+// Availability lists the nodes and non-terminated pods in the cluster, and computes their GPU availability.
+func (s *ClusterSource) Availability(ctx context.Context) (*Availability, error) {
+	nodes, err := s.Client.ListNodes()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list nodes: %w", err)
+	}
+
+	pods, err := s.Client.ListActivePods()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list pods: %w", err)
+	}
+
+	return FromCluster(nodes, pods), nil
+}
+
+// FallbackSource uses the Primary source, and falls back to the Secondary source if the primary fails.
+type FallbackSource struct {
+	Primary   Source
+	Secondary Source
+
+	// OnFallback, if set, is called with the error of the primary source before falling back.
+	OnFallback func(err error)
+}
+
+// Availability returns the availability provided by the primary source, or else the secondary source.
+func (s *FallbackSource) Availability(ctx context.Context) (*Availability, error) {
+	availability, err := s.Primary.Availability(ctx)
+	if err == nil {
+		return availability, nil
+	}
+
+	if s.OnFallback != nil {
+		s.OnFallback(err)
+	}
+
+	return s.Secondary.Availability(ctx)
+}
@@ -0,0 +1,70 @@
+package gpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceClientAvailability(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "json", r.URL.Query().Get("format"))
+		fmt.Fprint(w, `{"nodes": [
+			{"name": "b", "product": "A100", "total": 8, "used": 2, "free": 6, "namespaces": {"alice": 2}},
+			{"name": "a", "product": "A100", "total": 8, "used": 8, "free": 0, "unschedulable": true}
+		]}`)
+	}))
+	defer server.Close()
+
+	client := NewServiceClient(server.URL, time.Second)
+	availability, err := client.Availability(context.Background())
+	assert.NoError(t, err)
+	if assert.Len(t, availability.Nodes, 2) {
+		assert.Equal(t, "a", availability.Nodes[0].Name)
+		assert.True(t, availability.Nodes[0].Unschedulable)
+		assert.Equal(t, int64(2), availability.Nodes[1].Namespaces["alice"])
+	}
+	assert.Equal(t, int64(6), availability.MaxFree())
+}
+
+func TestServiceClientError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	client := NewServiceClient(server.URL, time.Second)
+	_, err := client.Availability(context.Background())
+	assert.EqualError(t, err, "service error: 500 Internal Server Error: boom")
+}
+
+type staticSource struct {
+	availability *Availability
+	err          error
+}
+
+func (s staticSource) Availability(ctx context.Context) (*Availability, error) {
+	return s.availability, s.err
+}
+
+func TestFallbackSource(t *testing.T) {
+	expected := &Availability{Nodes: []Node{{Name: "a"}}}
+
+	var fallbackErr error
+	source := &FallbackSource{
+		Primary:    staticSource{err: errors.New("unreachable")},
+		Secondary:  staticSource{availability: expected},
+		OnFallback: func(err error) { fallbackErr = err },
+	}
+
+	availability, err := source.Availability(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, availability)
+	assert.EqualError(t, fallbackErr, "unreachable")
+}
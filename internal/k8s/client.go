@@ -6,9 +6,11 @@ package k8s
 
 import (
 	"fmt"
+	"sort"
 
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
@@ -19,12 +21,32 @@ type Client interface {
 	CreateJob(job *batchv1.Job) error
 	DeleteJob(name string) error
 	GetJob(name string) (*batchv1.Job, error)
+	SuspendJob(name string, suspend bool) error
+	FailJob(name, reason string) error
 	GetJobLogs(name string, opts *corev1.PodLogOptions) (*rest.Request, error)
 	ListJobs() ([]batchv1.Job, error)
+	WatchJobs() (watch.Interface, error)
+	WatchPods(selector string) (watch.Interface, error)
+	CreateCronJob(cronJob *batchv1.CronJob) error
+	DeleteCronJob(name string) error
+	GetCronJob(name string) (*batchv1.CronJob, error)
+	ListCronJobs() ([]batchv1.CronJob, error)
 	GetJobEvents(name string) (string, error)
 	GetPodEvents(name string) (string, error)
 	GetPodsFromJob(jobName string) ([]string, error)
+	ListJobPods(jobName string) ([]corev1.Pod, error)
 	GetJobFromPod(podName string) (string, error)
+	ListNodes() ([]corev1.Node, error)
+	ListNamespaces() ([]corev1.Namespace, error)
+	ListActivePods() ([]corev1.Pod, error)
+	ListResourceQuotas() ([]corev1.ResourceQuota, error)
+	GetConfigMap(name string) (*corev1.ConfigMap, error)
+	ListConfigMaps(selector string) ([]corev1.ConfigMap, error)
+	CreateConfigMap(configMap *corev1.ConfigMap) error
+	UpdateConfigMap(configMap *corev1.ConfigMap) error
+	DeleteConfigMap(name string) error
+	CreateService(service *corev1.Service) error
+	DeleteService(name string) error
 }
 
 // NamespaceClient represents a namespaced Kubernetes API client.
@@ -50,6 +72,28 @@ func NewClient(context, namespace string) (Client, error) {
 	return client, nil
 }
 
+// NewRESTConfig returns the API server client config and the namespace for the specified context and namespace.
+// If namespace is empty, the namespace of the context is returned.
+func NewRESTConfig(context, namespace string) (*rest.Config, string, error) {
+	return buildClientConfig(context, namespace)
+}
+
+// ContextNames returns the names of the contexts in the kubeconfig, sorted by name.
+func ContextNames() ([]string, error) {
+	config, err := buildClientCmd("", "").RawConfig()
+	if err != nil {
+		return nil, fmt.Errorf("could not load kubeconfig: %w", err)
+	}
+
+	names := make([]string, 0, len(config.Contexts))
+	for name := range config.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
 // buildClientConfig returns a complete client config and the namespace for the given context.
 func buildClientConfig(context, namespace string) (*rest.Config, string, error) {
 	clientConfig := buildClientCmd(context, namespace)
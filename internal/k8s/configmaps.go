@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetConfigMap returns the config map with the given name, or nil if it does not exist.
+func (client *NamespaceClient) GetConfigMap(name string) (*corev1.ConfigMap, error) {
+	configMap, err := client.Clientset.CoreV1().ConfigMaps(client.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return configMap, nil
+}
+
+// ListConfigMaps returns the config maps matching the label selector.
+func (client *NamespaceClient) ListConfigMaps(selector string) ([]corev1.ConfigMap, error) {
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+	configMaps, err := client.Clientset.CoreV1().ConfigMaps(client.Namespace).List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return configMaps.Items, nil
+}
+
+// CreateConfigMap creates the config map.
+func (client *NamespaceClient) CreateConfigMap(configMap *corev1.ConfigMap) error {
+	_, err := client.Clientset.CoreV1().ConfigMaps(client.Namespace).Create(context.TODO(), configMap, metav1.CreateOptions{})
+	return err
+}
+
+// UpdateConfigMap replaces the existing config map with the same name.
+func (client *NamespaceClient) UpdateConfigMap(configMap *corev1.ConfigMap) error {
+	_, err := client.Clientset.CoreV1().ConfigMaps(client.Namespace).Update(context.TODO(), configMap, metav1.UpdateOptions{})
+	return err
+}
+
+// DeleteConfigMap deletes the config map with the given name.
+func (client *NamespaceClient) DeleteConfigMap(name string) error {
+	err := client.Clientset.CoreV1().ConfigMaps(client.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
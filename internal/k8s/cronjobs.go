@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// cronMacros are the schedules supported by Kubernetes in place of the five cron fields.
+var cronMacros = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+// ListCronJobs returns all cron jobs.
+func (client *NamespaceClient) ListCronJobs() ([]batchv1.CronJob, error) {
+	cronJobs, err := client.Clientset.BatchV1().CronJobs(client.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return cronJobs.Items, nil
+}
+
+// GetCronJob returns the cron job with the given name, or nil if it does not exist.
+func (client *NamespaceClient) GetCronJob(name string) (*batchv1.CronJob, error) {
+	cronJob, err := client.Clientset.BatchV1().CronJobs(client.Namespace).Get(context.TODO(), name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	return cronJob, nil
+}
+
+// CreateCronJob creates the cron job.
+func (client *NamespaceClient) CreateCronJob(cronJob *batchv1.CronJob) error {
+	_, err := client.Clientset.BatchV1().CronJobs(client.Namespace).Create(context.TODO(), cronJob, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteCronJob deletes the cron job with the given name, along with the jobs it created.
+// Deleting a cron job that does not exist is not an error.
+func (client *NamespaceClient) DeleteCronJob(name string) error {
+	deletePolicy := metav1.DeletePropagationBackground
+	deleteOptions := metav1.DeleteOptions{PropagationPolicy: &deletePolicy}
+
+	err := client.Clientset.BatchV1().CronJobs(client.Namespace).Delete(context.TODO(), name, deleteOptions)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
+// NewCronJob returns a cron job creating jobs from the specification of job on the given schedule.
+// The cron job has the name and labels of job, and its jobs are named "<name>-<scheduled time>".
+// A run of the cron job is skipped while its previous job is still active.
+func NewCronJob(job *batchv1.Job, schedule string) (*batchv1.CronJob, error) {
+	if err := ValidateSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	// The names of the pods of distributed jobs are derived from the name of the job, which is not known in advance.
+	if IsDistributed(*job) {
+		return nil, fmt.Errorf("distributed jobs cannot be scheduled")
+	}
+
+	// Kubernetes appends an 11 character suffix to the names of the jobs created by the cron job.
+	if len(job.Name) > 52 {
+		return nil, fmt.Errorf("name of scheduled job %s must be at most 52 characters", job.Name)
+	}
+
+	template := job.DeepCopy()
+	cronJob := &batchv1.CronJob{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        template.Name,
+			Labels:      template.Labels,
+			Annotations: template.Annotations,
+		},
+		Spec: batchv1.CronJobSpec{
+			Schedule:          schedule,
+			ConcurrencyPolicy: batchv1.ForbidConcurrent,
+			Suspend:           template.Spec.Suspend,
+			JobTemplate: batchv1.JobTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      template.Labels,
+					Annotations: template.Annotations,
+				},
+				Spec: template.Spec,
+			},
+		},
+	}
+	// Suspending a cron job suspends its schedule, rather than the jobs it creates.
+	cronJob.Spec.JobTemplate.Spec.Suspend = nil
+
+	return cronJob, nil
+}
+
+// ValidateSchedule returns an error if schedule is neither five cron fields, such as "0 2 * * *", nor a macro such as "@daily".
+// The fields themselves are validated by the API server when the cron job is created.
+func ValidateSchedule(schedule string) error {
+	if strings.HasPrefix(schedule, "@") {
+		for _, macro := range cronMacros {
+			if schedule == macro {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("invalid schedule %q: must be one of %v", schedule, cronMacros)
+	}
+
+	if fields := strings.Fields(schedule); len(fields) != 5 {
+		return fmt.Errorf("invalid schedule %q: must have five fields (minute, hour, day of month, month, day of week)", schedule)
+	}
+
+	return nil
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewCronJob(t *testing.T) {
+	suspend := true
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "nightly", Labels: map[string]string{UserLabel: "jane"}},
+		Spec:       batchv1.JobSpec{Suspend: &suspend},
+	}
+
+	cronJob, err := NewCronJob(job, "0 2 * * *")
+	assert.NoError(t, err)
+	assert.Equal(t, "nightly", cronJob.Name)
+	assert.Equal(t, "0 2 * * *", cronJob.Spec.Schedule)
+	assert.Equal(t, batchv1.ForbidConcurrent, cronJob.Spec.ConcurrencyPolicy)
+	assert.Equal(t, "jane", cronJob.Spec.JobTemplate.Labels[UserLabel])
+
+	// The schedule is suspended, rather than the jobs created by it.
+	assert.True(t, *cronJob.Spec.Suspend)
+	assert.Nil(t, cronJob.Spec.JobTemplate.Spec.Suspend)
+	assert.True(t, *job.Spec.Suspend)
+}
+
+func TestNewCronJobInvalid(t *testing.T) {
+	_, err := NewCronJob(newDistributedJob(), "@daily")
+	assert.EqualError(t, err, "distributed jobs cannot be scheduled")
+
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 53)}}
+	_, err = NewCronJob(job, "@daily")
+	assert.Error(t, err)
+}
+
+func TestValidateSchedule(t *testing.T) {
+	assert.NoError(t, ValidateSchedule("0 2 * * *"))
+	assert.NoError(t, ValidateSchedule("*/15 * * * 1-5"))
+	assert.NoError(t, ValidateSchedule("@hourly"))
+
+	assert.Error(t, ValidateSchedule(""))
+	assert.Error(t, ValidateSchedule("0 2 * *"))
+	assert.Error(t, ValidateSchedule("@sometimes"))
+}
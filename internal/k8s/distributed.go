@@ -0,0 +1,139 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// MasterPort is the port used for the rendezvous of the pods of distributed jobs.
+const MasterPort = 29500
+
+// completionIndexAnnotation holds the completion index of the pods of Indexed jobs.
+const completionIndexAnnotation = "batch.kubernetes.io/job-completion-index"
+
+// Environment variables set in the containers of distributed jobs, as used to launch torchrun, e.g.
+// "torchrun --nnodes=$WORLD_SIZE --node_rank=$NODE_RANK --nproc_per_node=$NPROC_PER_NODE
+// --master_addr=$MASTER_ADDR --master_port=$MASTER_PORT train.py".
+const (
+	MasterAddrEnv   = "MASTER_ADDR"
+	MasterPortEnv   = "MASTER_PORT"
+	WorldSizeEnv    = "WORLD_SIZE"
+	RankEnv         = "RANK"
+	NodeRankEnv     = "NODE_RANK"
+	NprocPerNodeEnv = "NPROC_PER_NODE"
+)
+
+// SetDistributed turns the job into an Indexed job running one pod on each of the given number of nodes.
+//
+// The pods reach each other via the headless service returned by HeadlessService, where the pod with index 0 is
+// the master. Their containers have the environment variables used to launch torchrun set, with WORLD_SIZE being
+// the number of nodes, RANK and NODE_RANK the index of the pod, and NPROC_PER_NODE the number of GPUs per node.
+func SetDistributed(job *batchv1.Job, nodes int32, gpusPerNode int64) {
+	indexed := batchv1.IndexedCompletion
+	job.Spec.CompletionMode = &indexed
+	job.Spec.Completions = &nodes
+	job.Spec.Parallelism = &nodes
+
+	rank := &corev1.EnvVarSource{FieldRef: &corev1.ObjectFieldSelector{
+		FieldPath: fmt.Sprintf("metadata.annotations['%s']", completionIndexAnnotation),
+	}}
+	nproc := gpusPerNode
+	if nproc < 1 {
+		nproc = 1
+	}
+
+	for _, env := range []corev1.EnvVar{
+		{Name: MasterPortEnv, Value: strconv.Itoa(MasterPort)},
+		{Name: WorldSizeEnv, Value: strconv.Itoa(int(nodes))},
+		{Name: RankEnv, ValueFrom: rank},
+		{Name: NodeRankEnv, ValueFrom: rank},
+		{Name: NprocPerNodeEnv, Value: strconv.FormatInt(nproc, 10)},
+	} {
+		setEnv(job, env)
+	}
+
+	setDistributedName(job)
+}
+
+// IsDistributed reports whether the job has been set up by SetDistributed.
+func IsDistributed(job batchv1.Job) bool {
+	mode := job.Spec.CompletionMode
+	return mode != nil && *mode == batchv1.IndexedCompletion && job.Spec.Template.Spec.Subdomain == job.Name
+}
+
+// Rename changes the name of the job, including the names derived from it by SetDistributed.
+func Rename(job *batchv1.Job, name string) {
+	distributed := IsDistributed(*job)
+	job.Name = name
+	if distributed {
+		setDistributedName(job)
+	}
+}
+
+// setDistributedName sets the fields of a distributed job that are derived from its name.
+// Pods of Indexed jobs have the hostname "<job>-<index>", so the master is reachable as "<job>-0.<service>".
+func setDistributedName(job *batchv1.Job) {
+	job.Spec.Template.Spec.Subdomain = job.Name
+	setEnv(job, corev1.EnvVar{Name: MasterAddrEnv, Value: fmt.Sprintf("%s-0.%s", job.Name, job.Name)})
+}
+
+// HeadlessService returns the headless service that gives the pods of a distributed job DNS names,
+// or nil if the job is not distributed. The service is owned by the job if it has been created.
+func HeadlessService(job batchv1.Job) *corev1.Service {
+	if !IsDistributed(job) {
+		return nil
+	}
+
+	service := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   job.Spec.Template.Spec.Subdomain,
+			Labels: map[string]string{"job-name": job.Name},
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP: corev1.ClusterIPNone,
+			Selector:  map[string]string{"job-name": job.Name},
+			Ports: []corev1.ServicePort{{
+				Name:       "master",
+				Port:       MasterPort,
+				TargetPort: intstr.FromInt(MasterPort),
+			}},
+			// Pods must be resolvable before they are ready, so that they can find each other during startup.
+			PublishNotReadyAddresses: true,
+		},
+	}
+
+	if job.UID != "" {
+		service.OwnerReferences = []metav1.OwnerReference{{
+			APIVersion: "batch/v1",
+			Kind:       "Job",
+			Name:       job.Name,
+			UID:        job.UID,
+		}}
+	}
+
+	return service
+}
+
+// CreateJobService creates the headless service of the job, if it is distributed, once the job has been created.
+// The service is owned by the job, so that it is deleted along with the job. An existing service, e.g. of a job
+// with the same name that was replaced, is taken over by the job; see Client.CreateService.
+func CreateJobService(client Client, job *batchv1.Job) error {
+	if !IsDistributed(*job) {
+		return nil
+	}
+
+	created, err := client.GetJob(job.Name)
+	if err != nil {
+		return err
+	}
+	if created == nil {
+		return fmt.Errorf("job %s not found", job.Name)
+	}
+
+	return client.CreateService(HeadlessService(*created))
+}
@@ -0,0 +1,91 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func newDistributedJob() *batchv1.Job {
+	simple := &SimpleJob{
+		Name:        "foo",
+		GPU:         resource.MustParse("1"),
+		Nodes:       2,
+		GPUsPerNode: resource.MustParse("4"),
+	}
+
+	return simple.Expand()
+}
+
+func envValue(container corev1.Container, name string) string {
+	for _, env := range container.Env {
+		if env.Name == name {
+			return env.Value
+		}
+	}
+
+	return ""
+}
+
+func TestExpandDistributedJob(t *testing.T) {
+	job := newDistributedJob()
+	container := job.Spec.Template.Spec.Containers[0]
+
+	assert.True(t, IsDistributed(*job))
+	assert.Equal(t, batchv1.IndexedCompletion, *job.Spec.CompletionMode)
+	assert.Equal(t, int32(2), *job.Spec.Completions)
+	assert.Equal(t, int32(2), *job.Spec.Parallelism)
+	assert.Equal(t, "foo", job.Spec.Template.Spec.Subdomain)
+	assert.Equal(t, resource.MustParse("4"), container.Resources.Limits["nvidia.com/gpu"])
+
+	assert.Equal(t, "foo-0.foo", envValue(container, MasterAddrEnv))
+	assert.Equal(t, "29500", envValue(container, MasterPortEnv))
+	assert.Equal(t, "2", envValue(container, WorldSizeEnv))
+	assert.Equal(t, "4", envValue(container, NprocPerNodeEnv))
+	for _, env := range container.Env {
+		if env.Name == RankEnv || env.Name == NodeRankEnv {
+			assert.Equal(t, "metadata.annotations['batch.kubernetes.io/job-completion-index']", env.ValueFrom.FieldRef.FieldPath)
+		}
+	}
+}
+
+func TestExpandSingleNodeJob(t *testing.T) {
+	simple := &SimpleJob{Name: "foo", Nodes: 1}
+	job := simple.Expand()
+
+	assert.False(t, IsDistributed(*job))
+	assert.Nil(t, HeadlessService(*job))
+}
+
+func TestRenameDistributedJob(t *testing.T) {
+	job := newDistributedJob()
+	Rename(job, "bar")
+
+	assert.Equal(t, "bar", job.Name)
+	assert.True(t, IsDistributed(*job))
+	assert.Equal(t, "bar-0.bar", envValue(job.Spec.Template.Spec.Containers[0], MasterAddrEnv))
+}
+
+func TestHeadlessService(t *testing.T) {
+	job := newDistributedJob()
+	job.UID = "1234"
+
+	service := HeadlessService(*job)
+	assert.Equal(t, "foo", service.Name)
+	assert.Equal(t, corev1.ClusterIPNone, service.Spec.ClusterIP)
+	assert.Equal(t, map[string]string{"job-name": "foo"}, service.Spec.Selector)
+	assert.Equal(t, job.UID, service.OwnerReferences[0].UID)
+}
+
+func TestResumeDistributedJob(t *testing.T) {
+	job := newDistributedJob()
+
+	resumed, err := ResumeJob(job)
+	assert.NoError(t, err)
+	assert.True(t, IsDistributed(*resumed))
+	assert.Equal(t, "foo-attempt-2", resumed.Spec.Template.Spec.Subdomain)
+	assert.Equal(t, "foo-attempt-2-0.foo-attempt-2", envValue(resumed.Spec.Template.Spec.Containers[0], MasterAddrEnv))
+}
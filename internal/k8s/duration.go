@@ -0,0 +1,131 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Duration is a duration in a human-friendly format, such as "90m", "12h" or "7d", encoded as a string.
+//
+// The value "none" explicitly disables whatever the duration is used for, e.g. to override a default,
+// and is represented by a negative duration.
+type Duration time.Duration
+
+// DurationNone is the value of durations set to "none".
+const DurationNone = Duration(-1)
+
+var durationUnits = regexp.MustCompile(`(\d+(?:\.\d+)?)([dw])`)
+
+// ParseDuration parses a duration such as "1d12h" or "2w", i.e. time.ParseDuration with support for
+// days ("d") and weeks ("w") in addition to the standard units. A plain number is treated as seconds.
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if seconds, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	var err error
+	expanded := durationUnits.ReplaceAllStringFunc(s, func(m string) string {
+		parts := durationUnits.FindStringSubmatch(m)
+		n, e := strconv.ParseFloat(parts[1], 64)
+		if e != nil {
+			err = e
+		}
+
+		hours := n * 24
+		if parts[2] == "w" {
+			hours *= 7
+		}
+
+		return strconv.FormatFloat(hours, 'f', -1, 64) + "h"
+	})
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	d, err := time.ParseDuration(expanded)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+
+	return d, nil
+}
+
+// UnmarshalJSON parses the duration from a string, or a number of seconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var seconds float64
+		if err := json.Unmarshal(b, &seconds); err != nil {
+			return fmt.Errorf("invalid duration %s", string(b))
+		}
+
+		*d = Duration(seconds * float64(time.Second))
+		return nil
+	}
+
+	if s == "none" {
+		*d = DurationNone
+		return nil
+	}
+
+	parsed, err := ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	if parsed < 0 {
+		return fmt.Errorf("invalid duration %q: must not be negative", s)
+	}
+	*d = Duration(parsed)
+
+	return nil
+}
+
+// MarshalJSON encodes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// String returns the duration in the format accepted by ParseDuration, e.g. "7d" or "36h0m0s".
+func (d Duration) String() string {
+	if d < 0 {
+		return "none"
+	}
+
+	day := Duration(24 * time.Hour)
+	if d >= day && d%day == 0 {
+		return fmt.Sprintf("%dd", d/day)
+	}
+
+	return time.Duration(d).String()
+}
+
+// Seconds returns the duration in whole seconds, rounded up, or nil if the duration is "none".
+func (d Duration) Seconds() *int64 {
+	if d < 0 {
+		return nil
+	}
+
+	seconds := int64((time.Duration(d) + time.Second - 1) / time.Second)
+	return &seconds
+}
+
+// TTLSeconds returns the duration as Seconds does, as used for TTLSecondsAfterFinished, which is limited to an int32.
+func (d Duration) TTLSeconds() (*int32, error) {
+	seconds := d.Seconds()
+	if seconds == nil {
+		return nil, nil
+	}
+
+	if *seconds > math.MaxInt32 {
+		return nil, fmt.Errorf("invalid ttl %s: must be at most %d seconds", d, math.MaxInt32)
+	}
+
+	ttl := int32(*seconds)
+	return &ttl, nil
+}
@@ -0,0 +1,49 @@
+package k8s
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"90m":   90 * time.Minute,
+		"7d":    7 * 24 * time.Hour,
+		"1d12h": 36 * time.Hour,
+		"2w":    14 * 24 * time.Hour,
+		"0.5d":  12 * time.Hour,
+		"3600":  time.Hour,
+	}
+
+	for s, expected := range tests {
+		d, err := ParseDuration(s)
+		assert.NoError(t, err, s)
+		assert.Equal(t, expected, d, s)
+	}
+
+	_, err := ParseDuration("a week")
+	assert.EqualError(t, err, `invalid duration "a week"`)
+}
+
+func TestDurationJSON(t *testing.T) {
+	var d Duration
+	assert.NoError(t, json.Unmarshal([]byte(`"7d"`), &d))
+	assert.Equal(t, Duration(7*24*time.Hour), d)
+	assert.Equal(t, int64(604800), *d.Seconds())
+
+	b, err := json.Marshal(d)
+	assert.NoError(t, err)
+	assert.Equal(t, `"7d"`, string(b))
+
+	assert.NoError(t, json.Unmarshal([]byte(`"none"`), &d))
+	assert.Equal(t, DurationNone, d)
+	assert.Nil(t, d.Seconds())
+
+	assert.NoError(t, json.Unmarshal([]byte(`60`), &d))
+	assert.Equal(t, Duration(time.Minute), d)
+
+	assert.Error(t, json.Unmarshal([]byte(`"-1h"`), &d))
+}
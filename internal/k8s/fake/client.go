@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 )
 
@@ -88,3 +89,154 @@ func (client *Client) GetJobFromPod(podName string) (string, error) {
 	}
 	return jobName, args.Error(1)
 }
+
+// ListNodes simulates returning all nodes in the cluster.
+func (client *Client) ListNodes() ([]corev1.Node, error) {
+	args := client.Called()
+	nodes, _ := args.Get(0).([]corev1.Node)
+
+	return nodes, args.Error(1)
+}
+
+// ListNamespaces simulates returning all namespaces in the cluster.
+func (client *Client) ListNamespaces() ([]corev1.Namespace, error) {
+	args := client.Called()
+	namespaces, _ := args.Get(0).([]corev1.Namespace)
+
+	return namespaces, args.Error(1)
+}
+
+// ListActivePods simulates returning all non-terminated pods in all namespaces.
+func (client *Client) ListActivePods() ([]corev1.Pod, error) {
+	args := client.Called()
+	pods, _ := args.Get(0).([]corev1.Pod)
+
+	return pods, args.Error(1)
+}
+
+// ListResourceQuotas simulates returning the resource quotas in the namespace.
+func (client *Client) ListResourceQuotas() ([]corev1.ResourceQuota, error) {
+	args := client.Called()
+	quotas, _ := args.Get(0).([]corev1.ResourceQuota)
+
+	return quotas, args.Error(1)
+}
+
+// GetConfigMap simulates returning the config map with the given name.
+func (client *Client) GetConfigMap(name string) (*corev1.ConfigMap, error) {
+	args := client.Called(name)
+	configMap, _ := args.Get(0).(*corev1.ConfigMap)
+
+	return configMap, args.Error(1)
+}
+
+// ListConfigMaps simulates returning the config maps matching the label selector.
+func (client *Client) ListConfigMaps(selector string) ([]corev1.ConfigMap, error) {
+	args := client.Called(selector)
+	configMaps, _ := args.Get(0).([]corev1.ConfigMap)
+
+	return configMaps, args.Error(1)
+}
+
+// CreateConfigMap simulates creating a config map.
+func (client *Client) CreateConfigMap(configMap *corev1.ConfigMap) error {
+	args := client.Called(configMap)
+
+	return args.Error(0)
+}
+
+// UpdateConfigMap simulates updating a config map.
+func (client *Client) UpdateConfigMap(configMap *corev1.ConfigMap) error {
+	args := client.Called(configMap)
+
+	return args.Error(0)
+}
+
+// DeleteConfigMap simulates deleting a config map.
+func (client *Client) DeleteConfigMap(name string) error {
+	args := client.Called(name)
+
+	return args.Error(0)
+}
+
+// CreateService simulates creating a service.
+func (client *Client) CreateService(service *corev1.Service) error {
+	args := client.Called(service)
+
+	return args.Error(0)
+}
+
+// DeleteService simulates deleting a service.
+func (client *Client) DeleteService(name string) error {
+	args := client.Called(name)
+
+	return args.Error(0)
+}
+
+// SuspendJob simulates suspending or resuming a job.
+func (client *Client) SuspendJob(name string, suspend bool) error {
+	args := client.Called(name, suspend)
+
+	return args.Error(0)
+}
+
+// FailJob simulates failing a job right away.
+func (client *Client) FailJob(name, reason string) error {
+	args := client.Called(name, reason)
+
+	return args.Error(0)
+}
+
+// ListCronJobs simulates returning all cron jobs.
+func (client *Client) ListCronJobs() ([]batchv1.CronJob, error) {
+	args := client.Called()
+	cronJobs, _ := args.Get(0).([]batchv1.CronJob)
+
+	return cronJobs, args.Error(1)
+}
+
+// GetCronJob simulates returning the cron job with the given name.
+func (client *Client) GetCronJob(name string) (*batchv1.CronJob, error) {
+	args := client.Called(name)
+	cronJob, _ := args.Get(0).(*batchv1.CronJob)
+
+	return cronJob, args.Error(1)
+}
+
+// CreateCronJob simulates creating a cron job.
+func (client *Client) CreateCronJob(cronJob *batchv1.CronJob) error {
+	args := client.Called(cronJob)
+
+	return args.Error(0)
+}
+
+// DeleteCronJob simulates deleting a cron job.
+func (client *Client) DeleteCronJob(name string) error {
+	args := client.Called(name)
+
+	return args.Error(0)
+}
+
+// WatchJobs simulates watching jobs; use watch.NewFake to send events.
+func (client *Client) WatchJobs() (watch.Interface, error) {
+	args := client.Called()
+	w, _ := args.Get(0).(watch.Interface)
+
+	return w, args.Error(1)
+}
+
+// WatchPods simulates watching the pods matching the label selector; use watch.NewFake to send events.
+func (client *Client) WatchPods(selector string) (watch.Interface, error) {
+	args := client.Called(selector)
+	w, _ := args.Get(0).(watch.Interface)
+
+	return w, args.Error(1)
+}
+
+// ListJobPods simulates returning the pods of the job with the given name.
+func (client *Client) ListJobPods(jobName string) ([]corev1.Pod, error) {
+	args := client.Called(jobName)
+	pods, _ := args.Get(0).([]corev1.Pod)
+
+	return pods, args.Error(1)
+}
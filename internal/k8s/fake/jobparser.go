@@ -1,16 +1,20 @@
 package fake
 
 import (
+	"io"
+
 	"github.com/stretchr/testify/mock"
 	batchv1 "k8s.io/api/batch/v1"
 )
 
+// JobParser is a fake k8s.JobParser, primarily intended for unit testing.
 type JobParser struct {
 	mock.Mock
 }
 
-func (p *JobParser) Parse(b []byte) (*batchv1.Job, error) {
-	args := p.Called(b)
+// Parse simulates parsing the job specification read from r.
+func (p *JobParser) Parse(r io.Reader, source string) (*batchv1.Job, error) {
+	args := p.Called(r, source)
 	job, _ := args.Get(0).(*batchv1.Job)
 
 	return job, args.Error(1)
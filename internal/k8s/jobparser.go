@@ -1,46 +1,183 @@
 package k8s
 
 import (
-	"regexp"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
 
 	"github.com/spf13/afero"
 	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/yaml"
 )
 
+// StdinSource is the source name used to denote that a job specification should be read from stdin.
+const StdinSource = "-"
+
+// JobParser parses job specifications, either full k8s jobs or simplified jobs, into full job objects.
 type JobParser interface {
-	Parse(filename string) (*batchv1.Job, error)
+	// Parse reads a job specification in YAML or JSON format from r.
+	// The source name is only used to provide context in error messages.
+	Parse(r io.Reader, source string) (*batchv1.Job, error)
+}
+
+// ParserOptions configures how a JobParser processes job specifications.
+type ParserOptions struct {
+	// Expanders are applied in order to the raw job specification before it is decoded.
+	Expanders []Expander
+
+	// Profiles are the profiles that simplified job specifications can extend.
+	Profiles Profiles
+
+	// Profile is the profile to extend, overriding the extends field of simplified job specifications.
+	// Full k8s job specifications cannot extend profiles, so parsing them fails when Profile is set.
+	Profile string
+
+	// DefaultProfile is the profile to extend when a simplified job specification does not specify one.
+	// Unlike Profile, it is ignored for full k8s job specifications.
+	DefaultProfile string
+
+	// DefaultImage is the image used when a simplified job specification does not specify one, even via a profile.
+	DefaultImage string
+
+	// DefaultTTL and DefaultDeadline are used for jobs that do not specify a TTL or deadline, respectively,
+	// either in the specification or via a profile. Zero values leave such jobs without a TTL or deadline.
+	DefaultTTL      time.Duration
+	DefaultDeadline time.Duration
+
+	// DefaultRetries is the number of retries of jobs that do not specify one, either in the specification
+	// (as the backoff limit of full k8s jobs) or via a profile.
+	DefaultRetries int32
 }
 
 type jobParser struct {
-	JobParser
-	Fs afero.Fs
+	ParserOptions
+}
+
+// NewJobParser returns a JobParser that accepts both full and simplified job specifications.
+//
+// The expanders are applied in order to the raw job specification before it is decoded.
+func NewJobParser(expanders ...Expander) JobParser {
+	return NewJobParserWithOptions(ParserOptions{Expanders: expanders})
 }
 
-func NewJobParser(fs afero.Fs) JobParser {
-	return &jobParser{Fs: fs}
+// NewJobParserWithOptions returns a JobParser configured by opts.
+func NewJobParserWithOptions(opts ParserOptions) JobParser {
+	return &jobParser{ParserOptions: opts}
 }
 
-func (p *jobParser) Parse(filename string) (*batchv1.Job, error) {
-	b, err := afero.ReadFile(p.Fs, filename)
+func (p *jobParser) Parse(r io.Reader, source string) (*batchv1.Job, error) {
+	b, err := ioutil.ReadAll(r)
 	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	for _, expander := range p.Expanders {
+		if b, err = expander.Expand(b); err != nil {
+			return nil, fmt.Errorf("%s: unable to expand: %w", source, err)
+		}
+	}
+
+	job, err := p.parseJob(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+
+	return job, nil
+}
+
+// parseJob decodes b, which can be either YAML or JSON, into a full job object.
+// Documents that declare an apiVersion are treated as full k8s jobs, everything else as simplified jobs.
+func (p *jobParser) parseJob(b []byte) (*batchv1.Job, error) {
+	var meta metav1.TypeMeta
+	if err := yaml.Unmarshal(b, &meta); err != nil {
 		return nil, err
 	}
 
-	var job *batchv1.Job
-	re := regexp.MustCompile(`apiVersion:`)
-	if re.Match(b) {
-		job = &batchv1.Job{}
+	if meta.APIVersion != "" {
+		if p.Profile != "" {
+			return nil, fmt.Errorf("profile %q cannot be applied to a full job specification", p.Profile)
+		}
+
+		job := &batchv1.Job{}
 		if err := yaml.UnmarshalStrict(b, job); err != nil {
 			return nil, err
 		}
-	} else {
-		simple := &SimpleJob{}
-		if err := yaml.UnmarshalStrict(b, simple); err != nil {
+
+		if job.Spec.TTLSecondsAfterFinished == nil && p.DefaultTTL > 0 {
+			ttl, err := Duration(p.DefaultTTL).TTLSeconds()
+			if err != nil {
+				return nil, err
+			}
+			job.Spec.TTLSecondsAfterFinished = ttl
+		}
+		if job.Spec.ActiveDeadlineSeconds == nil && p.DefaultDeadline > 0 {
+			job.Spec.ActiveDeadlineSeconds = Duration(p.DefaultDeadline).Seconds()
+		}
+		if job.Spec.BackoffLimit == nil && p.DefaultRetries > 0 {
+			retries := p.DefaultRetries
+			job.Spec.BackoffLimit = &retries
+		}
+		if _, err := JobRetryPolicy(*job); err != nil {
+			return nil, err
+		}
+
+		return job, nil
+	}
+
+	simple := &SimpleJob{}
+	if err := yaml.UnmarshalStrict(b, simple); err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.Profile != "":
+		simple.Extends = p.Profile
+	case simple.Extends == "":
+		simple.Extends = p.DefaultProfile
+	}
+
+	resolved, err := p.Profiles.Resolve(simple)
+	if err != nil {
+		return nil, err
+	}
+
+	if resolved.Image == "" {
+		resolved.Image = p.DefaultImage
+	}
+	if resolved.TTL == nil && p.DefaultTTL > 0 {
+		ttl := Duration(p.DefaultTTL)
+		resolved.TTL = &ttl
+	}
+	if resolved.Deadline == nil && p.DefaultDeadline > 0 {
+		deadline := Duration(p.DefaultDeadline)
+		resolved.Deadline = &deadline
+	}
+	if resolved.Retries == nil && p.DefaultRetries > 0 {
+		retries := p.DefaultRetries
+		resolved.Retries = &retries
+	}
+	if resolved.TTL != nil {
+		if _, err := resolved.TTL.TTLSeconds(); err != nil {
+			return nil, err
+		}
+	}
+	if resolved.RetryOn != nil {
+		if err := resolved.RetryOn.Validate(); err != nil {
 			return nil, err
 		}
-		job = simple.Expand()
 	}
 
-	return job, nil
+	return resolved.Expand(), nil
+}
+
+// OpenSpec opens the job specification identified by filename.
+// If filename is StdinSource, stdin is returned instead of reading from fs.
+func OpenSpec(fs afero.Fs, stdin io.Reader, filename string) (io.ReadCloser, error) {
+	if filename == StdinSource {
+		return ioutil.NopCloser(stdin), nil
+	}
+
+	return fs.Open(filename)
 }
@@ -2,56 +2,170 @@ package k8s
 
 import (
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/spf13/afero"
 	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
 )
 
-func TestParseValidJobSpec(t *testing.T) {
+func parseFile(t *testing.T, filename string) (*batchv1.Job, error) {
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := NewJobParser(fs)
+	r, err := OpenSpec(fs, nil, filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	return NewJobParser().Parse(r, filename)
+}
 
-	job, err := parser.Parse("job/basic.yaml")
+func TestParseValidJobSpec(t *testing.T) {
+	job, err := parseFile(t, "job/basic.yaml")
 	assert.NoError(t, err)
 	assert.Equal(t, "foo", job.Name)
 }
 
 func TestParseInvalidJobSpec(t *testing.T) {
-	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := NewJobParser(fs)
-
-	job, err := parser.Parse("job/invalid.yaml")
+	job, err := parseFile(t, "job/invalid.yaml")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown field \"naem\"")
+	assert.Contains(t, err.Error(), "job/invalid.yaml")
 	assert.Nil(t, job)
 }
 
 func TestParseValidSimpleJobSpec(t *testing.T) {
-	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := NewJobParser(fs)
-
-	job, err := parser.Parse("simplejob/basic.yaml")
+	job, err := parseFile(t, "simplejob/basic.yaml")
 	assert.NoError(t, err)
 	assert.Equal(t, "foo", job.Name)
 }
 
 func TestParseInvalidSimpleJobSpec(t *testing.T) {
-	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := NewJobParser(fs)
+	job, err := parseFile(t, "simplejob/invalid.yaml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown field \"naem\"")
+	assert.Nil(t, job)
+}
 
-	job, err := parser.Parse("simplejob/invalid.yaml")
+func TestParseValidJSONJobSpec(t *testing.T) {
+	job, err := parseFile(t, "job/basic.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", job.Name)
+	assert.Equal(t, "/storage", job.Spec.Template.Spec.Containers[0].WorkingDir)
+}
+
+func TestParseValidJSONSimpleJobSpec(t *testing.T) {
+	job, err := parseFile(t, "simplejob/basic.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", job.Name)
+	assert.Equal(t, defaultVolumes, job.Spec.Template.Spec.Volumes)
+}
+
+func TestParseInvalidJSONSimpleJobSpec(t *testing.T) {
+	job, err := parseFile(t, "simplejob/invalid.json")
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "unknown field \"naem\"")
 	assert.Nil(t, job)
 }
 
-func TestParseMissingFile(t *testing.T) {
+func TestParseFromStdin(t *testing.T) {
+	stdin := strings.NewReader(`{"name": "foo", "image": "ubuntu:latest"}`)
+	r, err := OpenSpec(afero.NewMemMapFs(), stdin, StdinSource)
+	assert.NoError(t, err)
+
+	job, err := NewJobParser().Parse(r, "stdin")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", job.Name)
+}
+
+func TestOpenMissingFile(t *testing.T) {
 	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
-	parser := NewJobParser(fs)
 
-	job, err := parser.Parse("missing.yaml")
+	r, err := OpenSpec(fs, nil, "missing.yaml")
 	assert.Error(t, err)
 	assert.IsType(t, &os.PathError{}, err)
-	assert.Nil(t, job)
+	assert.Nil(t, r)
+}
+
+func TestParseWithDefaults(t *testing.T) {
+	profiles := Profiles{"cpu": SimpleJob{WorkingDir: "/storage"}}
+	parser := NewJobParserWithOptions(ParserOptions{
+		Profiles:       profiles,
+		DefaultProfile: "cpu",
+		DefaultImage:   "ubuntu:latest",
+	})
+
+	job, err := parser.Parse(strings.NewReader("name: foo\n"), "test")
+	assert.NoError(t, err)
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "ubuntu:latest", container.Image)
+	assert.Equal(t, "/storage", container.WorkingDir)
+
+	job, err = parser.Parse(strings.NewReader("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\n"), "test")
+	assert.NoError(t, err, "default profile should be ignored for full jobs")
+	assert.Equal(t, "foo", job.Name)
+}
+
+func TestParseWithDefaultTTLAndDeadline(t *testing.T) {
+	parser := NewJobParserWithOptions(ParserOptions{
+		DefaultTTL:      7 * 24 * time.Hour,
+		DefaultDeadline: 48 * time.Hour,
+	})
+
+	job, err := parser.Parse(strings.NewReader("name: foo\nttl: 1d\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(86400), *job.Spec.TTLSecondsAfterFinished)
+	assert.Equal(t, int64(172800), *job.Spec.ActiveDeadlineSeconds)
+
+	job, err = parser.Parse(strings.NewReader("name: foo\nttl: none\ndeadline: none\n"), "test")
+	assert.NoError(t, err)
+	assert.Nil(t, job.Spec.TTLSecondsAfterFinished, "none should override the default")
+	assert.Nil(t, job.Spec.ActiveDeadlineSeconds, "none should override the default")
+
+	job, err = parser.Parse(strings.NewReader("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\nspec:\n  ttlSecondsAfterFinished: 60\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(60), *job.Spec.TTLSecondsAfterFinished)
+	assert.Equal(t, int64(172800), *job.Spec.ActiveDeadlineSeconds)
+}
+
+func TestParseRejectsTTLOverflow(t *testing.T) {
+	_, err := NewJobParser().Parse(strings.NewReader("name: foo\nttl: 10000w\n"), "test")
+	assert.EqualError(t, err, "test: invalid ttl 70000d: must be at most 2147483647 seconds")
+
+	parser := NewJobParserWithOptions(ParserOptions{DefaultTTL: 10000 * 7 * 24 * time.Hour})
+	_, err = parser.Parse(strings.NewReader("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\n"), "test")
+	assert.Error(t, err)
+}
+
+func TestParseWithDefaultRetries(t *testing.T) {
+	parser := NewJobParserWithOptions(ParserOptions{DefaultRetries: 2})
+
+	job, err := parser.Parse(strings.NewReader("name: foo\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), *job.Spec.BackoffLimit)
+
+	job, err = parser.Parse(strings.NewReader("name: foo\nretries: 0\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(0), *job.Spec.BackoffLimit, "explicit retries should override the default")
+
+	job, err = parser.Parse(strings.NewReader("apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\nspec:\n  backoffLimit: 5\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), *job.Spec.BackoffLimit)
+}
+
+func TestParseRetryOn(t *testing.T) {
+	job, err := NewJobParser().Parse(strings.NewReader("name: foo\nretries: 3\nretryOn:\n  exitCodes: [137]\n  disruptions: true\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "137", job.Annotations[RetryOnExitCodesAnnotation])
+	assert.Equal(t, "true", job.Annotations[RetryOnDisruptionsAnnotation])
+
+	_, err = NewJobParser().Parse(strings.NewReader("name: foo\nretryOn:\n  exitCodes: [0]\n"), "test")
+	assert.EqualError(t, err, "test: invalid retryOn: exit code 0 cannot be retried")
+
+	full := "apiVersion: batch/v1\nkind: Job\nmetadata:\n  name: foo\n  annotations:\n    frink/retry-on-disruptions: maybe\n"
+	_, err = NewJobParser().Parse(strings.NewReader(full), "test")
+	assert.Error(t, err)
 }
@@ -2,7 +2,9 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/uitml/frink/internal/util"
@@ -11,6 +13,8 @@ import (
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/rest"
 )
 
@@ -20,6 +24,10 @@ var (
 	// Do not restart failing jobs.
 	defaultRestartPolicy = corev1.RestartPolicyOnFailure
 	defaultBackoffLimit  = util.Int32Ptr(0)
+
+	// Jobs that are retried replace failed pods, so that each attempt is counted, and pods lost
+	// due to e.g. node preemption are retried as well.
+	retryRestartPolicy = corev1.RestartPolicyNever
 )
 
 // DefaultLogOptions is the default set of options used when retrieving logs.
@@ -37,6 +45,18 @@ func (client *NamespaceClient) ListJobs() ([]batchv1.Job, error) {
 	return jobs.Items, nil
 }
 
+// WatchJobs returns a watch of changes to jobs, starting with an ADDED event for each existing job.
+// The watch is closed by the API server after a while, so callers must be prepared to watch again.
+func (client *NamespaceClient) WatchJobs() (watch.Interface, error) {
+	return client.Clientset.BatchV1().Jobs(client.Namespace).Watch(context.TODO(), metav1.ListOptions{})
+}
+
+// WatchPods returns a watch of changes to pods matching the label selector; see WatchJobs.
+func (client *NamespaceClient) WatchPods(selector string) (watch.Interface, error) {
+	listOptions := metav1.ListOptions{LabelSelector: selector}
+	return client.Clientset.CoreV1().Pods(client.Namespace).Watch(context.TODO(), listOptions)
+}
+
 // GetJob returns the job with the given name.
 func (client *NamespaceClient) GetJob(name string) (*batchv1.Job, error) {
 	getOptions := metav1.GetOptions{}
@@ -75,6 +95,48 @@ func (client *NamespaceClient) CreateJob(job *batchv1.Job) error {
 	return err
 }
 
+// SuspendJob suspends or resumes the job with the given name by setting spec.suspend.
+// Suspending a job deletes its active pods, and resuming it creates new pods.
+func (client *NamespaceClient) SuspendJob(name string, suspend bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend))
+	_, err := client.Clientset.BatchV1().Jobs(client.Namespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// FailJob makes the started job with the given name fail right away, terminating its pods, by setting a deadline of
+// one second. The reason is recorded in the frink/failed-fast annotation, and the previous deadline in the frink/deadline
+// annotation, so that it can be restored when the job is resumed; see ResumeJob.
+func (client *NamespaceClient) FailJob(name, reason string) error {
+	job, err := client.GetJob(name)
+	if err != nil {
+		return err
+	}
+	if job == nil {
+		return fmt.Errorf("job %s not found", name)
+	}
+	if job.Annotations[FailedFastAnnotation] != "" {
+		return nil
+	}
+
+	deadline := ""
+	if job.Spec.ActiveDeadlineSeconds != nil {
+		deadline = strconv.FormatInt(*job.Spec.ActiveDeadlineSeconds, 10)
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{FailedFastAnnotation: reason, DeadlineAnnotation: deadline},
+		},
+		"spec": map[string]interface{}{"activeDeadlineSeconds": 1},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = client.Clientset.BatchV1().Jobs(client.Namespace).Patch(context.TODO(), name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
 // GetJobLogs returns the pod logs for the job with the given name.
 func (client *NamespaceClient) GetJobLogs(name string, opts *corev1.PodLogOptions) (*rest.Request, error) {
 	getOptions := metav1.GetOptions{}
@@ -102,37 +164,6 @@ func (client *NamespaceClient) GetJobLogs(name string, opts *corev1.PodLogOption
 	return req, nil
 }
 
-// OverrideJobSpec removes zero quantity resources, and sets other important defaults.
-func OverrideJobSpec(job *batchv1.Job) {
-	containers := job.Spec.Template.Spec.Containers
-	for i := range containers {
-		container := &containers[i]
-		removeZeroResources(container)
-		setTerminationPolicy(container)
-	}
-
-	setRestartPolicy(job)
-}
-
-func removeZeroResources(container *corev1.Container) {
-	limits := container.Resources.Limits
-	for k, v := range limits {
-		if v.IsZero() {
-			// TODO: Notify user.
-			delete(limits, k)
-		}
-	}
-}
-
-func setTerminationPolicy(container *corev1.Container) {
-	container.TerminationMessagePolicy = defaultTerminationMessagePolicy
-}
-
-func setRestartPolicy(job *batchv1.Job) {
-	job.Spec.BackoffLimit = defaultBackoffLimit
-	job.Spec.Template.Spec.RestartPolicy = defaultRestartPolicy
-}
-
 func (client *NamespaceClient) GetPodEvents(podName string) (string, error) {
 	listOptions := metav1.ListOptions{
 		FieldSelector: fmt.Sprintf("involvedObject.name=%s,involvedObject.kind=Pod", podName),
@@ -168,6 +199,21 @@ func (client *NamespaceClient) GetJobEvents(jobName string) (string, error) {
 }
 
 func (client *NamespaceClient) GetPodsFromJob(jobName string) ([]string, error) {
+	pods, err := client.ListJobPods(jobName)
+	if err != nil {
+		return nil, err
+	}
+
+	var podNames []string
+	for _, pod := range pods {
+		podNames = append(podNames, pod.Name)
+	}
+
+	return podNames, nil
+}
+
+// ListJobPods returns the pods of the job with the given name.
+func (client *NamespaceClient) ListJobPods(jobName string) ([]corev1.Pod, error) {
 	job, err := client.GetJob(jobName)
 	if err != nil {
 		return nil, err
@@ -183,12 +229,7 @@ func (client *NamespaceClient) GetPodsFromJob(jobName string) ([]string, error)
 		return nil, err
 	}
 
-	var podNames []string
-	for _, pod := range pods.Items {
-		podNames = append(podNames, pod.Name)
-	}
-
-	return podNames, nil
+	return pods.Items, nil
 }
 
 func (client *NamespaceClient) GetJobFromPod(podName string) (string, error) {
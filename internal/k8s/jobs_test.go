@@ -108,40 +108,6 @@ func TestDeleteJob(t *testing.T) {
 	assert.EqualError(t, err, "baz")
 }
 
-func TestOverrideJobSpec(t *testing.T) {
-	job := newJob("foo", newZeroMemoryContainer())
-
-	container := &job.Spec.Template.Spec.Containers[0]
-
-	assert.Len(t, container.Resources.Limits, 2)
-	assert.NotEqual(t, defaultTerminationMessagePolicy, container.TerminationMessagePolicy)
-	assert.NotEqual(t, defaultBackoffLimit, job.Spec.BackoffLimit)
-	assert.NotEqual(t, defaultRestartPolicy, job.Spec.Template.Spec.RestartPolicy)
-
-	OverrideJobSpec(&job)
-
-	assert.Len(t, container.Resources.Limits, 1)
-	assert.Equal(t, defaultTerminationMessagePolicy, container.TerminationMessagePolicy)
-	assert.Equal(t, defaultBackoffLimit, job.Spec.BackoffLimit)
-	assert.Equal(t, defaultRestartPolicy, job.Spec.Template.Spec.RestartPolicy)
-}
-
-func TestRemoveZeroResources(t *testing.T) {
-	container := newZeroMemoryContainer()
-
-	qty, ok := container.Resources.Limits["memory"]
-	assert.True(t, ok)
-	assert.True(t, qty.IsZero())
-	assert.Len(t, container.Resources.Limits, 2)
-
-	removeZeroResources(&container)
-
-	qty, ok = container.Resources.Limits["memory"]
-	assert.False(t, ok)
-	assert.True(t, qty.IsZero())
-	assert.Len(t, container.Resources.Limits, 1)
-}
-
 func newJob(name string, containers ...corev1.Container) batchv1.Job {
 	return batchv1.Job{
 		ObjectMeta: v1.ObjectMeta{Name: name},
@@ -0,0 +1,56 @@
+package k8s
+
+import (
+	"regexp"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+// UserLabel is the label identifying the user who submitted a job; it is set on jobs and their pods.
+const UserLabel = "frink/user"
+
+// UnknownUser is used in place of the user of jobs without the user label.
+const UnknownUser = "unknown"
+
+var invalidLabelChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// SetUser labels the job and its pods with the (sanitized) name of the user submitting it.
+// An empty user leaves the job unchanged.
+func SetUser(job *batchv1.Job, user string) {
+	value := LabelValue(user)
+	if value == "" {
+		return
+	}
+
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[UserLabel] = value
+
+	if job.Spec.Template.Labels == nil {
+		job.Spec.Template.Labels = map[string]string{}
+	}
+	job.Spec.Template.Labels[UserLabel] = value
+}
+
+// JobUser returns the user who submitted the job, or UnknownUser if the job has no user label.
+func JobUser(job batchv1.Job) string {
+	if user := job.Labels[UserLabel]; user != "" {
+		return user
+	}
+
+	return UnknownUser
+}
+
+// LabelValue returns s as a valid label value, replacing invalid characters with "_",
+// and truncating it to 63 characters. E.g. "jane.doe@uit.no" becomes "jane.doe_uit.no".
+func LabelValue(s string) string {
+	s = invalidLabelChars.ReplaceAllString(s, "_")
+	if len(s) > 63 {
+		s = s[:63]
+	}
+
+	// Label values must begin and end with an alphanumeric character.
+	return strings.Trim(s, "_.-")
+}
@@ -0,0 +1,26 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+)
+
+func TestLabelValue(t *testing.T) {
+	assert.Equal(t, "jane.doe_uit.no", LabelValue("jane.doe@uit.no"))
+	assert.Equal(t, "abc", LabelValue("-abc-"))
+	assert.Len(t, LabelValue(string(make([]byte, 100))+"a"), 1)
+}
+
+func TestSetUser(t *testing.T) {
+	job := &batchv1.Job{}
+	assert.Equal(t, UnknownUser, JobUser(*job))
+
+	SetUser(job, "")
+	assert.Nil(t, job.Labels)
+
+	SetUser(job, "jane")
+	assert.Equal(t, "jane", JobUser(*job))
+	assert.Equal(t, "jane", job.Spec.Template.Labels[UserLabel])
+}
@@ -0,0 +1,201 @@
+package k8s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations listing the names of mutators, separated by commas, that are disabled or forced for a job; see MutateJob.
+const (
+	DisableMutatorsAnnotation = "frink/disable-mutators"
+	ForceMutatorsAnnotation   = "frink/force-mutators"
+)
+
+// Mutator adjusts a job specification before it is submitted.
+type Mutator struct {
+	// Name identifies the mutator in settings and annotations.
+	Name string
+
+	// Mutate applies the mutator to job, returning a description of each change made.
+	// Fields that are already set are only changed if force is true.
+	Mutate func(job *batchv1.Job, force bool) []string
+}
+
+// Mutators are the mutators applied by MutateJob, in order.
+var Mutators = []Mutator{
+	{Name: "remove-zero-resources", Mutate: mutateZeroResources},
+	{Name: "termination-message-policy", Mutate: mutateTerminationPolicy},
+	{Name: "backoff-limit", Mutate: mutateBackoffLimit},
+	{Name: "restart-policy", Mutate: mutateRestartPolicy},
+}
+
+// MutatorNames returns the names of all mutators, in the order they are applied.
+func MutatorNames() []string {
+	names := make([]string, len(Mutators))
+	for i, m := range Mutators {
+		names[i] = m.Name
+	}
+
+	return names
+}
+
+// ValidateMutatorNames returns an error if any of names does not identify a mutator.
+func ValidateMutatorNames(names []string) error {
+	for _, name := range names {
+		if !containsString(MutatorNames(), name) {
+			return fmt.Errorf("unknown mutator %q: must be one of %v", name, MutatorNames())
+		}
+	}
+
+	return nil
+}
+
+// MutateOptions configures which mutators MutateJob applies, and how.
+type MutateOptions struct {
+	// Disabled are the names of mutators that are not applied.
+	Disabled []string
+
+	// Force are the names of mutators that also change fields which are already set.
+	Force []string
+}
+
+// Change describes a change made to a job by a mutator.
+type Change struct {
+	Mutator     string
+	Description string
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s (%s)", c.Description, c.Mutator)
+}
+
+// MutateJob applies the mutators that are not disabled to job, and returns the changes made.
+//
+// Besides opts, mutators can be disabled and forced by the DisableMutatorsAnnotation and ForceMutatorsAnnotation
+// of the job. A mutator only changes fields which are not already set, unless it is forced; fields set explicitly
+// in a full job specification are thereby left as they are. Disabling a mutator takes precedence over forcing it.
+func MutateJob(job *batchv1.Job, opts MutateOptions) ([]Change, error) {
+	disabled := append(splitNames(job.Annotations[DisableMutatorsAnnotation]), opts.Disabled...)
+	force := append(splitNames(job.Annotations[ForceMutatorsAnnotation]), opts.Force...)
+	if err := ValidateMutatorNames(disabled); err != nil {
+		return nil, err
+	}
+	if err := ValidateMutatorNames(force); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	for _, m := range Mutators {
+		if containsString(disabled, m.Name) {
+			continue
+		}
+
+		for _, description := range m.Mutate(job, containsString(force, m.Name)) {
+			changes = append(changes, Change{Mutator: m.Name, Description: description})
+		}
+	}
+
+	return changes, nil
+}
+
+// mutateZeroResources removes zero quantity limits. Since simplified jobs omit such limits when expanded,
+// zero limits are always set explicitly, and are therefore only removed when forced.
+func mutateZeroResources(job *batchv1.Job, force bool) []string {
+	if !force {
+		return nil
+	}
+
+	var changes []string
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		for _, name := range removeZeroResources(&containers[i]) {
+			changes = append(changes, fmt.Sprintf("removed zero %s limit of container %s", name, containers[i].Name))
+		}
+	}
+
+	return changes
+}
+
+// removeZeroResources removes the zero quantity limits of container, and returns their names in lexicographical order.
+func removeZeroResources(container *corev1.Container) []string {
+	var removed []string
+	limits := container.Resources.Limits
+	for k, v := range limits {
+		if v.IsZero() {
+			delete(limits, k)
+			removed = append(removed, string(k))
+		}
+	}
+	sort.Strings(removed)
+
+	return removed
+}
+
+func mutateTerminationPolicy(job *batchv1.Job, force bool) []string {
+	var changes []string
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		container := &containers[i]
+		if container.TerminationMessagePolicy == defaultTerminationMessagePolicy || (container.TerminationMessagePolicy != "" && !force) {
+			continue
+		}
+
+		container.TerminationMessagePolicy = defaultTerminationMessagePolicy
+		changes = append(changes, fmt.Sprintf("set terminationMessagePolicy of container %s to %s", container.Name, defaultTerminationMessagePolicy))
+	}
+
+	return changes
+}
+
+func mutateBackoffLimit(job *batchv1.Job, force bool) []string {
+	limit := job.Spec.BackoffLimit
+	if limit != nil && (*limit == *defaultBackoffLimit || !force) {
+		return nil
+	}
+
+	value := *defaultBackoffLimit
+	job.Spec.BackoffLimit = &value
+
+	return []string{fmt.Sprintf("set backoffLimit to %d", value)}
+}
+
+func mutateRestartPolicy(job *batchv1.Job, force bool) []string {
+	policy := defaultRestartPolicy
+	if limit := job.Spec.BackoffLimit; limit != nil && *limit > 0 {
+		policy = retryRestartPolicy
+	}
+
+	spec := &job.Spec.Template.Spec
+	if spec.RestartPolicy == policy || (spec.RestartPolicy != "" && !force) {
+		return nil
+	}
+
+	spec.RestartPolicy = policy
+
+	return []string{fmt.Sprintf("set restartPolicy to %s", policy)}
+}
+
+func splitNames(s string) []string {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
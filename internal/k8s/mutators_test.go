@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestMutateJobKeepsExplicitFields(t *testing.T) {
+	job := newJob("foo", newNamedZeroMemoryContainer("bar"))
+	container := &job.Spec.Template.Spec.Containers[0]
+
+	changes, err := MutateJob(&job, MutateOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Change{{
+		Mutator:     "termination-message-policy",
+		Description: "set terminationMessagePolicy of container bar to FallbackToLogsOnError",
+	}}, changes)
+	assert.Len(t, container.Resources.Limits, 2)
+	assert.Equal(t, int32(42), *job.Spec.BackoffLimit)
+	assert.Equal(t, corev1.RestartPolicyAlways, job.Spec.Template.Spec.RestartPolicy)
+}
+
+func TestMutateJobSetsUnsetFields(t *testing.T) {
+	job := newJob("foo", corev1.Container{Name: "foo"})
+	job.Spec.BackoffLimit = nil
+	job.Spec.Template.Spec.RestartPolicy = ""
+
+	changes, err := MutateJob(&job, MutateOptions{})
+	assert.NoError(t, err)
+
+	assert.Len(t, changes, 3)
+	assert.Equal(t, defaultTerminationMessagePolicy, job.Spec.Template.Spec.Containers[0].TerminationMessagePolicy)
+	assert.Equal(t, defaultBackoffLimit, job.Spec.BackoffLimit)
+	assert.Equal(t, defaultRestartPolicy, job.Spec.Template.Spec.RestartPolicy)
+
+	// Applying the mutators again changes nothing.
+	changes, err = MutateJob(&job, MutateOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestMutateJobForce(t *testing.T) {
+	job := newJob("foo", newNamedZeroMemoryContainer("bar"))
+	container := &job.Spec.Template.Spec.Containers[0]
+
+	changes, err := MutateJob(&job, MutateOptions{Force: MutatorNames()})
+	assert.NoError(t, err)
+
+	assert.Len(t, changes, 4)
+	assert.Equal(t, "removed zero memory limit of container bar (remove-zero-resources)", changes[0].String())
+	assert.Len(t, container.Resources.Limits, 1)
+	assert.Equal(t, defaultTerminationMessagePolicy, container.TerminationMessagePolicy)
+	assert.Equal(t, defaultBackoffLimit, job.Spec.BackoffLimit)
+	assert.Equal(t, defaultRestartPolicy, job.Spec.Template.Spec.RestartPolicy)
+}
+
+func TestMutateJobDisabled(t *testing.T) {
+	job := newJob("foo", newZeroMemoryContainer())
+	job.Annotations = map[string]string{
+		DisableMutatorsAnnotation: "termination-message-policy, backoff-limit",
+		ForceMutatorsAnnotation:   "backoff-limit,restart-policy",
+	}
+
+	changes, err := MutateJob(&job, MutateOptions{Disabled: []string{"restart-policy"}})
+	assert.NoError(t, err)
+
+	assert.Empty(t, changes)
+	assert.Equal(t, int32(42), *job.Spec.BackoffLimit)
+	assert.Equal(t, corev1.RestartPolicyAlways, job.Spec.Template.Spec.RestartPolicy)
+}
+
+func TestMutateJobUnknownMutator(t *testing.T) {
+	job := newJob("foo")
+	job.Annotations = map[string]string{DisableMutatorsAnnotation: "foo"}
+
+	_, err := MutateJob(&job, MutateOptions{})
+	assert.EqualError(t, err, `unknown mutator "foo": must be one of [remove-zero-resources termination-message-policy backoff-limit restart-policy]`)
+}
+
+func TestRemoveZeroResources(t *testing.T) {
+	container := newZeroMemoryContainer()
+
+	qty, ok := container.Resources.Limits["memory"]
+	assert.True(t, ok)
+	assert.True(t, qty.IsZero())
+	assert.Len(t, container.Resources.Limits, 2)
+
+	removed := removeZeroResources(&container)
+
+	assert.Equal(t, []string{"memory"}, removed)
+	qty, ok = container.Resources.Limits["memory"]
+	assert.False(t, ok)
+	assert.True(t, qty.IsZero())
+	assert.Len(t, container.Resources.Limits, 1)
+}
+
+func newNamedZeroMemoryContainer(name string) corev1.Container {
+	container := newZeroMemoryContainer()
+	container.Name = name
+
+	return container
+}
+
+func TestMutateJobRetriedRestartPolicy(t *testing.T) {
+	job := newJob("foo")
+	job.Spec.Template.Spec.RestartPolicy = ""
+
+	changes, err := MutateJob(&job, MutateOptions{})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Change{{Mutator: "restart-policy", Description: "set restartPolicy to Never"}}, changes)
+}
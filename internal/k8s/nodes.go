@@ -0,0 +1,51 @@
+package k8s
+
+import (
+	"context"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ListNodes returns all nodes in the cluster.
+func (client *NamespaceClient) ListNodes() ([]corev1.Node, error) {
+	nodes, err := client.Clientset.CoreV1().Nodes().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes.Items, nil
+}
+
+// ListActivePods returns all non-terminated pods in all namespaces.
+func (client *NamespaceClient) ListActivePods() ([]corev1.Pod, error) {
+	listOptions := metav1.ListOptions{
+		FieldSelector: "status.phase!=Succeeded,status.phase!=Failed",
+	}
+	pods, err := client.Clientset.CoreV1().Pods(metav1.NamespaceAll).List(context.TODO(), listOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, nil
+}
+
+// ListResourceQuotas returns the resource quotas in the namespace.
+func (client *NamespaceClient) ListResourceQuotas() ([]corev1.ResourceQuota, error) {
+	quotas, err := client.Clientset.CoreV1().ResourceQuotas(client.Namespace).List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return quotas.Items, nil
+}
+
+// ListNamespaces returns all namespaces in the cluster.
+func (client *NamespaceClient) ListNamespaces() ([]corev1.Namespace, error) {
+	namespaces, err := client.Clientset.CoreV1().Namespaces().List(context.TODO(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return namespaces.Items, nil
+}
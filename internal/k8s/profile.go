@@ -0,0 +1,169 @@
+package k8s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/yaml"
+)
+
+// Profiles holds named, partial simplified job specifications that other specifications can extend.
+//
+// A job specification extends a profile by setting "extends: <profile>", and profiles may in turn extend other profiles.
+// When a specification is merged on top of the profile it extends, the following rules apply:
+//
+//   - Scalar fields (name, image, workingDir, memory, cpu, gpu, nodes, gpusPerNode, retries, ttl, deadline) set in the specification replace those in the profile.
+//     Quantities explicitly set to zero, e.g. "gpu: 0", count as set.
+//   - The command and disableMutators are replaced as a whole when set in the specification.
+//   - Lists of named items (env, volumes) are merged by name. Items in the specification replace profile items
+//     with the same name, and new items are appended after the profile items.
+type Profiles map[string]SimpleJob
+
+// DecodeProfiles decodes profiles from a generic representation, such as a section of a configuration file.
+func DecodeProfiles(raw interface{}) (Profiles, error) {
+	if raw == nil {
+		return Profiles{}, nil
+	}
+
+	b, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid profiles: %w", err)
+	}
+
+	profiles := Profiles{}
+	if err := yaml.UnmarshalStrict(b, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid profiles: %w", err)
+	}
+
+	return profiles, nil
+}
+
+// Resolve returns the result of merging simple on top of the chain of profiles it extends.
+func (profiles Profiles) Resolve(simple *SimpleJob) (*SimpleJob, error) {
+	resolved := *simple
+	var chain []string
+	for resolved.Extends != "" {
+		name := resolved.Extends
+		for _, seen := range chain {
+			if seen == name {
+				return nil, fmt.Errorf("profile cycle detected: %s -> %s", strings.Join(chain, " -> "), name)
+			}
+		}
+		chain = append(chain, name)
+
+		profile, ok := profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+
+		resolved = *resolved.Merge(&profile)
+	}
+
+	return &resolved, nil
+}
+
+// Merge returns a copy of base with the fields set in simple merged on top of it.
+// The Extends field of the result is taken from base, so that profile chains can be followed.
+func (simple *SimpleJob) Merge(base *SimpleJob) *SimpleJob {
+	merged := *base
+	if simple.Name != "" {
+		merged.Name = simple.Name
+	}
+	if simple.Image != "" {
+		merged.Image = simple.Image
+	}
+	if simple.WorkingDir != "" {
+		merged.WorkingDir = simple.WorkingDir
+	}
+	if len(simple.Command) > 0 {
+		merged.Command = simple.Command
+	}
+
+	merged.Memory = mergeQuantity(base.Memory, simple.Memory)
+	merged.CPU = mergeQuantity(base.CPU, simple.CPU)
+	merged.GPU = mergeQuantity(base.GPU, simple.GPU)
+	merged.GPUsPerNode = mergeQuantity(base.GPUsPerNode, simple.GPUsPerNode)
+	if simple.Nodes != 0 {
+		merged.Nodes = simple.Nodes
+	}
+
+	if simple.Retries != nil {
+		merged.Retries = simple.Retries
+	}
+	if simple.RetryOn != nil {
+		merged.RetryOn = simple.RetryOn
+	}
+	if simple.TTL != nil {
+		merged.TTL = simple.TTL
+	}
+	if simple.Deadline != nil {
+		merged.Deadline = simple.Deadline
+	}
+	if simple.DisableMutators != nil {
+		merged.DisableMutators = simple.DisableMutators
+	}
+
+	merged.Env = mergeEnv(base.Env, simple.Env)
+	merged.Volumes = mergeVolumes(base.Volumes, simple.Volumes)
+
+	return &merged
+}
+
+// mergeQuantity returns override if it was explicitly set, and base otherwise.
+func mergeQuantity(base, override resource.Quantity) resource.Quantity {
+	// An unset quantity is the zero value, whereas a parsed "0" has its format set.
+	if override == (resource.Quantity{}) {
+		return base
+	}
+
+	return override
+}
+
+func mergeEnv(base, override []corev1.EnvVar) []corev1.EnvVar {
+	merged := append([]corev1.EnvVar{}, base...)
+	for _, env := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == env.Name {
+				merged[i] = env
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, env)
+		}
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+
+	return merged
+}
+
+func mergeVolumes(base, override []Volume) []Volume {
+	merged := append([]Volume{}, base...)
+	for _, volume := range override {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == volume.Name {
+				merged[i] = volume
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, volume)
+		}
+	}
+
+	if len(merged) == 0 {
+		return nil
+	}
+
+	return merged
+}
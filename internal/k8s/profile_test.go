@@ -0,0 +1,96 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+var testProfiles = Profiles{
+	"base": SimpleJob{
+		Image:   "ubuntu:latest",
+		Memory:  resource.MustParse("8Gi"),
+		Env:     []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "2"}},
+		Volumes: []Volume{{Name: "datasets", MountPath: "/datasets", ReadOnly: true}},
+	},
+	"gpu-large": SimpleJob{
+		Extends: "base",
+		GPU:     resource.MustParse("4"),
+		Memory:  resource.MustParse("64Gi"),
+	},
+	"loop-a": SimpleJob{Extends: "loop-b"},
+	"loop-b": SimpleJob{Extends: "loop-a"},
+}
+
+func TestResolveProfileChain(t *testing.T) {
+	simple := &SimpleJob{
+		Name:    "foo",
+		Extends: "gpu-large",
+		Env:     []corev1.EnvVar{{Name: "B", Value: "3"}, {Name: "C", Value: "4"}},
+	}
+
+	resolved, err := testProfiles.Resolve(simple)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", resolved.Name)
+	assert.Equal(t, "ubuntu:latest", resolved.Image)
+	assert.Equal(t, "", resolved.Extends)
+	assert.True(t, resolved.GPU.Equal(resource.MustParse("4")))
+	assert.True(t, resolved.Memory.Equal(resource.MustParse("64Gi")))
+	assert.Equal(t, []corev1.EnvVar{{Name: "A", Value: "1"}, {Name: "B", Value: "3"}, {Name: "C", Value: "4"}}, resolved.Env)
+	assert.Len(t, resolved.Volumes, 1)
+}
+
+func TestResolveExplicitZeroQuantity(t *testing.T) {
+	simple := &SimpleJob{Extends: "gpu-large", GPU: resource.MustParse("0")}
+
+	resolved, err := testProfiles.Resolve(simple)
+	assert.NoError(t, err)
+	assert.True(t, resolved.GPU.IsZero())
+}
+
+func TestResolveUnknownProfile(t *testing.T) {
+	_, err := testProfiles.Resolve(&SimpleJob{Extends: "missing"})
+	assert.EqualError(t, err, `unknown profile "missing"`)
+}
+
+func TestResolveProfileCycle(t *testing.T) {
+	_, err := testProfiles.Resolve(&SimpleJob{Extends: "loop-a"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "cycle")
+}
+
+func TestDecodeProfiles(t *testing.T) {
+	raw := map[string]interface{}{
+		"cpu-debug": map[string]interface{}{
+			"image":      "ubuntu:latest",
+			"workingdir": "/storage",
+			"cpu":        "2",
+			"env":        []interface{}{map[string]interface{}{"name": "DEBUG", "value": "1"}},
+		},
+	}
+
+	profiles, err := DecodeProfiles(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, "/storage", profiles["cpu-debug"].WorkingDir)
+	assert.True(t, profiles["cpu-debug"].CPU.Equal(resource.MustParse("2")))
+
+	_, err = DecodeProfiles(map[string]interface{}{"bad": map[string]interface{}{"imag": "x"}})
+	assert.Error(t, err)
+}
+
+func TestParseWithProfile(t *testing.T) {
+	parser := NewJobParserWithOptions(ParserOptions{Profiles: testProfiles, Profile: "gpu-large"})
+
+	job, err := parser.Parse(strings.NewReader("name: foo\n"), "test")
+	assert.NoError(t, err)
+
+	container := job.Spec.Template.Spec.Containers[0]
+	assert.Equal(t, "ubuntu:latest", container.Image)
+	assert.Len(t, container.VolumeMounts, 2)
+
+	_, err = parser.Parse(strings.NewReader("apiVersion: batch/v1\nkind: Job\n"), "test")
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Labels identifying the run a job belongs to, and which attempt of the run it is.
+// A run is a job along with the jobs created to resume it; see ResumeJob.
+const (
+	RunIDLabel   = "frink/run-id"
+	AttemptLabel = "frink/attempt"
+)
+
+// Environment variables set in the containers of jobs that are part of a run, so that they can resume from checkpoints.
+const (
+	RunIDEnv   = "FRINK_RUN_ID"
+	AttemptEnv = "FRINK_ATTEMPT"
+	ResumeEnv  = "FRINK_RESUME"
+)
+
+// Labels set on jobs and their pods by Kubernetes, which cannot be reused when recreating a job.
+// Kubernetes 1.27 added the prefixed labels, while still setting the legacy ones.
+var generatedLabels = []string{"controller-uid", "job-name", "batch.kubernetes.io/controller-uid", "batch.kubernetes.io/job-name"}
+
+// NewRunID returns a random identifier for a new run.
+func NewRunID() string {
+	return rand.String(10)
+}
+
+// SetRun labels the job and its pods as the first attempt of the run identified by runID,
+// and sets the run environment variables of its containers. An empty runID leaves the job unchanged.
+func SetRun(job *batchv1.Job, runID string) {
+	if runID == "" {
+		return
+	}
+
+	setAttempt(job, runID, 1)
+}
+
+// RunAttempt returns which attempt of its run the job is, where jobs not labeled as part of a run are the first attempt.
+func RunAttempt(job batchv1.Job) int {
+	attempt, err := strconv.Atoi(job.Labels[AttemptLabel])
+	if err != nil || attempt < 1 {
+		return 1
+	}
+
+	return attempt
+}
+
+// RunID returns the identifier of the run the job belongs to. Jobs created before runs were recorded
+// belong to a run identified by their UID, which their resumed attempts are labeled with; see ResumeJob.
+// An empty string is returned if the job has neither.
+func RunID(job batchv1.Job) string {
+	if runID := job.Labels[RunIDLabel]; runID != "" {
+		return runID
+	}
+
+	return LabelValue(string(job.UID))
+}
+
+// RunName returns the name of the first attempt of the run the job belongs to.
+func RunName(job batchv1.Job) string {
+	attempt := RunAttempt(job)
+	if attempt == 1 {
+		return job.Name
+	}
+
+	return strings.TrimSuffix(job.Name, attemptSuffix(attempt))
+}
+
+// ResumeJob returns a new job for the next attempt of the run of job, named "<run>-attempt-<n>".
+// The previous attempt is left as is, so that its logs remain available until it is deleted.
+//
+// The new job has the same specification as job, but its containers have FRINK_RESUME set to 1,
+// and FRINK_ATTEMPT incremented. Jobs created before runs were recorded start a run identified by their UID.
+// Jobs that were failed fast get back the deadline they had before; see EnforceRetryPolicy.
+// An error is returned if the name of the new job would be longer than allowed by Kubernetes.
+func ResumeJob(job *batchv1.Job) (*batchv1.Job, error) {
+	runID := RunID(*job)
+	if runID == "" {
+		runID = NewRunID()
+	}
+	attempt := RunAttempt(*job) + 1
+
+	// Job names are limited to the length of label values, since pods are labeled with the name of their job.
+	name := RunName(*job) + attemptSuffix(attempt)
+	if len(name) > validation.DNS1123LabelMaxLength {
+		return nil, fmt.Errorf("unable to resume job %s: the name of the next attempt, %s, is longer than %d characters", job.Name, name, validation.DNS1123LabelMaxLength)
+	}
+
+	resumed := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        job.Name,
+			Labels:      copyStringMap(job.Labels),
+			Annotations: copyStringMap(job.Annotations),
+		},
+		Spec: *job.Spec.DeepCopy(),
+	}
+
+	// The selector and its labels are generated for each job, unless set manually.
+	if resumed.Spec.ManualSelector == nil || !*resumed.Spec.ManualSelector {
+		resumed.Spec.Selector = nil
+		for _, label := range generatedLabels {
+			delete(resumed.Labels, label)
+			delete(resumed.Spec.Template.Labels, label)
+		}
+	}
+
+	clearFailedFast(resumed)
+	Rename(resumed, name)
+	setAttempt(resumed, runID, attempt)
+	setEnv(resumed, corev1.EnvVar{Name: ResumeEnv, Value: "1"})
+
+	return resumed, nil
+}
+
+func setAttempt(job *batchv1.Job, runID string, attempt int) {
+	labels := map[string]string{
+		RunIDLabel:   runID,
+		AttemptLabel: strconv.Itoa(attempt),
+	}
+
+	for k, v := range labels {
+		if job.Labels == nil {
+			job.Labels = map[string]string{}
+		}
+		job.Labels[k] = v
+
+		if job.Spec.Template.Labels == nil {
+			job.Spec.Template.Labels = map[string]string{}
+		}
+		job.Spec.Template.Labels[k] = v
+	}
+
+	setEnv(job, corev1.EnvVar{Name: RunIDEnv, Value: runID})
+	setEnv(job, corev1.EnvVar{Name: AttemptEnv, Value: strconv.Itoa(attempt)})
+}
+
+// setEnv sets the environment variable in all containers of the job, replacing any existing variable with the same name.
+func setEnv(job *batchv1.Job, env corev1.EnvVar) {
+	containers := job.Spec.Template.Spec.Containers
+	for i := range containers {
+		containers[i].Env = mergeEnv(containers[i].Env, []corev1.EnvVar{env})
+	}
+}
+
+func attemptSuffix(attempt int) string {
+	return fmt.Sprintf("-attempt-%d", attempt)
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	if m == nil {
+		return nil
+	}
+
+	copied := make(map[string]string, len(m))
+	for k, v := range m {
+		copied[k] = v
+	}
+
+	return copied
+}
@@ -0,0 +1,118 @@
+package k8s
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newRunJob() *batchv1.Job {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: "foo"},
+		Spec: batchv1.JobSpec{Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "foo", Env: []corev1.EnvVar{{Name: "LR", Value: "0.1"}}}},
+		}}},
+	}
+	SetRun(job, "abc")
+
+	return job
+}
+
+func TestSetRun(t *testing.T) {
+	job := newRunJob()
+
+	assert.Equal(t, "abc", job.Labels[RunIDLabel])
+	assert.Equal(t, "1", job.Spec.Template.Labels[AttemptLabel])
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "LR", Value: "0.1"},
+		{Name: RunIDEnv, Value: "abc"},
+		{Name: AttemptEnv, Value: "1"},
+	}, job.Spec.Template.Spec.Containers[0].Env)
+
+	unlabeled := &batchv1.Job{}
+	SetRun(unlabeled, "")
+	assert.Nil(t, unlabeled.Labels)
+}
+
+func TestResumeJob(t *testing.T) {
+	job := newRunJob()
+	// Simulate the fields set by Kubernetes.
+	job.UID = "1234"
+	job.Labels["controller-uid"] = "1234"
+	job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"controller-uid": "1234"}}
+	job.Spec.Template.Labels["job-name"] = "foo"
+	job.Status.Failed = 1
+
+	resumed, err := ResumeJob(job)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-attempt-2", resumed.Name)
+	assert.Equal(t, 2, RunAttempt(*resumed))
+	assert.Equal(t, "foo", RunName(*resumed))
+	assert.Equal(t, map[string]string{RunIDLabel: "abc", AttemptLabel: "2"}, resumed.Labels)
+	assert.Equal(t, map[string]string{RunIDLabel: "abc", AttemptLabel: "2"}, resumed.Spec.Template.Labels)
+	assert.Nil(t, resumed.Spec.Selector)
+	assert.Empty(t, resumed.UID)
+	assert.Equal(t, batchv1.JobStatus{}, resumed.Status)
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "LR", Value: "0.1"},
+		{Name: RunIDEnv, Value: "abc"},
+		{Name: AttemptEnv, Value: "2"},
+		{Name: ResumeEnv, Value: "1"},
+	}, resumed.Spec.Template.Spec.Containers[0].Env)
+
+	// The previous attempt is left unchanged.
+	assert.Equal(t, "1", job.Labels[AttemptLabel])
+	assert.Len(t, job.Spec.Template.Spec.Containers[0].Env, 3)
+
+	third, err := ResumeJob(resumed)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-attempt-3", third.Name)
+}
+
+func TestResumeJobPrefixedLabels(t *testing.T) {
+	job := newRunJob()
+	// Simulate the fields set by Kubernetes 1.27 and later.
+	job.UID = "1234"
+	generated := map[string]string{
+		"controller-uid":                     "1234",
+		"job-name":                           "foo",
+		"batch.kubernetes.io/controller-uid": "1234",
+		"batch.kubernetes.io/job-name":       "foo",
+	}
+	for k, v := range generated {
+		job.Labels[k] = v
+		job.Spec.Template.Labels[k] = v
+	}
+	job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"batch.kubernetes.io/controller-uid": "1234"}}
+
+	resumed, err := ResumeJob(job)
+	assert.NoError(t, err)
+	assert.Nil(t, resumed.Spec.Selector)
+	assert.Equal(t, map[string]string{RunIDLabel: "abc", AttemptLabel: "2"}, resumed.Labels)
+	assert.Equal(t, map[string]string{RunIDLabel: "abc", AttemptLabel: "2"}, resumed.Spec.Template.Labels)
+}
+
+func TestResumeJobWithoutRun(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo", UID: "1234"}}
+
+	resumed, err := ResumeJob(job)
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-attempt-2", resumed.Name)
+	assert.Equal(t, "1234", resumed.Labels[RunIDLabel])
+	assert.Equal(t, RunID(*job), RunID(*resumed))
+}
+
+func TestResumeJobNameTooLong(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 53), UID: "1234"}}
+
+	resumed, err := ResumeJob(job)
+	assert.NoError(t, err)
+	assert.Len(t, resumed.Name, 63)
+
+	_, err = ResumeJob(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: strings.Repeat("a", 54), UID: "1234"}})
+	assert.EqualError(t, err, "unable to resume job "+strings.Repeat("a", 54)+": the name of the next attempt, "+strings.Repeat("a", 54)+"-attempt-2, is longer than 63 characters")
+}
@@ -0,0 +1,215 @@
+package k8s
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Annotations recording the retry policy of a job; see RetryPolicy.
+const (
+	RetryOnExitCodesAnnotation   = "frink/retry-on-exit-codes"
+	RetryOnDisruptionsAnnotation = "frink/retry-on-disruptions"
+)
+
+// Annotations set on jobs that were failed fast by EnforceRetryPolicy: why the job was failed,
+// and its previous deadline in seconds, which is empty if it had none.
+const (
+	FailedFastAnnotation = "frink/failed-fast"
+	DeadlineAnnotation   = "frink/deadline"
+)
+
+// disruptionTarget is the pod condition set by Kubernetes 1.26 and later on pods that are about to be terminated due to
+// a disruption, such as preemption, eviction or a node shutdown.
+const disruptionTarget corev1.PodConditionType = "DisruptionTarget"
+
+// disruptionReasons are the reasons of pods that failed due to a disruption on clusters that do not set DisruptionTarget.
+var disruptionReasons = []string{"Evicted", "Preempting", "NodeLost", "Shutdown", "Terminated"}
+
+// RetryPolicy restricts which failures of a job are retried, following the podFailurePolicy of Kubernetes 1.26,
+// which the Kubernetes client frink is built with predates. Pods that failed with one of the ExitCodes,
+// or due to a disruption if Disruptions is set, are retried, while other failures fail the job right away.
+// Retried failures count towards the backoff limit. An empty policy retries all failures.
+//
+// The policy is recorded in annotations of the job, and enforced by frink while it waits for the job to finish;
+// see EnforceRetryPolicy.
+type RetryPolicy struct {
+	ExitCodes   []int32 `json:"exitCodes,omitempty"`
+	Disruptions bool    `json:"disruptions,omitempty"`
+}
+
+// IsZero returns whether the policy is empty, retrying all failures.
+func (policy RetryPolicy) IsZero() bool {
+	return len(policy.ExitCodes) == 0 && !policy.Disruptions
+}
+
+// Validate returns an error if the policy is invalid. As with podFailurePolicy, exit code 0 cannot be retried.
+func (policy RetryPolicy) Validate() error {
+	for _, code := range policy.ExitCodes {
+		if code == 0 {
+			return fmt.Errorf("invalid retryOn: exit code 0 cannot be retried")
+		}
+	}
+
+	return nil
+}
+
+// Retries returns whether the failed pod is retried according to the policy.
+func (policy RetryPolicy) Retries(pod corev1.Pod) bool {
+	if policy.IsZero() || policy.Disruptions && podDisrupted(pod) {
+		return true
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		terminated := status.State.Terminated
+		if terminated == nil || terminated.ExitCode == 0 {
+			continue
+		}
+
+		for _, code := range policy.ExitCodes {
+			if terminated.ExitCode == code {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+func podDisrupted(pod corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == disruptionTarget && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	for _, reason := range disruptionReasons {
+		if pod.Status.Reason == reason {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetRetryPolicy records the policy in annotations of the job, replacing any previous policy.
+func SetRetryPolicy(job *batchv1.Job, policy RetryPolicy) {
+	delete(job.Annotations, RetryOnExitCodesAnnotation)
+	delete(job.Annotations, RetryOnDisruptionsAnnotation)
+	if policy.IsZero() {
+		return
+	}
+
+	if job.Annotations == nil {
+		job.Annotations = map[string]string{}
+	}
+	if len(policy.ExitCodes) > 0 {
+		codes := make([]string, len(policy.ExitCodes))
+		for i, code := range policy.ExitCodes {
+			codes[i] = strconv.Itoa(int(code))
+		}
+		job.Annotations[RetryOnExitCodesAnnotation] = strings.Join(codes, ",")
+	}
+	if policy.Disruptions {
+		job.Annotations[RetryOnDisruptionsAnnotation] = "true"
+	}
+}
+
+// JobRetryPolicy returns the retry policy recorded in the annotations of the job, which is empty if it has none.
+func JobRetryPolicy(job batchv1.Job) (RetryPolicy, error) {
+	policy := RetryPolicy{}
+	if value := job.Annotations[RetryOnExitCodesAnnotation]; value != "" {
+		for _, s := range strings.Split(value, ",") {
+			code, err := strconv.ParseInt(strings.TrimSpace(s), 10, 32)
+			if err != nil {
+				return RetryPolicy{}, fmt.Errorf("invalid %s annotation: %q is not an exit code", RetryOnExitCodesAnnotation, s)
+			}
+			policy.ExitCodes = append(policy.ExitCodes, int32(code))
+		}
+	}
+
+	if value := job.Annotations[RetryOnDisruptionsAnnotation]; value != "" {
+		disruptions, err := strconv.ParseBool(value)
+		if err != nil {
+			return RetryPolicy{}, fmt.Errorf("invalid %s annotation: %w", RetryOnDisruptionsAnnotation, err)
+		}
+		policy.Disruptions = disruptions
+	}
+
+	if err := policy.Validate(); err != nil {
+		return RetryPolicy{}, err
+	}
+
+	return policy, nil
+}
+
+// EnforceRetryPolicy fails the job right away if one of its pods failed in a way that its retry policy does not retry,
+// rather than letting Kubernetes retry the pod until the backoff limit is reached. It returns whether the job was failed.
+// Only jobs that are still being retried are considered, since finished jobs have nothing left to fail.
+func EnforceRetryPolicy(client Client, job batchv1.Job) (bool, error) {
+	if job.Status.Failed == 0 || JobStatus(job) != StatusActive || job.Annotations[FailedFastAnnotation] != "" {
+		return false, nil
+	}
+
+	policy, err := JobRetryPolicy(job)
+	if err != nil || policy.IsZero() {
+		return false, err
+	}
+
+	pods, err := client.ListJobPods(job.Name)
+	if err != nil {
+		return false, fmt.Errorf("unable to list pods of job %s: %w", job.Name, err)
+	}
+
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodFailed || policy.Retries(pod) {
+			continue
+		}
+
+		if err := client.FailJob(job.Name, podFailure(pod)+", which is not retried"); err != nil {
+			return false, fmt.Errorf("unable to fail job %s: %w", job.Name, err)
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// podFailure describes how the failed pod failed, such as "pod train-x7k2p exited with code 1".
+func podFailure(pod corev1.Pod) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return fmt.Sprintf("pod %s exited with code %d", pod.Name, terminated.ExitCode)
+		}
+	}
+
+	if reason := PodFailureReason(pod); reason != "" {
+		return fmt.Sprintf("pod %s failed (%s)", pod.Name, reason)
+	}
+
+	return fmt.Sprintf("pod %s failed", pod.Name)
+}
+
+// JobFailedFast returns why the job was failed fast by EnforceRetryPolicy, or "" if it was not.
+func JobFailedFast(job batchv1.Job) string {
+	return job.Annotations[FailedFastAnnotation]
+}
+
+// clearFailedFast restores the deadline the job had before it was failed fast, and removes the annotations recording it.
+func clearFailedFast(job *batchv1.Job) {
+	if _, ok := job.Annotations[FailedFastAnnotation]; !ok {
+		return
+	}
+
+	job.Spec.ActiveDeadlineSeconds = nil
+	if seconds, err := strconv.ParseInt(job.Annotations[DeadlineAnnotation], 10, 64); err == nil {
+		job.Spec.ActiveDeadlineSeconds = &seconds
+	}
+
+	delete(job.Annotations, FailedFastAnnotation)
+	delete(job.Annotations, DeadlineAnnotation)
+}
@@ -0,0 +1,101 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/util"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newFailedPod(name string, exitCode int32) *corev1.Pod {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"job-name": "foo"}}}
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: "Error"}},
+	}}
+
+	return pod
+}
+
+func TestRetryPolicyRetries(t *testing.T) {
+	policy := RetryPolicy{ExitCodes: []int32{137}}
+	assert.True(t, policy.Retries(*newFailedPod("a", 137)))
+	assert.False(t, policy.Retries(*newFailedPod("b", 1)))
+
+	evicted := newFailedPod("c", 1)
+	evicted.Status.Reason = "Evicted"
+	assert.False(t, policy.Retries(*evicted))
+
+	preempted := newFailedPod("d", 1)
+	preempted.Status.Conditions = []corev1.PodCondition{{Type: "DisruptionTarget", Status: corev1.ConditionTrue}}
+	assert.False(t, policy.Retries(*preempted))
+
+	policy.Disruptions = true
+	assert.True(t, policy.Retries(*evicted))
+	assert.True(t, policy.Retries(*preempted))
+
+	assert.True(t, RetryPolicy{}.Retries(*newFailedPod("e", 1)))
+}
+
+func TestJobRetryPolicy(t *testing.T) {
+	job := &batchv1.Job{}
+	policy := RetryPolicy{ExitCodes: []int32{137, 143}, Disruptions: true}
+	SetRetryPolicy(job, policy)
+	assert.Equal(t, "137,143", job.Annotations[RetryOnExitCodesAnnotation])
+	assert.Equal(t, "true", job.Annotations[RetryOnDisruptionsAnnotation])
+
+	parsed, err := JobRetryPolicy(*job)
+	assert.NoError(t, err)
+	assert.Equal(t, policy, parsed)
+
+	job.Annotations[RetryOnExitCodesAnnotation] = "137,oom"
+	_, err = JobRetryPolicy(*job)
+	assert.EqualError(t, err, `invalid frink/retry-on-exit-codes annotation: "oom" is not an exit code`)
+
+	job.Annotations[RetryOnExitCodesAnnotation] = "0"
+	_, err = JobRetryPolicy(*job)
+	assert.EqualError(t, err, "invalid retryOn: exit code 0 cannot be retried")
+}
+
+func TestEnforceRetryPolicy(t *testing.T) {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "foo"}}
+	job.Spec.BackoffLimit = util.Int32Ptr(3)
+	job.Spec.ActiveDeadlineSeconds = util.Int64Ptr(3600)
+	job.Spec.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "foo"}}
+	job.Status.Failed = 1
+	SetRetryPolicy(job, RetryPolicy{ExitCodes: []int32{137}})
+
+	clientset := fake.NewSimpleClientset(job, newFailedPod("foo-a", 137))
+	client := &NamespaceClient{Clientset: clientset}
+
+	// The failure is retried, so the job is left as is.
+	failed, err := EnforceRetryPolicy(client, *job)
+	assert.NoError(t, err)
+	assert.False(t, failed)
+
+	_, err = clientset.CoreV1().Pods("").Create(context.TODO(), newFailedPod("foo-b", 1), metav1.CreateOptions{})
+	assert.NoError(t, err)
+	job.Status.Failed = 2
+
+	failed, err = EnforceRetryPolicy(client, *job)
+	assert.NoError(t, err)
+	assert.True(t, failed)
+
+	updated, err := clientset.BatchV1().Jobs("").Get(context.TODO(), "foo", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, util.Int64Ptr(1), updated.Spec.ActiveDeadlineSeconds)
+	assert.Equal(t, "pod foo-b exited with code 1, which is not retried", JobFailedFast(*updated))
+
+	// Resuming the job restores its deadline.
+	resumed, err := ResumeJob(updated)
+	assert.NoError(t, err)
+	assert.Equal(t, util.Int64Ptr(3600), resumed.Spec.ActiveDeadlineSeconds)
+	assert.Empty(t, JobFailedFast(*resumed))
+	assert.NotContains(t, resumed.Annotations, DeadlineAnnotation)
+	assert.Equal(t, "137", resumed.Annotations[RetryOnExitCodesAnnotation])
+}
@@ -0,0 +1,47 @@
+package k8s
+
+import (
+	"context"
+
+	"github.com/uitml/frink/internal/k8s/retry"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CreateService creates the service. An existing service with the same name, e.g. of a job that was replaced,
+// is updated to have the labels, selector, ports and owner of service, so that it is not garbage collected
+// along with its previous owner.
+func (client *NamespaceClient) CreateService(service *corev1.Service) error {
+	services := client.Clientset.CoreV1().Services(client.Namespace)
+	_, err := services.Create(context.TODO(), service, metav1.CreateOptions{})
+	if !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return retry.OnConflict(retry.DefaultRetry, func() error {
+		existing, err := services.Get(context.TODO(), service.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		existing.Labels = service.Labels
+		existing.OwnerReferences = service.OwnerReferences
+		existing.Spec.Selector = service.Spec.Selector
+		existing.Spec.Ports = service.Spec.Ports
+		existing.Spec.PublishNotReadyAddresses = service.Spec.PublishNotReadyAddresses
+
+		_, err = services.Update(context.TODO(), existing, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// DeleteService deletes the service with the given name. Deleting a service that does not exist is not an error.
+func (client *NamespaceClient) DeleteService(name string) error {
+	err := client.Clientset.CoreV1().Services(client.Namespace).Delete(context.TODO(), name, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,30 @@
+package k8s
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCreateServiceReplacedJob(t *testing.T) {
+	previous := newDistributedJob()
+	previous.UID = "1234"
+	clientset := fake.NewSimpleClientset(HeadlessService(*previous))
+	client := NamespaceClient{Clientset: clientset}
+
+	// The job is replaced by a job with the same name, but a different UID.
+	job := newDistributedJob()
+	job.UID = "5678"
+	err := client.CreateService(HeadlessService(*job))
+	assert.NoError(t, err)
+
+	service, err := clientset.CoreV1().Services("").Get(context.TODO(), "foo", v1.GetOptions{})
+	assert.NoError(t, err)
+	if assert.Len(t, service.OwnerReferences, 1) {
+		assert.Equal(t, job.UID, service.OwnerReferences[0].UID)
+	}
+	assert.Equal(t, map[string]string{"job-name": "foo"}, service.Spec.Selector)
+}
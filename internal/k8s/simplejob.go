@@ -1,6 +1,8 @@
 package k8s
 
 import (
+	"strings"
+
 	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -17,6 +19,41 @@ type SimpleJob struct {
 	Memory resource.Quantity `json:"memory,omitempty"`
 	CPU    resource.Quantity `json:"cpu,omitempty"`
 	GPU    resource.Quantity `json:"gpu,omitempty"`
+
+	// Nodes is the number of nodes a distributed job runs on, with one pod per node; see SetDistributed.
+	Nodes int32 `json:"nodes,omitempty"`
+
+	// GPUsPerNode is the number of GPUs of each pod of a distributed job, taking precedence over GPU.
+	GPUsPerNode resource.Quantity `json:"gpusPerNode,omitempty"`
+
+	Env     []corev1.EnvVar `json:"env,omitempty"`
+	Volumes []Volume        `json:"volumes,omitempty"`
+
+	// TTL is how long the job is kept after it has finished, before it is deleted automatically.
+	TTL *Duration `json:"ttl,omitempty"`
+
+	// Deadline is how long the job may run before it is terminated.
+	Deadline *Duration `json:"deadline,omitempty"`
+
+	// Retries is how many times failed pods are replaced before the job is considered failed.
+	Retries *int32 `json:"retries,omitempty"`
+
+	// RetryOn restricts which failures are retried; see RetryPolicy.
+	RetryOn *RetryPolicy `json:"retryOn,omitempty"`
+
+	// DisableMutators are the names of mutators not applied to the job; see MutateJob.
+	DisableMutators []string `json:"disableMutators,omitempty"`
+
+	// Extends is the name of the profile this job specification is based on.
+	Extends string `json:"extends,omitempty"`
+}
+
+// Volume represents a persistent volume claim mounted into the job container.
+type Volume struct {
+	Name      string `json:"name"`
+	ClaimName string `json:"claimName,omitempty"`
+	MountPath string `json:"mountPath"`
+	ReadOnly  bool   `json:"readOnly,omitempty"`
 }
 
 var defaultVolumes = []corev1.Volume{{
@@ -34,21 +71,95 @@ var defaultVolumeMounts = []corev1.VolumeMount{{
 }}
 
 func (simple *SimpleJob) volumes() []corev1.Volume {
-	return defaultVolumes
+	volumes := append([]corev1.Volume{}, defaultVolumes...)
+	for _, v := range simple.Volumes {
+		claimName := v.ClaimName
+		if claimName == "" {
+			claimName = v.Name
+		}
+
+		volume := corev1.Volume{
+			Name: v.Name,
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: claimName,
+					ReadOnly:  v.ReadOnly,
+				},
+			},
+		}
+
+		if i := indexOfVolume(volumes, v.Name); i >= 0 {
+			volumes[i] = volume
+		} else {
+			volumes = append(volumes, volume)
+		}
+	}
+
+	return volumes
 }
 
 func (simple *SimpleJob) volumeMounts() []corev1.VolumeMount {
-	return defaultVolumeMounts
+	mounts := append([]corev1.VolumeMount{}, defaultVolumeMounts...)
+	for _, v := range simple.Volumes {
+		mount := corev1.VolumeMount{
+			Name:      v.Name,
+			MountPath: v.MountPath,
+			ReadOnly:  v.ReadOnly,
+		}
+
+		if i := indexOfVolumeMount(mounts, v.Name); i >= 0 {
+			mounts[i] = mount
+		} else {
+			mounts = append(mounts, mount)
+		}
+	}
+
+	return mounts
+}
+
+func indexOfVolume(volumes []corev1.Volume, name string) int {
+	for i, volume := range volumes {
+		if volume.Name == name {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func indexOfVolumeMount(mounts []corev1.VolumeMount, name string) int {
+	for i, mount := range mounts {
+		if mount.Name == name {
+			return i
+		}
+	}
+
+	return -1
 }
 
 func (simple *SimpleJob) resources() corev1.ResourceRequirements {
-	resources := corev1.ResourceRequirements{Limits: corev1.ResourceList{
+	// Zero quantities, whether unset or explicitly set to override a profile, mean no limit.
+	limits := corev1.ResourceList{}
+	for name, qty := range map[corev1.ResourceName]resource.Quantity{
 		"memory":         simple.Memory,
 		"cpu":            simple.CPU,
-		"nvidia.com/gpu": simple.GPU,
-	}}
+		"nvidia.com/gpu": simple.gpus(),
+	} {
+		if !qty.IsZero() {
+			limits[name] = qty
+		}
+	}
+
+	return corev1.ResourceRequirements{Limits: limits}
+}
+
+// gpus returns the number of GPUs of each pod of the job.
+func (simple *SimpleJob) gpus() resource.Quantity {
+	if !simple.GPUsPerNode.IsZero() {
+		return simple.GPUsPerNode
+	}
 
-	return resources
+	return simple.GPU
 }
 
 func (simple *SimpleJob) containers() []corev1.Container {
@@ -57,6 +168,7 @@ func (simple *SimpleJob) containers() []corev1.Container {
 		Image:        simple.Image,
 		Command:      simple.Command,
 		WorkingDir:   simple.WorkingDir,
+		Env:          simple.Env,
 		VolumeMounts: simple.volumeMounts(),
 		Resources:    simple.resources(),
 
@@ -68,9 +180,14 @@ func (simple *SimpleJob) containers() []corev1.Container {
 }
 
 func (simple *SimpleJob) meta() metav1.ObjectMeta {
-	return metav1.ObjectMeta{
+	meta := metav1.ObjectMeta{
 		Name: simple.Name,
 	}
+	if len(simple.DisableMutators) > 0 {
+		meta.Annotations = map[string]string{DisableMutatorsAnnotation: strings.Join(simple.DisableMutators, ",")}
+	}
+
+	return meta
 }
 
 // Expand expands the simplified job into a full job object.
@@ -85,5 +202,30 @@ func (simple *SimpleJob) Expand() *batchv1.Job {
 		},
 	}
 
+	if simple.Nodes > 1 {
+		gpus := simple.gpus()
+		SetDistributed(job, simple.Nodes, gpus.Value())
+	}
+
+	if simple.Retries != nil {
+		retries := *simple.Retries
+		job.Spec.BackoffLimit = &retries
+	}
+	if simple.RetryOn != nil {
+		SetRetryPolicy(job, *simple.RetryOn)
+	}
+
+	// TTLs too long for Kubernetes are rejected by JobParser, and left unset here.
+	if simple.TTL != nil {
+		if ttl, err := simple.TTL.TTLSeconds(); err == nil {
+			job.Spec.TTLSecondsAfterFinished = ttl
+		}
+	}
+
+	// Kubernetes requires the deadline to be positive, so a zero deadline is treated as none.
+	if simple.Deadline != nil && *simple.Deadline > 0 {
+		job.Spec.ActiveDeadlineSeconds = simple.Deadline.Seconds()
+	}
+
 	return job
 }
@@ -4,6 +4,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
 func TestExpandSetsObjectMeta(t *testing.T) {
@@ -34,6 +36,21 @@ func TestExpandDefinesContainer(t *testing.T) {
 	assert.True(t, container.TTY)
 }
 
+func TestExpandOmitsZeroLimits(t *testing.T) {
+	simple := &SimpleJob{
+		Name:            "foo",
+		Memory:          resource.MustParse("1Gi"),
+		GPU:             resource.MustParse("0"),
+		DisableMutators: []string{"backoff-limit"},
+	}
+
+	job := simple.Expand()
+	limits := job.Spec.Template.Spec.Containers[0].Resources.Limits
+
+	assert.Equal(t, corev1.ResourceList{"memory": resource.MustParse("1Gi")}, limits)
+	assert.Equal(t, "backoff-limit", job.Annotations[DisableMutatorsAnnotation])
+}
+
 func TestExpandDefinesVolumes(t *testing.T) {
 	simple := &SimpleJob{}
 	job := simple.Expand()
@@ -58,3 +75,22 @@ func TestDefaultVolumeCompatibility(t *testing.T) {
 
 	assert.Equal(t, claimNames, mountNames)
 }
+
+func TestExpandMergesVolumesByName(t *testing.T) {
+	simple := &SimpleJob{Volumes: []Volume{
+		{Name: "storage", ClaimName: "scratch", MountPath: "/scratch"},
+		{Name: "datasets", MountPath: "/datasets", ReadOnly: true},
+	}}
+
+	job := simple.Expand()
+	pod := job.Spec.Template.Spec
+	assert.Len(t, pod.Volumes, 2)
+	assert.Equal(t, "scratch", pod.Volumes[0].VolumeSource.PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "datasets", pod.Volumes[1].VolumeSource.PersistentVolumeClaim.ClaimName)
+
+	mounts := pod.Containers[0].VolumeMounts
+	assert.Equal(t, "/scratch", mounts[0].MountPath)
+	assert.True(t, mounts[1].ReadOnly)
+
+	assert.Equal(t, "storage", defaultVolumes[0].VolumeSource.PersistentVolumeClaim.ClaimName, "defaults must not be modified")
+}
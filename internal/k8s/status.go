@@ -0,0 +1,115 @@
+package k8s
+
+import (
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Job statuses, as reported by JobStatus.
+const (
+	StatusActive    = "Active"
+	StatusFailed    = "Failed"
+	StatusSucceeded = "Succeeded"
+	StatusStopped   = "Stopped"
+	StatusSuspended = "Suspended"
+)
+
+// JobStatus returns a simplified status of the job.
+// Jobs that have not yet started any pods, or that have been stopped, are reported as stopped.
+// Jobs with failed pods that are about to be retried, as their backoff limit has not been reached, are reported as active.
+// Unfinished jobs that are suspended are reported as suspended, even while their pods are being terminated.
+func JobStatus(job batchv1.Job) string {
+	switch {
+	case hasCondition(job, batchv1.JobComplete):
+		return StatusSucceeded
+	case hasCondition(job, batchv1.JobFailed):
+		return StatusFailed
+	case job.Spec.Suspend != nil && *job.Spec.Suspend:
+		return StatusSuspended
+	case job.Status.Active > 0:
+		return StatusActive
+	case job.Status.Failed > 0 && job.Status.Failed <= backoffLimit(job):
+		return StatusActive
+	case job.Status.Failed > 0:
+		return StatusFailed
+	case job.Spec.Completions == nil && job.Status.Succeeded > 0 || job.Spec.Completions != nil && *job.Spec.Completions == job.Status.Succeeded:
+		return StatusSucceeded
+	}
+
+	return StatusStopped
+}
+
+// JobFinishTime returns when the job finished, either by completing or by failing, or false if it has not finished.
+func JobFinishTime(job batchv1.Job) (time.Time, bool) {
+	if job.Status.CompletionTime != nil {
+		return job.Status.CompletionTime.Time, true
+	}
+
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// JobAttempts returns the number of pods the job has started, which for jobs with a single completion is the number
+// of the current (or last) attempt, along with the maximum number of attempts allowed by its backoff limit.
+//
+// Containers restarted in place, due to the OnFailure restart policy, are not counted as separate attempts.
+func JobAttempts(job batchv1.Job) (int32, int32) {
+	return job.Status.Active + job.Status.Succeeded + job.Status.Failed, backoffLimit(job) + 1
+}
+
+// backoffLimit returns the number of times failed pods of the job are replaced. Jobs submitted by frink always have
+// a backoff limit, as set by the backoff-limit mutator, and are not retried without one; see MutateJob.
+func backoffLimit(job batchv1.Job) int32 {
+	if job.Spec.BackoffLimit == nil {
+		return *defaultBackoffLimit
+	}
+
+	return *job.Spec.BackoffLimit
+}
+
+// JobFailureReason returns the reason of the failed condition of the job, such as "BackoffLimitExceeded" or
+// "DeadlineExceeded", or "" if the job has not failed.
+func JobFailureReason(job batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobFailed && condition.Status == corev1.ConditionTrue {
+			return condition.Reason
+		}
+	}
+
+	return ""
+}
+
+// PodFailureReason returns why the pod, or one of its containers, last failed, such as "OOMKilled" or "Evicted",
+// or "" if it has not failed. Containers that exited successfully are not considered failed.
+func PodFailureReason(pod corev1.Pod) string {
+	if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason != "" {
+		return pod.Status.Reason
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+			if terminated := state.Terminated; terminated != nil && terminated.ExitCode != 0 && terminated.Reason != "" {
+				return terminated.Reason
+			}
+		}
+	}
+
+	return ""
+}
+
+func hasCondition(job batchv1.Job, conditionType batchv1.JobConditionType) bool {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == conditionType && condition.Status == corev1.ConditionTrue {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/util"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestJobStatusRetrying(t *testing.T) {
+	job := batchv1.Job{
+		Spec:   batchv1.JobSpec{BackoffLimit: util.Int32Ptr(2)},
+		Status: batchv1.JobStatus{Failed: 2},
+	}
+	assert.Equal(t, StatusActive, JobStatus(job))
+
+	job.Status.Failed = 3
+	assert.Equal(t, StatusFailed, JobStatus(job))
+}
+
+func TestJobStatusConditions(t *testing.T) {
+	job := batchv1.Job{
+		Spec: batchv1.JobSpec{BackoffLimit: util.Int32Ptr(2)},
+		Status: batchv1.JobStatus{
+			Failed:     1,
+			Succeeded:  1,
+			Conditions: []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}},
+		},
+	}
+	assert.Equal(t, StatusSucceeded, JobStatus(job))
+
+	job.Status.Succeeded = 0
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	assert.Equal(t, StatusFailed, JobStatus(job))
+}
+
+func TestJobAttempts(t *testing.T) {
+	job := batchv1.Job{
+		Spec:   batchv1.JobSpec{BackoffLimit: util.Int32Ptr(2)},
+		Status: batchv1.JobStatus{Active: 1, Failed: 1},
+	}
+
+	attempt, max := JobAttempts(job)
+	assert.Equal(t, int32(2), attempt)
+	assert.Equal(t, int32(3), max)
+}
+
+func TestJobStatusAndAttemptsWithoutBackoffLimit(t *testing.T) {
+	// Jobs without a backoff limit are treated as not retried, both by their status and by their attempts.
+	job := batchv1.Job{Status: batchv1.JobStatus{Failed: 1}}
+	assert.Equal(t, StatusFailed, JobStatus(job))
+
+	attempt, max := JobAttempts(job)
+	assert.Equal(t, int32(1), attempt)
+	assert.Equal(t, int32(1), max)
+}
+
+func TestJobStatusSuspended(t *testing.T) {
+	suspend := true
+	job := batchv1.Job{
+		Spec:   batchv1.JobSpec{Suspend: &suspend},
+		Status: batchv1.JobStatus{Active: 1},
+	}
+	assert.Equal(t, StatusSuspended, JobStatus(job))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Equal(t, StatusSucceeded, JobStatus(job))
+}
+
+func TestJobStatusSuspendedRetrying(t *testing.T) {
+	// A suspended job with retries left is suspended rather than active, since no pods are replaced until it is resumed.
+	suspend := true
+	job := batchv1.Job{
+		Spec:   batchv1.JobSpec{Suspend: &suspend, BackoffLimit: util.Int32Ptr(2)},
+		Status: batchv1.JobStatus{Failed: 1},
+	}
+	assert.Equal(t, StatusSuspended, JobStatus(job))
+
+	job.Status.Failed = 3
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue}}
+	assert.Equal(t, StatusFailed, JobStatus(job))
+}
+
+func TestPodFailureReason(t *testing.T) {
+	terminated := func(exitCode int32, reason string) corev1.ContainerState {
+		return corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode, Reason: reason}}
+	}
+
+	pod := corev1.Pod{}
+	assert.Equal(t, "", PodFailureReason(pod))
+
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{State: terminated(0, "Completed")}}
+	assert.Equal(t, "", PodFailureReason(pod))
+
+	// Containers restarted in place keep the reason they failed in their last termination state.
+	pod.Status.ContainerStatuses = []corev1.ContainerStatus{{LastTerminationState: terminated(137, "OOMKilled")}}
+	assert.Equal(t, "OOMKilled", PodFailureReason(pod))
+
+	pod.Status.Phase = corev1.PodFailed
+	pod.Status.Reason = "Evicted"
+	assert.Equal(t, "Evicted", PodFailureReason(pod))
+}
+
+func TestJobFailureReason(t *testing.T) {
+	job := batchv1.Job{}
+	assert.Equal(t, "", JobFailureReason(job))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Reason: "DeadlineExceeded"}}
+	assert.Equal(t, "DeadlineExceeded", JobFailureReason(job))
+}
@@ -0,0 +1,248 @@
+package k8s
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/rand"
+)
+
+// Expander transforms a raw job specification before it is decoded.
+type Expander interface {
+	Expand(b []byte) ([]byte, error)
+}
+
+// ExpanderFunc is an adapter that allows the use of ordinary functions as an Expander.
+type ExpanderFunc func(b []byte) ([]byte, error)
+
+// Expand calls fn(b).
+func (fn ExpanderFunc) Expand(b []byte) ([]byte, error) {
+	return fn(b)
+}
+
+// Variables resolves the values used when expanding job specifications.
+//
+// Values are looked up in the following order: explicitly set values, and built-in values.
+// The environment is deliberately not consulted, neither by ${VAR} references nor by templates, since references
+// such as ${HOME} are usually meant for the container, and specifications would otherwise depend on the shell
+// they were submitted from. Values from the environment can be passed explicitly, e.g. --set home=$HOME.
+type Variables struct {
+	// Set holds explicitly set values, typically specified via command-line flags.
+	Set map[string]string
+
+	// Builtins holds lazily evaluated built-in values, such as USER and GIT_SHA.
+	Builtins map[string]func() (string, error)
+
+	cache map[string]string
+}
+
+// NewVariables returns Variables consisting of the specified values and the default built-ins.
+//
+// The specified values may themselves reference built-in variables, e.g. name=exp-${DATE}.
+func NewVariables(set map[string]string) (*Variables, error) {
+	vars := &Variables{
+		Builtins: DefaultBuiltins(),
+	}
+
+	expanded := map[string]string{}
+	for key, value := range set {
+		value, err := vars.ExpandString(value)
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand %s: %w", key, err)
+		}
+		expanded[key] = value
+	}
+	vars.Set = expanded
+
+	return vars, nil
+}
+
+// DefaultBuiltins returns the default set of built-in variables.
+//
+// The values are evaluated at most once per Variables, so e.g. RANDOM_SUFFIX is stable within a single job specification.
+func DefaultBuiltins() map[string]func() (string, error) {
+	return map[string]func() (string, error){
+		"USER":          CurrentUser,
+		"DATE":          func() (string, error) { return time.Now().Format("2006-01-02"), nil },
+		"GIT_SHA":       gitSHA,
+		"RANDOM_SUFFIX": func() (string, error) { return rand.String(5), nil },
+	}
+}
+
+// Lookup returns the value of the variable with the given key, and whether it was found.
+func (v *Variables) Lookup(key string) (string, bool, error) {
+	if value, ok := v.Set[key]; ok {
+		return value, true, nil
+	}
+
+	if value, ok := v.cache[key]; ok {
+		return value, true, nil
+	}
+
+	if fn, ok := v.Builtins[key]; ok {
+		value, err := fn()
+		if err != nil {
+			return "", false, fmt.Errorf("unable to evaluate built-in variable %s: %w", key, err)
+		}
+
+		if v.cache == nil {
+			v.cache = map[string]string{}
+		}
+		v.cache[key] = value
+
+		return value, true, nil
+	}
+
+	return "", false, nil
+}
+
+// Map returns all explicitly set and built-in variables, with the built-ins evaluated.
+//
+// Built-ins that cannot be evaluated, e.g. GIT_SHA outside of a git repository, are omitted.
+// Environment variables are not included; see Variables.
+func (v *Variables) Map() map[string]string {
+	vars := map[string]string{}
+	for key := range v.Builtins {
+		if value, ok, err := v.Lookup(key); ok && err == nil {
+			vars[key] = value
+		}
+	}
+
+	for key, value := range v.Set {
+		vars[key] = value
+	}
+
+	return vars
+}
+
+// variablePattern matches "$$" (an escaped dollar sign), as well as "${VAR}" and "${VAR:-default}".
+var variablePattern = regexp.MustCompile(`\$\$|\$\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?\}`)
+
+// ExpandString replaces all occurrences of ${VAR} and ${VAR:-default} in s.
+//
+// Unknown variables without a default are left untouched, so that they can be expanded by the container shell.
+// Use $${VAR} to prevent expansion of a variable that is otherwise defined.
+func (v *Variables) ExpandString(s string) (string, error) {
+	var errs []string
+	expanded := variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		if match == "$$" {
+			return "$"
+		}
+
+		groups := variablePattern.FindStringSubmatch(match)
+		key, fallback := groups[1], groups[2]
+		value, ok, err := v.Lookup(key)
+		if err != nil {
+			errs = append(errs, err.Error())
+			return match
+		}
+
+		switch {
+		case ok:
+			return value
+		case strings.Contains(match, ":-"):
+			return fallback
+		}
+
+		return match
+	})
+
+	if len(errs) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	return expanded, nil
+}
+
+// VariableExpander returns an Expander that replaces ${VAR} references using vars.
+func VariableExpander(vars *Variables) Expander {
+	return ExpanderFunc(func(b []byte) ([]byte, error) {
+		s, err := vars.ExpandString(string(b))
+		if err != nil {
+			return nil, err
+		}
+
+		return []byte(s), nil
+	})
+}
+
+// TemplateExpander returns an Expander that renders the job specification as a Go text/template.
+//
+// The template data is the map of explicitly set and built-in variables, e.g. {{ .USER }} or {{ .lr }}.
+// As with ${VAR} references, the environment is not available.
+func TemplateExpander(vars *Variables) Expander {
+	return ExpanderFunc(func(b []byte) ([]byte, error) {
+		data := vars.Map()
+		tmpl, err := template.New("spec").Option("missingkey=error").Funcs(templateFuncs()).Parse(string(b))
+		if err != nil {
+			return nil, err
+		}
+
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, data); err != nil {
+			return nil, err
+		}
+
+		return buf.Bytes(), nil
+	})
+}
+
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"default": func(fallback, value string) string {
+			if value == "" {
+				return fallback
+			}
+			return value
+		},
+		"lower":   strings.ToLower,
+		"upper":   strings.ToUpper,
+		"replace": func(old, new, s string) string { return strings.ReplaceAll(s, old, new) },
+		"trim":    strings.TrimSpace,
+		"quote":   func(s string) string { return fmt.Sprintf("%q", s) },
+	}
+}
+
+// ParseAssignments parses a list of key=value pairs, as typically specified via --set flags.
+func ParseAssignments(assignments []string) (map[string]string, error) {
+	vars := map[string]string{}
+	for _, assignment := range assignments {
+		parts := strings.SplitN(assignment, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid assignment %q: expected key=value", assignment)
+		}
+		vars[parts[0]] = parts[1]
+	}
+
+	return vars, nil
+}
+
+// CurrentUser returns the name of the user running frink.
+func CurrentUser() (string, error) {
+	if name := os.Getenv("USER"); name != "" {
+		return name, nil
+	}
+
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	return u.Username, nil
+}
+
+func gitSHA() (string, error) {
+	out, err := exec.Command("git", "rev-parse", "--short", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
+	}
+
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,112 @@
+package k8s
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestVariables(set map[string]string) *Variables {
+	return &Variables{
+		Set: set,
+		Builtins: map[string]func() (string, error){
+			"USER":    func() (string, error) { return "homer", nil },
+			"GIT_SHA": func() (string, error) { return "", errors.New("not a git repository") },
+		},
+	}
+}
+
+func TestExpandStringLookupOrder(t *testing.T) {
+	vars := newTestVariables(map[string]string{"USER": "marge"})
+
+	out, err := vars.ExpandString("${USER}")
+	assert.NoError(t, err)
+	assert.Equal(t, "marge", out)
+
+	vars.Set = nil
+	out, err = vars.ExpandString("${USER}")
+	assert.NoError(t, err)
+	assert.Equal(t, "homer", out)
+}
+
+func TestExpandStringDefaultsAndEscapes(t *testing.T) {
+	vars := newTestVariables(nil)
+
+	out, err := vars.ExpandString("${lr:-1e-3} ${UNKNOWN} $${USER} $PATH")
+	assert.NoError(t, err)
+	assert.Equal(t, "1e-3 ${UNKNOWN} ${USER} $PATH", out)
+}
+
+func TestExpandStringIgnoresEnvironment(t *testing.T) {
+	vars, err := NewVariables(nil)
+	assert.NoError(t, err)
+
+	// HOME is always set in the environment, but is meant to be expanded by the container shell.
+	out, err := vars.ExpandString("echo ${HOME}")
+	assert.NoError(t, err)
+	assert.Equal(t, "echo ${HOME}", out)
+}
+
+func TestExpandStringBuiltinError(t *testing.T) {
+	vars := newTestVariables(nil)
+
+	_, err := vars.ExpandString("${GIT_SHA}")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "GIT_SHA")
+}
+
+func TestBuiltinsEvaluatedOnce(t *testing.T) {
+	vars, err := NewVariables(nil)
+	assert.NoError(t, err)
+
+	out, err := vars.ExpandString("${RANDOM_SUFFIX}-${RANDOM_SUFFIX}")
+	assert.NoError(t, err)
+
+	parts := strings.Split(out, "-")
+	assert.Len(t, parts[0], 5)
+	assert.Equal(t, parts[0], parts[1])
+}
+
+func TestNewVariablesExpandsSetValues(t *testing.T) {
+	vars, err := NewVariables(map[string]string{"name": "exp-${RANDOM_SUFFIX}"})
+	assert.NoError(t, err)
+
+	suffix, _, _ := vars.Lookup("RANDOM_SUFFIX")
+	assert.Equal(t, "exp-"+suffix, vars.Set["name"])
+}
+
+func TestTemplateExpander(t *testing.T) {
+	vars := newTestVariables(map[string]string{"lr": "1e-4"})
+	expander := TemplateExpander(vars)
+
+	out, err := expander.Expand([]byte(`{{ .USER | upper }} {{ .lr }} {{ index . "epochs" | default "10" }}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "HOMER 1e-4 10", string(out))
+
+	out, err = expander.Expand([]byte(`{{ .epochs }}`))
+	assert.Error(t, err, "missing keys should be reported")
+	assert.Nil(t, out)
+
+	_, err = expander.Expand([]byte(`{{ env "HOME" }}`))
+	assert.Error(t, err, "the environment should not be available")
+}
+
+func TestParseAssignments(t *testing.T) {
+	vars, err := ParseAssignments([]string{"lr=1e-4", "name=exp=1", "empty="})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"lr": "1e-4", "name": "exp=1", "empty": ""}, vars)
+
+	_, err = ParseAssignments([]string{"lr"})
+	assert.EqualError(t, err, `invalid assignment "lr": expected key=value`)
+}
+
+func TestParseWithVariableExpander(t *testing.T) {
+	vars := newTestVariables(map[string]string{"name": "foo"})
+	parser := NewJobParser(VariableExpander(vars))
+
+	job, err := parser.Parse(strings.NewReader("name: ${name}-${USER}\nimage: ubuntu:latest\n"), "test")
+	assert.NoError(t, err)
+	assert.Equal(t, "foo-homer", job.Name)
+}
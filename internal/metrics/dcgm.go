@@ -0,0 +1,225 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Metrics exported by the NVIDIA DCGM exporter.
+const (
+	dcgmUtilization = "DCGM_FI_DEV_GPU_UTIL" // percent
+	dcgmMemoryUsed  = "DCGM_FI_DEV_FB_USED"  // MiB
+	dcgmMemoryFree  = "DCGM_FI_DEV_FB_FREE"  // MiB
+)
+
+var dcgmMetrics = []string{dcgmUtilization, dcgmMemoryUsed, dcgmMemoryFree}
+
+// sample is a single sample of a metric.
+type sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// Prometheus is a GPUSource querying DCGM exporter metrics stored in Prometheus.
+type Prometheus struct {
+	// URL is the URL of the Prometheus server, e.g. http://prometheus:9090.
+	URL string
+
+	HTTPClient *http.Client
+}
+
+// NewPrometheus returns a Prometheus source for the server at url.
+func NewPrometheus(url string, timeout time.Duration) *Prometheus {
+	return &Prometheus{URL: url, HTTPClient: &http.Client{Timeout: timeout}}
+}
+
+// prometheusResponse is the subset of the response of the Prometheus instant query API used by frink.
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Metric map[string]string `json:"metric"`
+			Value  []interface{}     `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// GPUUsage queries the DCGM metrics of the pods.
+func (p *Prometheus) GPUUsage(ctx context.Context, namespace string, pods []string) (map[string][]GPUUsage, error) {
+	quoted := make([]string, 0, len(pods))
+	for _, pod := range pods {
+		quoted = append(quoted, regexp.QuoteMeta(pod))
+	}
+	selector := fmt.Sprintf(`{namespace=%q,pod=~%q}`, namespace, strings.Join(quoted, "|"))
+
+	samples := map[string][]sample{}
+	for _, metric := range dcgmMetrics {
+		s, err := p.query(ctx, metric+selector)
+		if err != nil {
+			return nil, err
+		}
+		samples[metric] = s
+	}
+
+	return gpuUsage(samples, namespace, pods), nil
+}
+
+func (p *Prometheus) query(ctx context.Context, query string) ([]sample, error) {
+	u := strings.TrimRight(p.URL, "/") + "/api/v1/query?" + url.Values{"query": {query}}.Encode()
+	b, err := get(ctx, p.HTTPClient, u)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := prometheusResponse{}
+	if err := json.Unmarshal(b, &resp); err != nil {
+		return nil, fmt.Errorf("invalid prometheus response: %w", err)
+	}
+	if resp.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s", resp.Error)
+	}
+
+	var samples []sample
+	for _, result := range resp.Data.Result {
+		if len(result.Value) != 2 {
+			continue
+		}
+		s, _ := result.Value[1].(string)
+		value, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			continue
+		}
+		samples = append(samples, sample{Labels: result.Metric, Value: value})
+	}
+
+	return samples, nil
+}
+
+// DCGMExporter is a GPUSource scraping the metrics endpoint of a DCGM exporter directly,
+// e.g. http://dcgm-exporter:9400/metrics. This is mostly useful for single node clusters,
+// since each exporter only reports the GPUs of the node it runs on.
+type DCGMExporter struct {
+	URL string
+
+	HTTPClient *http.Client
+}
+
+// NewDCGMExporter returns a DCGMExporter source for the metrics endpoint at url.
+func NewDCGMExporter(url string, timeout time.Duration) *DCGMExporter {
+	return &DCGMExporter{URL: url, HTTPClient: &http.Client{Timeout: timeout}}
+}
+
+// GPUUsage scrapes the exporter, and returns the usage of the GPUs allocated to the pods.
+func (e *DCGMExporter) GPUUsage(ctx context.Context, namespace string, pods []string) (map[string][]GPUUsage, error) {
+	b, err := get(ctx, e.HTTPClient, e.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	samples, err := parseExposition(b, dcgmMetrics)
+	if err != nil {
+		return nil, err
+	}
+
+	return gpuUsage(samples, namespace, pods), nil
+}
+
+var exposition = regexp.MustCompile(`^([a-zA-Z_:][a-zA-Z0-9_:]*)(?:\{(.*)\})?\s+(\S+)`)
+var expositionLabel = regexp.MustCompile(`([a-zA-Z_][a-zA-Z0-9_]*)="((?:[^"\\]|\\.)*)"`)
+
+// parseExposition parses the samples of the given metrics from the Prometheus text exposition format.
+func parseExposition(b []byte, metrics []string) (map[string][]sample, error) {
+	samples := map[string][]sample{}
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		m := exposition.FindStringSubmatch(line)
+		if m == nil || !contains(metrics, m[1]) {
+			continue
+		}
+
+		value, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			continue
+		}
+
+		labels := map[string]string{}
+		for _, l := range expositionLabel.FindAllStringSubmatch(m[2], -1) {
+			labels[l[1]] = strings.ReplaceAll(l[2], `\"`, `"`)
+		}
+		samples[m[1]] = append(samples[m[1]], sample{Labels: labels, Value: value})
+	}
+
+	return samples, scanner.Err()
+}
+
+// gpuUsage combines DCGM samples into the usage of the GPUs allocated to the pods.
+func gpuUsage(samples map[string][]sample, namespace string, pods []string) map[string][]GPUUsage {
+	type key struct{ pod, gpu string }
+
+	gpus := map[key]*GPUUsage{}
+	var order []key
+	for _, metric := range dcgmMetrics {
+		for _, s := range samples[metric] {
+			pod, ns := podLabels(s.Labels)
+			if ns != namespace || !contains(pods, pod) {
+				continue
+			}
+
+			id := s.Labels["UUID"]
+			if id == "" {
+				id = s.Labels["gpu"]
+			}
+
+			k := key{pod, id}
+			usage, ok := gpus[k]
+			if !ok {
+				usage = &GPUUsage{ID: id}
+				gpus[k] = usage
+				order = append(order, k)
+			}
+
+			switch metric {
+			case dcgmUtilization:
+				usage.Utilization = s.Value
+			case dcgmMemoryUsed:
+				usage.MemoryUsedMiB = s.Value
+				usage.MemoryTotalMiB += s.Value
+			case dcgmMemoryFree:
+				usage.MemoryTotalMiB += s.Value
+			}
+		}
+	}
+
+	result := map[string][]GPUUsage{}
+	for _, k := range order {
+		result[k.pod] = append(result[k.pod], *gpus[k])
+	}
+
+	return result
+}
+
+// podLabels returns the pod and namespace a DCGM sample is attributed to.
+// Older versions of the exporter use the pod_name and pod_namespace labels.
+func podLabels(labels map[string]string) (string, string) {
+	pod, namespace := labels["pod"], labels["namespace"]
+	if pod == "" {
+		pod, namespace = labels["pod_name"], labels["pod_namespace"]
+	}
+
+	return pod, namespace
+}
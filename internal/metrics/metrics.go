@@ -0,0 +1,94 @@
+// Package metrics retrieves the resource usage of pods from pluggable metrics sources.
+//
+// CPU and memory usage is provided by a ResourceSource, typically the metrics.k8s.io API served by the metrics server,
+// while GPU usage is provided by a GPUSource, such as Prometheus or a DCGM exporter.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// PodUsage holds the current resource usage of a pod.
+type PodUsage struct {
+	CPUMillicores int64 `json:"cpuMillicores"`
+	MemoryBytes   int64 `json:"memoryBytes"`
+}
+
+// GPUUsage holds the current usage of a single GPU.
+type GPUUsage struct {
+	// ID identifies the GPU, typically by UUID or index.
+	ID string `json:"id"`
+
+	// Utilization is the GPU utilization in percent.
+	Utilization float64 `json:"utilization"`
+
+	MemoryUsedMiB  float64 `json:"memoryUsedMiB"`
+	MemoryTotalMiB float64 `json:"memoryTotalMiB,omitempty"`
+}
+
+// ResourceSource provides the CPU and memory usage of pods.
+type ResourceSource interface {
+	// PodUsage returns the usage of the given pods in the namespace, keyed by pod name.
+	// Pods without metrics, e.g. because they have not started yet, are omitted.
+	PodUsage(ctx context.Context, namespace string, pods []string) (map[string]PodUsage, error)
+}
+
+// GPUSource provides the usage of the GPUs allocated to pods.
+type GPUSource interface {
+	// GPUUsage returns the usage of the GPUs allocated to the given pods in the namespace, keyed by pod name.
+	// Pods without metrics are omitted.
+	GPUUsage(ctx context.Context, namespace string, pods []string) (map[string][]GPUUsage, error)
+}
+
+// MeanUtilization returns the mean utilization of the GPUs, or false if there are none.
+func MeanUtilization(gpus []GPUUsage) (float64, bool) {
+	if len(gpus) == 0 {
+		return 0, false
+	}
+
+	var sum float64
+	for _, gpu := range gpus {
+		sum += gpu.Utilization
+	}
+
+	return sum / float64(len(gpus)), true
+}
+
+// get performs a GET request to url, returning the response body.
+func get(ctx context.Context, client *http.Client, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metrics error: %s: %s", resp.Status, strings.TrimSpace(string(b)))
+	}
+
+	return b, nil
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
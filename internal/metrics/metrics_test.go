@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serveFile(t *testing.T, path, filename string) *httptest.Server {
+	b, err := os.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != path {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+}
+
+func TestMetricsAPIPodUsage(t *testing.T) {
+	server := serveFile(t, "/apis/metrics.k8s.io/v1beta1/namespaces/jane/pods", "testdata/podmetrics.json")
+	defer server.Close()
+
+	api := &MetricsAPI{BaseURL: server.URL, HTTPClient: server.Client()}
+	usage, err := api.PodUsage(context.Background(), "jane", []string{"train-abcde"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]PodUsage{
+		"train-abcde": {CPUMillicores: 1750, MemoryBytes: 2560 << 20},
+	}, usage)
+}
+
+func TestMetricsAPIError(t *testing.T) {
+	server := serveFile(t, "/elsewhere", "testdata/podmetrics.json")
+	defer server.Close()
+
+	api := &MetricsAPI{BaseURL: server.URL, HTTPClient: server.Client()}
+	_, err := api.PodUsage(context.Background(), "jane", []string{"train-abcde"})
+	assert.Error(t, err)
+}
+
+func TestDCGMExporterGPUUsage(t *testing.T) {
+	server := serveFile(t, "/metrics", "testdata/dcgm.txt")
+	defer server.Close()
+
+	exporter := NewDCGMExporter(server.URL+"/metrics", time.Second)
+	usage, err := exporter.GPUUsage(context.Background(), "jane", []string{"train-abcde"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]GPUUsage{
+		"train-abcde": {
+			{ID: "GPU-a", Utilization: 4, MemoryUsedMiB: 1024, MemoryTotalMiB: 40960},
+			{ID: "GPU-b", Utilization: 6, MemoryUsedMiB: 2048, MemoryTotalMiB: 40960},
+		},
+	}, usage)
+
+	mean, ok := MeanUtilization(usage["train-abcde"])
+	assert.True(t, ok)
+	assert.Equal(t, 5.0, mean)
+}
+
+func TestPrometheusGPUUsage(t *testing.T) {
+	values := map[string]string{
+		dcgmUtilization: "42",
+		dcgmMemoryUsed:  "1000",
+		dcgmMemoryFree:  "3000",
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/query", r.URL.Path)
+		query := r.URL.Query().Get("query")
+		assert.Contains(t, query, `{namespace="jane",pod=~"train-abcde"}`)
+
+		metric := query[:strings.Index(query, "{")]
+		fmt.Fprintf(w, `{"status": "success", "data": {"resultType": "vector", "result": [
+			{"metric": {"gpu": "0", "namespace": "jane", "pod": "train-abcde"}, "value": [1633089600, %q]}
+		]}}`, values[metric])
+	}))
+	defer server.Close()
+
+	prometheus := NewPrometheus(server.URL, time.Second)
+	usage, err := prometheus.GPUUsage(context.Background(), "jane", []string{"train-abcde"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]GPUUsage{
+		"train-abcde": {{ID: "0", Utilization: 42, MemoryUsedMiB: 1000, MemoryTotalMiB: 4000}},
+	}, usage)
+}
+
+func TestMeanUtilizationNoGPUs(t *testing.T) {
+	_, ok := MeanUtilization(nil)
+	assert.False(t, ok)
+}
@@ -0,0 +1,83 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+// MetricsAPI is a ResourceSource using the metrics.k8s.io API, as served by the metrics server.
+type MetricsAPI struct {
+	// BaseURL is the URL of the API server.
+	BaseURL string
+
+	// HTTPClient is used to perform requests; it must be authenticated against the API server.
+	HTTPClient *http.Client
+}
+
+// NewMetricsAPI returns a MetricsAPI using the API server identified by config.
+func NewMetricsAPI(config *rest.Config, timeout time.Duration) (*MetricsAPI, error) {
+	transport, err := rest.TransportFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("transport build failed: %w", err)
+	}
+
+	api := &MetricsAPI{
+		BaseURL:    config.Host,
+		HTTPClient: &http.Client{Transport: transport, Timeout: timeout},
+	}
+
+	return api, nil
+}
+
+// podMetricsList is the subset of the metrics.k8s.io/v1beta1 PodMetricsList used by frink.
+type podMetricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Containers []struct {
+			Usage map[string]resource.Quantity `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// PodUsage returns the usage of the pods, summed over their containers.
+func (api *MetricsAPI) PodUsage(ctx context.Context, namespace string, pods []string) (map[string]PodUsage, error) {
+	url := fmt.Sprintf("%s/apis/metrics.k8s.io/v1beta1/namespaces/%s/pods", strings.TrimRight(api.BaseURL, "/"), namespace)
+	b, err := get(ctx, api.HTTPClient, url)
+	if err != nil {
+		return nil, err
+	}
+
+	list := podMetricsList{}
+	if err := json.Unmarshal(b, &list); err != nil {
+		return nil, fmt.Errorf("invalid metrics response: %w", err)
+	}
+
+	usage := map[string]PodUsage{}
+	for _, item := range list.Items {
+		if !contains(pods, item.Metadata.Name) {
+			continue
+		}
+
+		var u PodUsage
+		for _, container := range item.Containers {
+			if cpu, ok := container.Usage["cpu"]; ok {
+				u.CPUMillicores += cpu.MilliValue()
+			}
+			if memory, ok := container.Usage["memory"]; ok {
+				u.MemoryBytes += memory.Value()
+			}
+		}
+		usage[item.Metadata.Name] = u
+	}
+
+	return usage, nil
+}
@@ -0,0 +1,201 @@
+// Package notify sends notifications about finished jobs to sinks such as webhooks, the desktop, or email.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultTimeout is the timeout of HTTP requests made by sinks without a client.
+const defaultTimeout = 10 * time.Second
+
+// Notification describes a finished job.
+type Notification struct {
+	Job      string `json:"job"`
+	Status   string `json:"status"`
+	Duration string `json:"duration"`
+
+	// Reason is why the job failed, such as "OOMKilled" or "BackoffLimitExceeded", if known.
+	Reason string `json:"reason,omitempty"`
+
+	// Logs holds the last lines of the logs of the job.
+	Logs []string `json:"logs,omitempty"`
+}
+
+// Title returns a one-line summary of the notification, e.g. "Job exp-3 Failed (OOMKilled)".
+func (n Notification) Title() string {
+	title := fmt.Sprintf("Job %s %s", n.Job, n.Status)
+	if n.Reason != "" {
+		title += fmt.Sprintf(" (%s)", n.Reason)
+	}
+
+	return title
+}
+
+// Text returns the title of the notification along with the duration of the job.
+func (n Notification) Text() string {
+	return fmt.Sprintf("%s after %s", n.Title(), n.Duration)
+}
+
+// Sink sends notifications somewhere.
+type Sink interface {
+	Send(n Notification) error
+}
+
+// Send sends the notification to every sink, returning an error describing the sinks that failed, if any.
+func Send(sinks []Sink, n Notification) error {
+	var failed []string
+	for _, sink := range sinks {
+		if err := sink.Send(n); err != nil {
+			failed = append(failed, err.Error())
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to send notification: %s", strings.Join(failed, "; "))
+	}
+
+	return nil
+}
+
+// Webhook posts notifications as JSON to a URL.
+type Webhook struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts the notification as is.
+func (w *Webhook) Send(n Notification) error {
+	return postJSON(w.Client, w.URL, n)
+}
+
+// Slack posts notifications to an incoming webhook of Slack, or of a compatible service such as Mattermost.
+type Slack struct {
+	URL    string
+	Client *http.Client
+}
+
+// Send posts the text of the notification, followed by the logs as a code block.
+func (s *Slack) Send(n Notification) error {
+	text := n.Text()
+	if len(n.Logs) > 0 {
+		text += "\n```\n" + strings.Join(n.Logs, "\n") + "\n```"
+	}
+
+	return postJSON(s.Client, s.URL, map[string]string{"text": text})
+}
+
+func postJSON(client *http.Client, url string, value interface{}) error {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Post(url, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook %s responded with %s", url, resp.Status)
+	}
+
+	return nil
+}
+
+// Desktop shows notifications on the desktop using notify-send, or a compatible command.
+type Desktop struct {
+	// Command is the command run with the title and text of the notification; defaults to "notify-send".
+	Command string
+}
+
+// Send runs the command.
+func (d *Desktop) Send(n Notification) error {
+	command := d.Command
+	if command == "" {
+		command = "notify-send"
+	}
+
+	if out, err := exec.Command(command, n.Title(), n.Text()).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", command, err, strings.TrimSpace(string(out)))
+	}
+
+	return nil
+}
+
+// Email sends notifications by email via an SMTP server.
+type Email struct {
+	// Addr is the address of the SMTP server, e.g. "smtp.example.com:587".
+	Addr string
+	From string
+	To   []string
+
+	// Username and Password are used to authenticate with the server, if set.
+	Username string
+	Password string
+
+	// PasswordCommand is a shell command printing the password, run when sending if Password is empty.
+	PasswordCommand string
+
+	// SendMail sends the message; defaults to smtp.SendMail.
+	SendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// Send sends the notification as a plain-text email.
+func (e *Email) Send(n Notification) error {
+	sendMail := e.SendMail
+	if sendMail == nil {
+		sendMail = smtp.SendMail
+	}
+
+	var auth smtp.Auth
+	if e.Username != "" {
+		password, err := e.password()
+		if err != nil {
+			return err
+		}
+
+		host := e.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", e.Username, password, host)
+	}
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", n.Title())
+	fmt.Fprintf(&msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	fmt.Fprintf(&msg, "%s\r\n", n.Text())
+	if len(n.Logs) > 0 {
+		fmt.Fprintf(&msg, "\r\nLast log lines:\r\n\r\n%s\r\n", strings.Join(n.Logs, "\r\n"))
+	}
+
+	return sendMail(e.Addr, auth, e.From, e.To, []byte(msg.String()))
+}
+
+// password returns the password, running the password command if it is not set.
+func (e *Email) password() (string, error) {
+	if e.Password != "" || e.PasswordCommand == "" {
+		return e.Password, nil
+	}
+
+	out, err := exec.Command("sh", "-c", e.PasswordCommand).Output()
+	if err != nil {
+		return "", fmt.Errorf("password command failed: %w", err)
+	}
+
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
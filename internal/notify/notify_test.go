@@ -0,0 +1,145 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var failed = Notification{
+	Job:      "exp-3",
+	Status:   "Failed",
+	Duration: "2 hours 3 minutes",
+	Reason:   "OOMKilled",
+	Logs:     []string{"epoch 12", "Killed"},
+}
+
+// recorder returns a server recording the body of the last request, responding with the given status.
+func recorder(t *testing.T, status int, body *[]byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+
+		b, err := ioutil.ReadAll(r.Body)
+		assert.NoError(t, err)
+		*body = b
+
+		w.WriteHeader(status)
+	}))
+}
+
+func TestNotificationText(t *testing.T) {
+	assert.Equal(t, "Job exp-3 Failed (OOMKilled)", failed.Title())
+	assert.Equal(t, "Job exp-3 Failed (OOMKilled) after 2 hours 3 minutes", failed.Text())
+	assert.Equal(t, "Job exp-1 Succeeded", Notification{Job: "exp-1", Status: "Succeeded"}.Title())
+}
+
+func TestWebhookSend(t *testing.T) {
+	var body []byte
+	server := recorder(t, http.StatusOK, &body)
+	defer server.Close()
+
+	err := (&Webhook{URL: server.URL}).Send(failed)
+	assert.NoError(t, err)
+
+	var received Notification
+	assert.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, failed, received)
+}
+
+func TestWebhookSendError(t *testing.T) {
+	var body []byte
+	server := recorder(t, http.StatusInternalServerError, &body)
+	defer server.Close()
+
+	err := (&Webhook{URL: server.URL}).Send(failed)
+	assert.EqualError(t, err, "webhook "+server.URL+" responded with 500 Internal Server Error")
+}
+
+func TestSlackSend(t *testing.T) {
+	var body []byte
+	server := recorder(t, http.StatusOK, &body)
+	defer server.Close()
+
+	err := (&Slack{URL: server.URL}).Send(failed)
+	assert.NoError(t, err)
+
+	var received map[string]string
+	assert.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, "Job exp-3 Failed (OOMKilled) after 2 hours 3 minutes\n```\nepoch 12\nKilled\n```", received["text"])
+}
+
+func TestDesktopSend(t *testing.T) {
+	assert.NoError(t, (&Desktop{Command: "true"}).Send(failed))
+	assert.Error(t, (&Desktop{Command: "false"}).Send(failed))
+}
+
+func TestEmailSend(t *testing.T) {
+	var (
+		addr, from string
+		to         []string
+		msg        []byte
+		auth       smtp.Auth
+	)
+	email := &Email{
+		Addr:     "smtp.example.com:587",
+		From:     "frink@example.com",
+		To:       []string{"jane@example.com"},
+		Username: "jane",
+		Password: "secret",
+		SendMail: func(a string, au smtp.Auth, f string, t []string, m []byte) error {
+			addr, auth, from, to, msg = a, au, f, t, m
+			return nil
+		},
+	}
+
+	err := email.Send(failed)
+	assert.NoError(t, err)
+	assert.Equal(t, "smtp.example.com:587", addr)
+	assert.NotNil(t, auth)
+	assert.Equal(t, "frink@example.com", from)
+	assert.Equal(t, []string{"jane@example.com"}, to)
+	assert.Contains(t, string(msg), "Subject: Job exp-3 Failed (OOMKilled)\r\n")
+	assert.Contains(t, string(msg), "epoch 12\r\nKilled")
+}
+
+func TestEmailPasswordCommand(t *testing.T) {
+	var auth smtp.Auth
+	email := &Email{
+		Addr:            "smtp.example.com:587",
+		From:            "frink@example.com",
+		To:              []string{"jane@example.com"},
+		Username:        "jane",
+		PasswordCommand: "echo secret",
+		SendMail: func(_ string, a smtp.Auth, _ string, _ []string, _ []byte) error {
+			auth = a
+			return nil
+		},
+	}
+	assert.NoError(t, email.Send(failed))
+	assert.Equal(t, smtp.PlainAuth("", "jane", "secret", "smtp.example.com"), auth)
+
+	email.PasswordCommand = "false"
+	assert.Error(t, email.Send(failed))
+}
+
+type failingSink struct{}
+
+func (failingSink) Send(Notification) error { return errors.New("boom") }
+
+func TestSend(t *testing.T) {
+	var body []byte
+	server := recorder(t, http.StatusOK, &body)
+	defer server.Close()
+
+	// Sinks after a failing sink are still notified.
+	err := Send([]Sink{failingSink{}, &Webhook{URL: server.URL}}, failed)
+	assert.EqualError(t, err, "unable to send notification: boom")
+	assert.NotEmpty(t, body)
+}
@@ -0,0 +1,211 @@
+// Package pipeline implements pipelines of jobs, where each job is created once the jobs it depends on have succeeded.
+package pipeline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/afero"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// Labels identifying the pipeline and step of jobs and state config maps created by frink.
+const (
+	PipelineLabel = "frink/pipeline"
+	StepLabel     = "frink/step"
+)
+
+// File is the pipeline file format.
+//
+// Each job is either read from a job specification file, relative to the pipeline file,
+// or specified inline; in both cases it can be a full k8s job or a simplified job.
+type File struct {
+	Name string     `json:"name"`
+	Jobs []FileStep `json:"jobs"`
+}
+
+// FileStep is a job in a pipeline file.
+type FileStep struct {
+	Name      string          `json:"name"`
+	File      string          `json:"file,omitempty"`
+	Job       json.RawMessage `json:"job,omitempty"`
+	DependsOn []string        `json:"dependsOn,omitempty"`
+}
+
+// Pipeline is a parsed pipeline, where the jobs of all steps have been resolved.
+type Pipeline struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single job in a pipeline.
+type Step struct {
+	Name      string       `json:"name"`
+	DependsOn []string     `json:"dependsOn,omitempty"`
+	Job       *batchv1.Job `json:"job"`
+}
+
+// Parse reads the pipeline file at filename from fs, parsing the job specifications using parser.
+//
+// Jobs are named "<pipeline>-<step>", regardless of the names in their specifications,
+// so that the jobs of different pipelines do not conflict. Jobs are kept until the pipeline is removed,
+// regardless of any TTL, since the status of a step is lost along with its job.
+func Parse(fs afero.Fs, filename string, parser k8s.JobParser) (*Pipeline, error) {
+	b, err := afero.ReadFile(fs, filename)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{}
+	if err := yaml.UnmarshalStrict(b, file); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	p := &Pipeline{Name: file.Name}
+	for _, fileStep := range file.Jobs {
+		job, err := parseStepJob(fs, filepath.Dir(filename), fileStep, parser)
+		if err != nil {
+			return nil, fmt.Errorf("%s: step %q: %w", filename, fileStep.Name, err)
+		}
+
+		k8s.Rename(job, JobName(file.Name, fileStep.Name))
+		setLabels(job, file.Name, fileStep.Name)
+		// A step that succeeded while the pipeline was not being run would otherwise be considered failed on resume.
+		job.Spec.TTLSecondsAfterFinished = nil
+		p.Steps = append(p.Steps, Step{Name: fileStep.Name, DependsOn: fileStep.DependsOn, Job: job})
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return p, nil
+}
+
+func parseStepJob(fs afero.Fs, dir string, step FileStep, parser k8s.JobParser) (*batchv1.Job, error) {
+	switch {
+	case step.File != "" && len(step.Job) > 0:
+		return nil, fmt.Errorf("only one of file and job can be specified")
+	case step.File != "":
+		filename := step.File
+		if !filepath.IsAbs(filename) {
+			filename = filepath.Join(dir, filename)
+		}
+
+		f, err := fs.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return parser.Parse(f, filename)
+	case len(step.Job) > 0:
+		return parser.Parse(bytes.NewReader(step.Job), "job")
+	}
+
+	return nil, fmt.Errorf("either file or job must be specified")
+}
+
+func setLabels(job *batchv1.Job, pipeline, step string) {
+	if job.Labels == nil {
+		job.Labels = map[string]string{}
+	}
+	job.Labels[PipelineLabel] = pipeline
+	job.Labels[StepLabel] = step
+}
+
+// JobName returns the name of the job of the step in the pipeline.
+func JobName(pipeline, step string) string {
+	return pipeline + "-" + step
+}
+
+// Validate returns an error if the pipeline is invalid, e.g. if the dependencies of the steps contain cycles.
+func (p *Pipeline) Validate() error {
+	if errs := validation.IsDNS1123Label(p.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid pipeline name %q: %s", p.Name, errs[0])
+	}
+
+	if len(p.Steps) == 0 {
+		return fmt.Errorf("pipeline has no jobs")
+	}
+
+	steps := map[string]bool{}
+	for _, step := range p.Steps {
+		if errs := validation.IsDNS1123Label(step.Job.Name); len(errs) > 0 {
+			return fmt.Errorf("invalid job name %q for step %q: %s", step.Job.Name, step.Name, errs[0])
+		}
+		if steps[step.Name] {
+			return fmt.Errorf("duplicate step %q", step.Name)
+		}
+		steps[step.Name] = true
+	}
+
+	for _, step := range p.Steps {
+		for _, dep := range step.DependsOn {
+			if !steps[dep] {
+				return fmt.Errorf("step %q depends on unknown step %q", step.Name, dep)
+			}
+		}
+	}
+
+	if cycle := p.cycle(); cycle != nil {
+		return fmt.Errorf("dependency cycle: %v", cycle)
+	}
+
+	return nil
+}
+
+// cycle returns the steps of a dependency cycle, or nil if there are none.
+func (p *Pipeline) cycle() []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	deps := map[string][]string{}
+	for _, step := range p.Steps {
+		deps[step.Name] = step.DependsOn
+	}
+
+	state := map[string]int{}
+	var path []string
+	var visit func(name string) []string
+	visit = func(name string) []string {
+		switch state[name] {
+		case visiting:
+			for i, n := range path {
+				if n == name {
+					return append(append([]string{}, path[i:]...), name)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+		for _, dep := range deps[name] {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+
+		return nil
+	}
+
+	for _, step := range p.Steps {
+		if cycle := visit(step.Name); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package pipeline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParse(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
+	p, err := Parse(fs, "pipeline.yaml", k8s.NewJobParser())
+	assert.NoError(t, err)
+
+	if assert.Len(t, p.Steps, 2) {
+		assert.Equal(t, "exp1-preprocess", p.Steps[0].Job.Name)
+		assert.Equal(t, "python:3", p.Steps[0].Job.Spec.Template.Spec.Containers[0].Image)
+		assert.Equal(t, "exp1-train", p.Steps[1].Job.Name)
+		assert.Equal(t, []string{"preprocess"}, p.Steps[1].DependsOn)
+		assert.Equal(t, "train", p.Steps[1].Job.Labels[StepLabel])
+		assert.Equal(t, "exp1", p.Steps[1].Job.Labels[PipelineLabel])
+	}
+}
+
+func TestParseIgnoresTTL(t *testing.T) {
+	fs := afero.NewBasePathFs(afero.NewOsFs(), "testdata")
+	parser := k8s.NewJobParserWithOptions(k8s.ParserOptions{DefaultTTL: time.Hour})
+	p, err := Parse(fs, "pipeline.yaml", parser)
+	assert.NoError(t, err)
+
+	for _, step := range p.Steps {
+		assert.Nil(t, step.Job.Spec.TTLSecondsAfterFinished, "step %s", step.Name)
+	}
+}
+
+func newStep(name string, dependsOn ...string) Step {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: JobName("p", name)}}
+	return Step{Name: name, DependsOn: dependsOn, Job: job}
+}
+
+func TestValidate(t *testing.T) {
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	assert.NoError(t, p.Validate())
+
+	p = &Pipeline{Name: "p", Steps: []Step{newStep("a", "c"), newStep("b", "a"), newStep("c", "b")}}
+	assert.EqualError(t, p.Validate(), "dependency cycle: [a c b a]")
+
+	p = &Pipeline{Name: "p", Steps: []Step{newStep("a", "x")}}
+	assert.EqualError(t, p.Validate(), `step "a" depends on unknown step "x"`)
+
+	p = &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("a")}}
+	assert.EqualError(t, p.Validate(), `duplicate step "a"`)
+
+	p = &Pipeline{Name: "Invalid_Name", Steps: []Step{newStep("a")}}
+	assert.Error(t, p.Validate())
+}
+
+func TestEncodeDecode(t *testing.T) {
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	state := NewState(p)
+
+	configMap, err := Encode(p, state)
+	assert.NoError(t, err)
+	assert.Equal(t, "frink-pipeline-p", configMap.Name)
+
+	decoded, decodedState, err := Decode(configMap)
+	assert.NoError(t, err)
+	assert.Equal(t, p, decoded)
+	assert.Equal(t, StatusPending, decodedState.Steps["b"])
+	assert.False(t, decodedState.Finished())
+}
@@ -0,0 +1,395 @@
+package pipeline
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/retry"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var backoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   1.0,
+	Steps:    1200,
+}
+
+// conflictBackoff is used to retry saving the state after concurrent modifications, e.g. by another resumed run.
+var conflictBackoff = wait.Backoff{
+	Duration: 10 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   1.0,
+	Steps:    10,
+}
+
+// Runner runs pipelines, creating the job of each step once the steps it depends on have succeeded.
+type Runner struct {
+	Client k8s.Client
+
+	// Out receives progress messages, such as step status transitions.
+	Out io.Writer
+
+	// Interval is the time between checking the status of running jobs.
+	Interval time.Duration
+
+	// DeleteTimeout is how long to wait for existing jobs to be deleted before recreating them.
+	DeleteTimeout time.Duration
+
+	// OnConflict decides what happens when the job of a step already exists but does not belong to the pipeline,
+	// as for "frink run": "replace" (the default), "fail", or "suffix". Jobs of previous runs are always replaced.
+	OnConflict string
+}
+
+// Start persists the initial state of the pipeline, replacing any previous run that has finished.
+// It fails if a previous run of the pipeline has not finished; see Resume.
+func (r *Runner) Start(p *Pipeline) (*State, error) {
+	existing, err := r.Client.GetConfigMap(ConfigMapName(p.Name))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get pipeline state: %w", err)
+	}
+
+	if existing != nil {
+		_, state, err := Decode(existing)
+		if err == nil && !state.Finished() {
+			return nil, fmt.Errorf("pipeline %s is already running; resume it with --resume, or remove it first", p.Name)
+		}
+	}
+
+	state := NewState(p)
+	configMap, err := Encode(p, state)
+	if err != nil {
+		return nil, err
+	}
+
+	if existing != nil {
+		// The resource version is kept, so that a run started concurrently is detected.
+		configMap.ResourceVersion = existing.ResourceVersion
+		err = r.Client.UpdateConfigMap(configMap)
+	} else {
+		err = r.Client.CreateConfigMap(configMap)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to save pipeline state: %w", err)
+	}
+
+	return state, nil
+}
+
+// Resume returns the persisted pipeline and state of an unfinished run of the named pipeline.
+func (r *Runner) Resume(name string) (*Pipeline, *State, error) {
+	configMap, err := r.Client.GetConfigMap(ConfigMapName(name))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to get pipeline state: %w", err)
+	}
+	if configMap == nil {
+		return nil, nil, fmt.Errorf("pipeline %s not found", name)
+	}
+
+	p, state, err := Decode(configMap)
+	if err != nil {
+		return nil, nil, err
+	}
+	if state.Finished() {
+		return nil, nil, fmt.Errorf("pipeline %s has already finished (%s)", name, state.Status)
+	}
+
+	return p, state, nil
+}
+
+// Run drives the pipeline until all steps have succeeded, or a step has failed.
+// Steps that have already succeeded or are running according to the state are not recreated,
+// so a run that was interrupted can be continued by calling Run with the persisted state.
+func (r *Runner) Run(p *Pipeline, state *State) error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+
+	for {
+		changed, err := r.step(p, state)
+		if err != nil {
+			return err
+		}
+
+		if changed {
+			state.Updated = time.Now()
+			if err := r.save(p, state); err != nil {
+				return err
+			}
+		}
+
+		switch state.Status {
+		case StatusSucceeded:
+			fmt.Fprintf(r.Out, "Pipeline %s succeeded\n", p.Name)
+			return nil
+		case StatusFailed:
+			return fmt.Errorf("pipeline %s failed", p.Name)
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// step updates the status of running steps, and creates the jobs of steps whose dependencies have succeeded.
+// It returns whether the state changed.
+func (r *Runner) step(p *Pipeline, state *State) (bool, error) {
+	changed := false
+	transition := func(step Step, status string) {
+		state.Steps[step.Name] = status
+		changed = true
+		fmt.Fprintf(r.Out, "%s: %s (job %s)\n", step.Name, status, step.Job.Name)
+	}
+
+	for _, step := range p.Steps {
+		if state.Steps[step.Name] != StatusRunning {
+			continue
+		}
+
+		job, err := r.Client.GetJob(step.Job.Name)
+		if err != nil {
+			return changed, fmt.Errorf("unable to get job %s: %w", step.Job.Name, err)
+		}
+
+		switch {
+		case job == nil:
+			// The job was deleted by someone else, so it will never succeed.
+			transition(step, StatusFailed)
+		case k8s.JobStatus(*job) == k8s.StatusSucceeded:
+			transition(step, StatusSucceeded)
+		case k8s.JobStatus(*job) == k8s.StatusFailed:
+			transition(step, StatusFailed)
+		}
+	}
+
+	failed := false
+	for _, status := range state.Steps {
+		if status == StatusFailed {
+			failed = true
+		}
+	}
+
+	// Stop on failure: steps that have not been started yet are skipped, while running steps are waited for,
+	// so that the persisted state does not report them as running after the pipeline has failed.
+	if failed {
+		running := false
+		for _, step := range p.Steps {
+			switch state.Steps[step.Name] {
+			case StatusPending:
+				transition(step, StatusSkipped)
+			case StatusRunning:
+				running = true
+			}
+		}
+		if running {
+			return changed, nil
+		}
+		state.Status = StatusFailed
+
+		return true, nil
+	}
+
+	succeeded := 0
+	for _, step := range p.Steps {
+		switch state.Steps[step.Name] {
+		case StatusSucceeded:
+			succeeded++
+			continue
+		case StatusPending:
+		default:
+			continue
+		}
+
+		if !dependenciesSucceeded(step, state) {
+			continue
+		}
+
+		if err := r.createJob(p.Name, step); err != nil {
+			return changed, fmt.Errorf("unable to create job for step %s: %w", step.Name, err)
+		}
+		transition(step, StatusRunning)
+	}
+
+	if succeeded == len(p.Steps) {
+		state.Status = StatusSucceeded
+		changed = true
+	}
+
+	return changed, nil
+}
+
+func dependenciesSucceeded(step Step, state *State) bool {
+	for _, dep := range step.DependsOn {
+		if state.Steps[dep] != StatusSucceeded {
+			return false
+		}
+	}
+
+	return true
+}
+
+// createJob creates the job of the step. An existing job of the step from a previous run of the pipeline is replaced,
+// while other jobs with the same name are handled according to the OnConflict policy.
+// With the suffix policy, the job of the step is renamed, which is persisted when the state is saved.
+func (r *Runner) createJob(pipeline string, step Step) error {
+	existing, err := r.Client.GetJob(step.Job.Name)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		owned := existing.Labels[PipelineLabel] == pipeline && existing.Labels[StepLabel] == step.Name
+		switch {
+		case owned:
+		case r.OnConflict == "" || r.OnConflict == "replace":
+			fmt.Fprintf(r.Out, "%s: replacing job %s, which does not belong to pipeline %s\n", step.Name, existing.Name, pipeline)
+		case r.OnConflict == "fail":
+			return fmt.Errorf("job %s already exists and does not belong to pipeline %s", existing.Name, pipeline)
+		case r.OnConflict == "suffix":
+			k8s.Rename(step.Job, fmt.Sprintf("%s-%s", step.Job.Name, rand.String(5)))
+			fmt.Fprintf(r.Out, "%s: job %s already exists; using name %s\n", step.Name, existing.Name, step.Job.Name)
+			existing = nil
+		default:
+			return fmt.Errorf("unknown conflict policy %q (use replace, fail or suffix)", r.OnConflict)
+		}
+	}
+
+	if existing != nil {
+		if err := r.deleteJob(existing.Name); err != nil {
+			return err
+		}
+	}
+
+	// Jobs are passed by pointer and might be modified by the client, so a copy is created.
+	if err := retry.OnExists(backoff, func() error { return r.Client.CreateJob(step.Job.DeepCopy()) }); err != nil {
+		return err
+	}
+
+	return k8s.CreateJobService(r.Client, step.Job)
+}
+
+func (r *Runner) deleteJob(name string) error {
+	if err := r.Client.DeleteJob(name); err != nil {
+		return err
+	}
+
+	err := wait.Poll(100*time.Millisecond, r.DeleteTimeout, func() (bool, error) {
+		job, err := r.Client.GetJob(name)
+		return job == nil, err
+	})
+	if err != nil {
+		return fmt.Errorf("timed out waiting for previous job to be deleted: %w", err)
+	}
+
+	return nil
+}
+
+// save persists the pipeline and its state, merging them with the persisted state, which another process running
+// the same pipeline might have changed. Steps keep the most advanced status of either, along with the job of that
+// status, and p and state are updated with the merged result. Concurrent modifications are detected using the
+// resource version of the config map, and retried.
+func (r *Runner) save(p *Pipeline, state *State) error {
+	err := retry.OnError(conflictBackoff, isConcurrentModification, func() error {
+		existing, err := r.Client.GetConfigMap(ConfigMapName(p.Name))
+		if err != nil {
+			return err
+		}
+
+		if existing != nil {
+			stored, storedState, err := Decode(existing)
+			if err != nil {
+				return err
+			}
+			merge(p, state, stored, storedState)
+		}
+
+		configMap, err := Encode(p, state)
+		if err != nil {
+			return err
+		}
+
+		if existing == nil {
+			return r.Client.CreateConfigMap(configMap)
+		}
+
+		configMap.ResourceVersion = existing.ResourceVersion
+		return r.Client.UpdateConfigMap(configMap)
+	})
+	if err != nil {
+		return fmt.Errorf("unable to save pipeline state: %w", err)
+	}
+
+	return nil
+}
+
+// merge updates p and state with the steps that are further along in the stored pipeline and state.
+func merge(p *Pipeline, state *State, stored *Pipeline, storedState *State) {
+	storedJobs := map[string]*batchv1.Job{}
+	for _, step := range stored.Steps {
+		storedJobs[step.Name] = step.Job
+	}
+
+	for i, step := range p.Steps {
+		status := storedState.Steps[step.Name]
+		if statusRank(status) <= statusRank(state.Steps[step.Name]) {
+			continue
+		}
+
+		state.Steps[step.Name] = status
+		if job := storedJobs[step.Name]; job != nil {
+			p.Steps[i].Job = job
+		}
+	}
+
+	if storedState.Finished() && !state.Finished() {
+		state.Status = storedState.Status
+	}
+}
+
+// statusRank orders the statuses of steps by progress; steps never go back to a status of lower rank.
+func statusRank(status string) int {
+	switch status {
+	case StatusRunning:
+		return 1
+	case StatusSucceeded, StatusFailed, StatusSkipped:
+		return 2
+	}
+
+	return 0
+}
+
+func isConcurrentModification(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+}
+
+// Remove deletes the jobs of the pipeline, as well as its persisted state.
+func (r *Runner) Remove(name string) error {
+	configMap, err := r.Client.GetConfigMap(ConfigMapName(name))
+	if err != nil {
+		return fmt.Errorf("unable to get pipeline state: %w", err)
+	}
+	if configMap == nil {
+		return fmt.Errorf("pipeline %s not found", name)
+	}
+
+	p, _, err := Decode(configMap)
+	if err != nil {
+		return err
+	}
+
+	for _, step := range p.Steps {
+		if err := r.Client.DeleteJob(step.Job.Name); err != nil {
+			return fmt.Errorf("unable to delete job %s: %w", step.Job.Name, err)
+		}
+	}
+
+	if err := r.Client.DeleteConfigMap(configMap.Name); err != nil {
+		return fmt.Errorf("unable to delete pipeline state: %w", err)
+	}
+
+	return nil
+}
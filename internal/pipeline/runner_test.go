@@ -0,0 +1,188 @@
+package pipeline
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func finishedJob(name string, succeeded bool) *batchv1.Job {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if succeeded {
+		job.Status.Succeeded = 1
+	} else {
+		job.Status.Failed = 1
+	}
+
+	return job
+}
+
+// storedConfigMap returns the config map holding the pipeline and state, as returned by the API server.
+func storedConfigMap(p *Pipeline, state *State) *corev1.ConfigMap {
+	configMap, _ := Encode(p, state)
+	configMap.ResourceVersion = "1"
+
+	return configMap
+}
+
+func TestRunnerRun(t *testing.T) {
+	var out strings.Builder
+	client := &fake.Client{}
+	runner := &Runner{Client: client, Out: &out, Interval: time.Millisecond}
+
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	state := NewState(p)
+
+	client.On("GetJob", "p-a").Return(nil, nil).Once()
+	client.On("GetJob", "p-a").Return(finishedJob("p-a", true), nil)
+	client.On("GetJob", "p-b").Return(nil, nil).Once()
+	client.On("GetJob", "p-b").Return(finishedJob("p-b", true), nil)
+	client.On("CreateJob", p.Steps[0].Job).Return(nil).Once()
+	client.On("CreateJob", p.Steps[1].Job).Return(nil).Once()
+	client.On("GetConfigMap", "frink-pipeline-p").Return(storedConfigMap(p, NewState(p)), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(nil)
+
+	err := runner.Run(p, state)
+	assert.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, state.Status)
+	assert.Equal(t, map[string]string{"a": StatusSucceeded, "b": StatusSucceeded}, state.Steps)
+	assert.Contains(t, out.String(), "b: Running (job p-b)")
+	assert.Contains(t, out.String(), "Pipeline p succeeded")
+
+	client.AssertExpectations(t)
+}
+
+func TestRunnerRunFailure(t *testing.T) {
+	client := &fake.Client{}
+	runner := &Runner{Client: client, Out: &strings.Builder{}, Interval: time.Millisecond}
+
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	state := NewState(p)
+	state.Steps["a"] = StatusRunning
+
+	client.On("GetJob", "p-a").Return(finishedJob("p-a", false), nil)
+	client.On("GetConfigMap", "frink-pipeline-p").Return(storedConfigMap(p, NewState(p)), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(nil)
+
+	err := runner.Run(p, state)
+	assert.EqualError(t, err, "pipeline p failed")
+	assert.Equal(t, map[string]string{"a": StatusFailed, "b": StatusSkipped}, state.Steps)
+
+	client.AssertNotCalled(t, "CreateJob", mock.Anything)
+}
+
+func TestRunnerRunFailureWaitsForRunningSteps(t *testing.T) {
+	var out strings.Builder
+	client := &fake.Client{}
+	runner := &Runner{Client: client, Out: &out, Interval: time.Millisecond}
+
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b"), newStep("c", "a")}}
+	state := NewState(p)
+	state.Steps["a"] = StatusRunning
+	state.Steps["b"] = StatusRunning
+
+	client.On("GetJob", "p-a").Return(finishedJob("p-a", false), nil)
+	client.On("GetJob", "p-b").Return(&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "p-b"}, Status: batchv1.JobStatus{Active: 1}}, nil).Once()
+	client.On("GetJob", "p-b").Return(finishedJob("p-b", true), nil)
+	client.On("GetConfigMap", "frink-pipeline-p").Return(storedConfigMap(p, NewState(p)), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(nil)
+
+	err := runner.Run(p, state)
+	assert.EqualError(t, err, "pipeline p failed")
+	assert.Equal(t, map[string]string{"a": StatusFailed, "b": StatusSucceeded, "c": StatusSkipped}, state.Steps)
+	assert.Contains(t, out.String(), "b: Succeeded (job p-b)")
+
+	client.AssertNotCalled(t, "CreateJob", mock.Anything)
+}
+
+func TestRunnerStartAlreadyRunning(t *testing.T) {
+	client := &fake.Client{}
+	runner := &Runner{Client: client}
+
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a")}}
+	configMap, _ := Encode(p, NewState(p))
+	client.On("GetConfigMap", "frink-pipeline-p").Return(configMap, nil)
+
+	_, err := runner.Start(p)
+	assert.Error(t, err)
+}
+
+func TestRunnerCreateJobConflict(t *testing.T) {
+	owned := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "p-a", Labels: map[string]string{PipelineLabel: "p", StepLabel: "a"}}}
+	foreign := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "p-a"}}
+
+	// Jobs of previous runs of the pipeline are replaced regardless of the policy.
+	client := &fake.Client{}
+	client.On("GetJob", "p-a").Return(owned, nil).Once()
+	client.On("GetJob", "p-a").Return(nil, nil)
+	client.On("DeleteJob", "p-a").Return(nil)
+	client.On("CreateJob", mock.Anything).Return(nil)
+	runner := &Runner{Client: client, Out: &strings.Builder{}, OnConflict: "fail", DeleteTimeout: time.Second}
+	assert.NoError(t, runner.createJob("p", newStep("a")))
+	client.AssertExpectations(t)
+
+	client = &fake.Client{}
+	client.On("GetJob", "p-a").Return(foreign, nil)
+	runner = &Runner{Client: client, Out: &strings.Builder{}, OnConflict: "fail"}
+	err := runner.createJob("p", newStep("a"))
+	assert.EqualError(t, err, "job p-a already exists and does not belong to pipeline p")
+	client.AssertNotCalled(t, "DeleteJob", mock.Anything)
+
+	var out strings.Builder
+	client = &fake.Client{}
+	client.On("GetJob", "p-a").Return(foreign, nil)
+	client.On("CreateJob", mock.Anything).Return(nil)
+	runner = &Runner{Client: client, Out: &out, OnConflict: "suffix"}
+	step := newStep("a")
+	assert.NoError(t, runner.createJob("p", step))
+	assert.Regexp(t, `^p-a-\w{5}$`, step.Job.Name)
+	assert.Contains(t, out.String(), "a: job p-a already exists; using name "+step.Job.Name)
+	client.AssertNotCalled(t, "DeleteJob", mock.Anything)
+
+	out.Reset()
+	client = &fake.Client{}
+	client.On("GetJob", "p-a").Return(foreign, nil).Once()
+	client.On("GetJob", "p-a").Return(nil, nil)
+	client.On("DeleteJob", "p-a").Return(nil)
+	client.On("CreateJob", mock.Anything).Return(nil)
+	runner = &Runner{Client: client, Out: &out, DeleteTimeout: time.Second}
+	assert.NoError(t, runner.createJob("p", newStep("a")))
+	assert.Contains(t, out.String(), "a: replacing job p-a, which does not belong to pipeline p")
+}
+
+func TestRunnerSaveMergesConcurrentState(t *testing.T) {
+	p := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	state := NewState(p)
+	state.Steps["a"] = StatusSucceeded
+
+	// Another process has meanwhile started b, with a renamed job.
+	stored := &Pipeline{Name: "p", Steps: []Step{newStep("a"), newStep("b", "a")}}
+	k8s.Rename(stored.Steps[1].Job, "p-b-x")
+	storedState := NewState(stored)
+	storedState.Steps["a"] = StatusRunning
+	storedState.Steps["b"] = StatusRunning
+
+	client := &fake.Client{}
+	client.On("GetConfigMap", "frink-pipeline-p").Return(storedConfigMap(stored, storedState), nil)
+	client.On("UpdateConfigMap", mock.Anything).Return(apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, "frink-pipeline-p", nil)).Once()
+	client.On("UpdateConfigMap", mock.MatchedBy(func(configMap *corev1.ConfigMap) bool {
+		return configMap.ResourceVersion == "1"
+	})).Return(nil).Once()
+
+	runner := &Runner{Client: client}
+	assert.NoError(t, runner.save(p, state))
+	assert.Equal(t, map[string]string{"a": StatusSucceeded, "b": StatusRunning}, state.Steps)
+	assert.Equal(t, "p-b-x", p.Steps[1].Job.Name)
+
+	client.AssertExpectations(t)
+}
@@ -0,0 +1,95 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Statuses of pipelines and their steps.
+const (
+	StatusPending   = "Pending"
+	StatusRunning   = "Running"
+	StatusSucceeded = "Succeeded"
+	StatusFailed    = "Failed"
+	StatusSkipped   = "Skipped" // a step is skipped if a step it depends on failed
+)
+
+// Keys of the data in state config maps.
+const (
+	pipelineKey = "pipeline.json"
+	stateKey    = "state.json"
+)
+
+// State is the persisted progress of a pipeline run.
+type State struct {
+	Status  string            `json:"status"`
+	Steps   map[string]string `json:"steps"`
+	Started time.Time         `json:"started"`
+	Updated time.Time         `json:"updated"`
+}
+
+// NewState returns the initial state of a run of the pipeline, where all steps are pending.
+func NewState(p *Pipeline) *State {
+	now := time.Now()
+	state := &State{Status: StatusRunning, Steps: map[string]string{}, Started: now, Updated: now}
+	for _, step := range p.Steps {
+		state.Steps[step.Name] = StatusPending
+	}
+
+	return state
+}
+
+// Finished reports whether the pipeline run has finished, successfully or not.
+func (s *State) Finished() bool {
+	return s.Status == StatusSucceeded || s.Status == StatusFailed
+}
+
+// ConfigMapName returns the name of the config map holding the state of the pipeline.
+func ConfigMapName(pipeline string) string {
+	return "frink-pipeline-" + pipeline
+}
+
+// Encode returns a config map holding the pipeline and its state.
+func Encode(p *Pipeline, state *State) (*corev1.ConfigMap, error) {
+	pipeline, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := json.Marshal(state)
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   ConfigMapName(p.Name),
+			Labels: map[string]string{PipelineLabel: p.Name},
+		},
+		Data: map[string]string{
+			pipelineKey: string(pipeline),
+			stateKey:    string(s),
+		},
+	}
+
+	return configMap, nil
+}
+
+// Decode returns the pipeline and its state held by the config map.
+func Decode(configMap *corev1.ConfigMap) (*Pipeline, *State, error) {
+	p := &Pipeline{}
+	if err := json.Unmarshal([]byte(configMap.Data[pipelineKey]), p); err != nil {
+		return nil, nil, fmt.Errorf("invalid pipeline in config map %s: %w", configMap.Name, err)
+	}
+
+	state := &State{}
+	if err := json.Unmarshal([]byte(configMap.Data[stateKey]), state); err != nil {
+		return nil, nil, fmt.Errorf("invalid pipeline state in config map %s: %w", configMap.Name, err)
+	}
+
+	return p, state, nil
+}
@@ -0,0 +1,159 @@
+// Package queue implements a client-side queue of jobs, which are submitted while keeping the number of active jobs bounded.
+//
+// Each user has their own queue, persisted in a config map, so that it can be shared by several clients.
+// Concurrent modifications are detected using the resource version of the config map, and retried.
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/retry"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// QueueLabel identifies config maps holding queues; its value is the (sanitized) name of the user owning the queue.
+const QueueLabel = "frink/queue"
+
+// dataKey is the key of the queue in its config map.
+const dataKey = "queue.json"
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// conflictBackoff is used to retry updates of the queue after concurrent modifications.
+// It is jittered, so that clients updating the queue at the same time are unlikely to conflict again.
+var conflictBackoff = wait.Backoff{
+	Duration: 10 * time.Millisecond,
+	Factor:   1.5,
+	Jitter:   1.0,
+	Steps:    10,
+}
+
+// Entry is a job waiting in a queue.
+type Entry struct {
+	Job   *batchv1.Job `json:"job"`
+	Added time.Time    `json:"added"`
+}
+
+// Queue holds the jobs waiting to be submitted, in order.
+type Queue struct {
+	Entries []Entry `json:"entries"`
+}
+
+// Pop removes and returns the first entry of the queue, or false if the queue is empty.
+func (q *Queue) Pop() (Entry, bool) {
+	if len(q.Entries) == 0 {
+		return Entry{}, false
+	}
+
+	entry := q.Entries[0]
+	q.Entries = q.Entries[1:]
+
+	return entry, true
+}
+
+// Remove removes the entries of the jobs with the given name, and returns how many were removed.
+func (q *Queue) Remove(name string) int {
+	var kept []Entry
+	for _, entry := range q.Entries {
+		if entry.Job.Name != name {
+			kept = append(kept, entry)
+		}
+	}
+
+	removed := len(q.Entries) - len(kept)
+	q.Entries = kept
+
+	return removed
+}
+
+// ConfigMapName returns the name of the config map holding the queue of the user.
+func ConfigMapName(user string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(strings.ToLower(user), "-"), "-")
+	if name == "" {
+		name = k8s.UnknownUser
+	}
+
+	return "frink-queue-" + name
+}
+
+// Store loads and saves the queue of a user.
+type Store struct {
+	Client k8s.Client
+	User   string
+}
+
+// Load returns the queue, which is empty if it has not been saved before.
+func (s *Store) Load() (*Queue, error) {
+	configMap, err := s.Client.GetConfigMap(ConfigMapName(s.User))
+	if err != nil {
+		return nil, fmt.Errorf("unable to get queue: %w", err)
+	}
+
+	return decode(configMap)
+}
+
+// Update applies fn to the current queue, and saves the result.
+// If the queue is modified concurrently, fn is applied again to the new queue; errors returned by fn abort the update.
+func (s *Store) Update(fn func(q *Queue) error) error {
+	return retry.OnError(conflictBackoff, isConcurrentModification, func() error {
+		configMap, err := s.Client.GetConfigMap(ConfigMapName(s.User))
+		if err != nil {
+			return fmt.Errorf("unable to get queue: %w", err)
+		}
+
+		q, err := decode(configMap)
+		if err != nil {
+			return err
+		}
+
+		if err := fn(q); err != nil {
+			return err
+		}
+
+		b, err := json.Marshal(q)
+		if err != nil {
+			return err
+		}
+
+		if configMap == nil {
+			configMap = &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{
+				Name:   ConfigMapName(s.User),
+				Labels: map[string]string{QueueLabel: k8s.LabelValue(s.User)},
+			}}
+			configMap.Data = map[string]string{dataKey: string(b)}
+
+			// Returned as is, so that a queue created concurrently is detected.
+			return s.Client.CreateConfigMap(configMap)
+		}
+
+		// The resource version of the config map is kept, so that concurrent updates are detected.
+		configMap.Data = map[string]string{dataKey: string(b)}
+		return s.Client.UpdateConfigMap(configMap)
+	})
+}
+
+func decode(configMap *corev1.ConfigMap) (*Queue, error) {
+	q := &Queue{}
+	if configMap == nil {
+		return q, nil
+	}
+
+	if err := json.Unmarshal([]byte(configMap.Data[dataKey]), q); err != nil {
+		return nil, fmt.Errorf("unable to decode queue: %w", err)
+	}
+
+	return q, nil
+}
+
+func isConcurrentModification(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsAlreadyExists(err)
+}
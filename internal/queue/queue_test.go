@@ -0,0 +1,243 @@
+package queue
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/fake"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// configMapClient keeps config maps in memory, rejecting updates of stale resource versions like the API server.
+type configMapClient struct {
+	*fake.Client
+
+	mu         sync.Mutex
+	configMaps map[string]*corev1.ConfigMap
+	version    int
+}
+
+func newConfigMapClient() *configMapClient {
+	return &configMapClient{Client: &fake.Client{}, configMaps: map[string]*corev1.ConfigMap{}}
+}
+
+func (c *configMapClient) GetConfigMap(name string) (*corev1.ConfigMap, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if configMap, ok := c.configMaps[name]; ok {
+		return configMap.DeepCopy(), nil
+	}
+
+	return nil, nil
+}
+
+func (c *configMapClient) CreateConfigMap(configMap *corev1.ConfigMap) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.configMaps[configMap.Name]; ok {
+		return apierrors.NewAlreadyExists(schema.GroupResource{Resource: "configmaps"}, configMap.Name)
+	}
+
+	return c.save(configMap)
+}
+
+func (c *configMapClient) UpdateConfigMap(configMap *corev1.ConfigMap) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.configMaps[configMap.Name].ResourceVersion != configMap.ResourceVersion {
+		return apierrors.NewConflict(schema.GroupResource{Resource: "configmaps"}, configMap.Name, nil)
+	}
+
+	return c.save(configMap)
+}
+
+func (c *configMapClient) save(configMap *corev1.ConfigMap) error {
+	c.version++
+	saved := configMap.DeepCopy()
+	saved.ResourceVersion = strconv.Itoa(c.version)
+	c.configMaps[configMap.Name] = saved
+
+	return nil
+}
+
+func newJob(name string) *batchv1.Job {
+	return &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: name}}
+}
+
+func jobNames(q *Queue) []string {
+	var names []string
+	for _, entry := range q.Entries {
+		names = append(names, entry.Job.Name)
+	}
+
+	return names
+}
+
+func TestConfigMapName(t *testing.T) {
+	assert.Equal(t, "frink-queue-jane-doe-uit-no", ConfigMapName("Jane.Doe@uit.no"))
+	assert.Equal(t, "frink-queue-unknown", ConfigMapName(""))
+}
+
+func TestQueueRemove(t *testing.T) {
+	q := &Queue{Entries: []Entry{{Job: newJob("a")}, {Job: newJob("b")}, {Job: newJob("a")}}}
+
+	assert.Equal(t, 2, q.Remove("a"))
+	assert.Equal(t, 0, q.Remove("c"))
+	assert.Equal(t, []string{"b"}, jobNames(q))
+}
+
+func TestStoreUpdate(t *testing.T) {
+	client := newConfigMapClient()
+	store := &Store{Client: client, User: "jane"}
+
+	q, err := store.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, q.Entries)
+
+	for _, name := range []string{"a", "b"} {
+		err := store.Update(func(q *Queue) error {
+			q.Entries = append(q.Entries, Entry{Job: newJob(name)})
+			return nil
+		})
+		assert.NoError(t, err)
+	}
+
+	q, err = store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, jobNames(q))
+	assert.Equal(t, "jane", client.configMaps["frink-queue-jane"].Labels[QueueLabel])
+}
+
+func TestStoreUpdateConcurrently(t *testing.T) {
+	client := newConfigMapClient()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Each store stands in for a separate terminal.
+			store := &Store{Client: client, User: "jane"}
+			err := store.Update(func(q *Queue) error {
+				q.Entries = append(q.Entries, Entry{Job: newJob(strconv.Itoa(i))})
+				return nil
+			})
+			assert.NoError(t, err)
+		}(i)
+	}
+	wg.Wait()
+
+	q, err := (&Store{Client: client, User: "jane"}).Load()
+	assert.NoError(t, err)
+	assert.Len(t, q.Entries, 10)
+}
+
+func TestRunnerRun(t *testing.T) {
+	var out strings.Builder
+	client := newConfigMapClient()
+	store := &Store{Client: client, User: "jane"}
+	err := store.Update(func(q *Queue) error {
+		q.Entries = []Entry{{Job: newJob("a")}, {Job: newJob("b")}, {Job: newJob("c")}}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	active := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "x", Labels: map[string]string{k8s.UserLabel: "jane"}}}
+	active.Status.Active = 1
+	other := batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "y", Labels: map[string]string{k8s.UserLabel: "joe"}}}
+	other.Status.Active = 1
+
+	// One slot is taken by an active job, which finishes before the second check.
+	client.On("ListJobs").Return([]batchv1.Job{active, other}, nil).Once()
+	client.On("ListJobs").Return([]batchv1.Job{other}, nil)
+	client.On("GetJob", "a").Return(nil, nil)
+	client.On("GetJob", "b").Return(newJob("b"), nil)
+	client.On("GetJob", "c").Return(nil, nil)
+	client.On("CreateJob", mock.Anything).Return(nil).Times(3)
+
+	runner := &Runner{Client: client, Store: store, Out: &out, MaxActive: 2, Interval: time.Millisecond}
+	err = runner.Run()
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	assert.Len(t, lines, 4)
+	assert.Equal(t, "Submitted job a (2 left in queue)", lines[0])
+	// The name of an existing job is taken, so a suffix is appended.
+	assert.Regexp(t, `^Submitted job b-\w{5} \(1 left in queue\)$`, lines[1])
+	assert.Equal(t, "Submitted job c (0 left in queue)", lines[2])
+	assert.Equal(t, "Queue is empty", lines[3])
+
+	q, err := store.Load()
+	assert.NoError(t, err)
+	assert.Empty(t, q.Entries)
+
+	client.AssertExpectations(t)
+}
+
+func TestRunnerRunRequeuesOnFailure(t *testing.T) {
+	client := newConfigMapClient()
+	store := &Store{Client: client, User: "jane"}
+	err := store.Update(func(q *Queue) error {
+		q.Entries = []Entry{{Job: newJob("a")}, {Job: newJob("b")}}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	client.On("ListJobs").Return(nil, nil)
+	client.On("GetJob", "a").Return(nil, nil)
+	client.On("CreateJob", mock.Anything).Return(apierrors.NewForbidden(schema.GroupResource{Resource: "jobs"}, "a", nil))
+
+	runner := &Runner{Client: client, Store: store, Out: &strings.Builder{}, MaxActive: 1}
+	err = runner.Run()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to create job a")
+
+	q, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, jobNames(q))
+}
+
+func TestRunnerRunDoesNotRequeueAfterSubmit(t *testing.T) {
+	client := newConfigMapClient()
+	store := &Store{Client: client, User: "jane"}
+
+	job := newJob("a")
+	job.Spec.Template.Spec.Containers = []corev1.Container{{Name: "main"}}
+	k8s.SetDistributed(job, 2, 1)
+	err := store.Update(func(q *Queue) error {
+		q.Entries = []Entry{{Job: job}, {Job: newJob("b")}}
+		return nil
+	})
+	assert.NoError(t, err)
+
+	client.On("ListJobs").Return(nil, nil)
+	client.On("GetJob", "a").Return(nil, nil).Once()
+	client.On("GetJob", "a").Return(job, nil)
+	client.On("CreateJob", mock.Anything).Return(nil).Once()
+	client.On("CreateService", mock.Anything).Return(errors.New("forbidden"))
+
+	runner := &Runner{Client: client, Store: store, Out: &strings.Builder{}, MaxActive: 1}
+	err = runner.Run()
+	assert.EqualError(t, err, "job a was submitted, but its service could not be created: forbidden")
+
+	q, err := store.Load()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"b"}, jobNames(q), "submitted jobs must not be put back in the queue")
+
+	client.AssertExpectations(t)
+}
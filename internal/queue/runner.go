@@ -0,0 +1,149 @@
+package queue
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/uitml/frink/internal/k8s"
+	"github.com/uitml/frink/internal/k8s/retry"
+	batchv1 "k8s.io/api/batch/v1"
+	"k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var backoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   1.0,
+	Steps:    1200,
+}
+
+// Runner submits the jobs of a queue, keeping at most MaxActive of the user's jobs active at a time.
+//
+// Several runners may process the same queue, e.g. from different terminals; each job is submitted by only one of them,
+// but the limit is only approximate in that case, since they do not coordinate when counting active jobs.
+type Runner struct {
+	Client k8s.Client
+	Store  *Store
+
+	// Out receives progress messages, such as the jobs being submitted.
+	Out io.Writer
+
+	// MaxActive is the maximum number of the user's jobs active at a time, including jobs not submitted via the queue.
+	MaxActive int
+
+	// Interval is the time between checking the status of active jobs.
+	Interval time.Duration
+}
+
+// Run submits jobs from the queue until it is empty.
+func (r *Runner) Run() error {
+	interval := r.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+
+	maxActive := r.MaxActive
+	if maxActive < 1 {
+		maxActive = 1
+	}
+
+	for {
+		active, err := r.activeJobs()
+		if err != nil {
+			return err
+		}
+
+		for ; active < maxActive; active++ {
+			submitted, err := r.submitNext()
+			if err != nil {
+				return err
+			}
+			if !submitted {
+				fmt.Fprintln(r.Out, "Queue is empty")
+				return nil
+			}
+		}
+
+		time.Sleep(interval)
+	}
+}
+
+// activeJobs returns the number of jobs of the user that have not finished, excluding suspended jobs.
+// Jobs that have been created but have no pods yet count as active, so that they are not exceeded while starting.
+func (r *Runner) activeJobs() (int, error) {
+	jobs, err := r.Client.ListJobs()
+	if err != nil {
+		return 0, fmt.Errorf("unable to list jobs: %w", err)
+	}
+
+	user := k8s.LabelValue(r.Store.User)
+	active := 0
+	for _, job := range jobs {
+		if job.Labels[k8s.UserLabel] != user {
+			continue
+		}
+
+		switch k8s.JobStatus(job) {
+		case k8s.StatusActive, k8s.StatusStopped:
+			active++
+		}
+	}
+
+	return active, nil
+}
+
+// submitNext removes the first job from the queue and creates it, returning false if the queue is empty.
+// If the job cannot be created, it is put back at the front of the queue. Once the job has been created,
+// it stays out of the queue even if its service cannot be created, since requeueing it would submit it twice.
+func (r *Runner) submitNext() (bool, error) {
+	var (
+		entry Entry
+		ok    bool
+		left  int
+	)
+	err := r.Store.Update(func(q *Queue) error {
+		entry, ok = q.Pop()
+		left = len(q.Entries)
+		return nil
+	})
+	if err != nil || !ok {
+		return false, err
+	}
+
+	job := entry.Job
+	if err := r.createJob(job); err != nil {
+		requeueErr := r.Store.Update(func(q *Queue) error {
+			q.Entries = append([]Entry{entry}, q.Entries...)
+			return nil
+		})
+		if requeueErr != nil {
+			return false, fmt.Errorf("unable to create job %s: %v; unable to put it back in the queue: %w", job.Name, err, requeueErr)
+		}
+
+		return false, fmt.Errorf("unable to create job %s: %w", job.Name, err)
+	}
+
+	fmt.Fprintf(r.Out, "Submitted job %s (%d left in queue)\n", job.Name, left)
+
+	if err := k8s.CreateJobService(r.Client, job); err != nil {
+		return false, fmt.Errorf("job %s was submitted, but its service could not be created: %w", job.Name, err)
+	}
+
+	return true, nil
+}
+
+// createJob creates the job, appending a random suffix to its name if a job with the same name already exists,
+// since jobs may wait in the queue long after they were added.
+func (r *Runner) createJob(job *batchv1.Job) error {
+	existing, err := r.Client.GetJob(job.Name)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		k8s.Rename(job, fmt.Sprintf("%s-%s", job.Name, rand.String(5)))
+	}
+
+	// Jobs are passed by pointer and might be modified by the client, so a copy is created.
+	return retry.OnExists(backoff, func() error { return r.Client.CreateJob(job.DeepCopy()) })
+}
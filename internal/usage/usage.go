@@ -0,0 +1,160 @@
+// Package usage aggregates the resources consumed by jobs over a time window.
+package usage
+
+import (
+	"sort"
+	"time"
+
+	"github.com/uitml/frink/internal/gpu"
+	"github.com/uitml/frink/internal/k8s"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Record holds the resources consumed by a group of jobs, in resource-hours.
+// E.g. a job running for 2 hours with 4 GPUs consumes 8 GPU-hours.
+type Record struct {
+	Key  string `json:"key"`
+	Jobs int    `json:"jobs"`
+
+	GPUHours       float64 `json:"gpuHours"`
+	CPUHours       float64 `json:"cpuHours"`
+	MemoryGiBHours float64 `json:"memoryGiBHours"`
+}
+
+// Add adds the resources consumed by other to the record.
+func (r *Record) Add(other Record) {
+	r.Jobs += other.Jobs
+	r.GPUHours += other.GPUHours
+	r.CPUHours += other.CPUHours
+	r.MemoryGiBHours += other.MemoryGiBHours
+}
+
+// Aggregate returns the resources consumed by the jobs within the window [from, to), grouped by key,
+// and ordered by GPU-hours, then CPU-hours, in descending order. Jobs that did not run within the window are ignored.
+func Aggregate(jobs []batchv1.Job, from, to time.Time, key func(batchv1.Job) string) []Record {
+	index := map[string]int{}
+	var records []Record
+	for _, job := range jobs {
+		usage, ok := JobUsage(job, from, to)
+		if !ok {
+			continue
+		}
+
+		k := key(job)
+		i, ok := index[k]
+		if !ok {
+			i = len(records)
+			index[k] = i
+			records = append(records, Record{Key: k})
+		}
+		records[i].Add(usage)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].GPUHours != records[j].GPUHours {
+			return records[i].GPUHours > records[j].GPUHours
+		}
+		if records[i].CPUHours != records[j].CPUHours {
+			return records[i].CPUHours > records[j].CPUHours
+		}
+		return records[i].Key < records[j].Key
+	})
+
+	return records
+}
+
+// JobUsage returns the resources consumed by the job within the window [from, to),
+// or false if the job did not run within the window.
+//
+// The job is considered to have run from its start time until its completion time, or until it failed.
+// Active jobs are considered to run until the end of the window, while suspended jobs, e.g. paused via
+// "frink pause", run until they were suspended, and other unfinished jobs without active pods are not charged.
+// Resources are the requests of the pod template, falling back to limits, multiplied by the parallelism of the job.
+func JobUsage(job batchv1.Job, from, to time.Time) (Record, bool) {
+	start, end, ok := runtime(job, to)
+	if !ok {
+		return Record{}, false
+	}
+
+	if start.Before(from) {
+		start = from
+	}
+	if end.After(to) {
+		end = to
+	}
+	if !end.After(start) {
+		return Record{}, false
+	}
+
+	hours := end.Sub(start).Hours() * float64(parallelism(job))
+	spec := job.Spec.Template.Spec
+	record := Record{
+		Jobs:           1,
+		GPUHours:       float64(gpu.PodRequest(corev1.Pod{Spec: spec})) * hours,
+		CPUHours:       PodRequest(spec, corev1.ResourceCPU) * hours,
+		MemoryGiBHours: PodRequest(spec, corev1.ResourceMemory) / (1 << 30) * hours,
+	}
+
+	return record, true
+}
+
+// runtime returns when the job started and stopped running, using now for jobs that are still running,
+// or false if the job has not run, or it is unknown until when it did; see JobUsage.
+func runtime(job batchv1.Job, now time.Time) (time.Time, time.Time, bool) {
+	if job.Status.StartTime == nil {
+		return time.Time{}, time.Time{}, false
+	}
+
+	start := job.Status.StartTime.Time
+	if end, ok := k8s.JobFinishTime(job); ok {
+		return start, end, true
+	}
+
+	if job.Spec.Suspend != nil && *job.Spec.Suspend {
+		if end, ok := suspendTime(job); ok {
+			return start, end, true
+		}
+		return time.Time{}, time.Time{}, false
+	}
+
+	if job.Status.Active == 0 {
+		return time.Time{}, time.Time{}, false
+	}
+
+	return start, now, true
+}
+
+// suspendTime returns when the job was suspended, or false if it is not known.
+func suspendTime(job batchv1.Job) (time.Time, bool) {
+	for _, condition := range job.Status.Conditions {
+		if condition.Type == batchv1.JobSuspended && condition.Status == corev1.ConditionTrue {
+			return condition.LastTransitionTime.Time, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+func parallelism(job batchv1.Job) int32 {
+	n := int32(1)
+	if job.Spec.Parallelism != nil {
+		n = *job.Spec.Parallelism
+	}
+	if job.Spec.Completions != nil && *job.Spec.Completions < n {
+		n = *job.Spec.Completions
+	}
+	if n < 1 {
+		n = 1
+	}
+
+	return n
+}
+
+// PodRequest returns the effective request of the resource by pods using the specification,
+// i.e. the larger of the sum of the container requests and the largest init container request.
+// Limits are used for containers without requests. CPU is in cores, and memory in bytes.
+func PodRequest(spec corev1.PodSpec, name corev1.ResourceName) float64 {
+	qty := gpu.ResourceRequest(spec, name)
+	return qty.AsApproximateFloat64()
+}
@@ -0,0 +1,109 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var now = time.Date(2021, 10, 1, 12, 0, 0, 0, time.UTC)
+
+func newJob(name, user string, start time.Time, end *time.Time, gpus string) batchv1.Job {
+	job := batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{"frink/user": user}},
+	}
+	job.Spec.Template.Spec.Containers = []corev1.Container{{
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("500m")},
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("8Gi"),
+				"nvidia.com/gpu":      resource.MustParse(gpus),
+			},
+		},
+	}}
+	job.Status.StartTime = &metav1.Time{Time: start}
+	if end != nil {
+		job.Status.CompletionTime = &metav1.Time{Time: *end}
+	}
+
+	return job
+}
+
+func TestJobUsage(t *testing.T) {
+	end := now.Add(-time.Hour)
+	job := newJob("a", "jane", now.Add(-3*time.Hour), &end, "4")
+
+	record, ok := JobUsage(job, now.Add(-24*time.Hour), now)
+	assert.True(t, ok)
+	assert.Equal(t, 1, record.Jobs)
+	assert.InDelta(t, 8, record.GPUHours, 1e-9)
+	assert.InDelta(t, 1, record.CPUHours, 1e-9)
+	assert.InDelta(t, 16, record.MemoryGiBHours, 1e-9)
+
+	// Only the part of the run within the window counts.
+	record, ok = JobUsage(job, now.Add(-2*time.Hour), now)
+	assert.True(t, ok)
+	assert.InDelta(t, 4, record.GPUHours, 1e-9)
+
+	_, ok = JobUsage(job, now.Add(-30*time.Minute), now)
+	assert.False(t, ok)
+}
+
+func TestJobUsageActiveAndParallel(t *testing.T) {
+	job := newJob("a", "jane", now.Add(-2*time.Hour), nil, "1")
+	parallelism := int32(3)
+	job.Spec.Parallelism = &parallelism
+	job.Status.Active = 3
+
+	record, ok := JobUsage(job, now.Add(-24*time.Hour), now)
+	assert.True(t, ok)
+	assert.InDelta(t, 6, record.GPUHours, 1e-9)
+}
+
+func TestJobUsageSuspendedAndStopped(t *testing.T) {
+	job := newJob("a", "jane", now.Add(-3*time.Hour), nil, "2")
+	suspend := true
+	job.Spec.Suspend = &suspend
+	job.Status.Conditions = []batchv1.JobCondition{{
+		Type:               batchv1.JobSuspended,
+		Status:             corev1.ConditionTrue,
+		LastTransitionTime: metav1.Time{Time: now.Add(-2 * time.Hour)},
+	}}
+
+	// The job is charged until it was paused.
+	record, ok := JobUsage(job, now.Add(-24*time.Hour), now)
+	assert.True(t, ok)
+	assert.InDelta(t, 2, record.GPUHours, 1e-9)
+
+	_, ok = JobUsage(job, now.Add(-time.Hour), now)
+	assert.False(t, ok)
+
+	// Unfinished jobs without active pods are not charged.
+	job.Spec.Suspend = nil
+	job.Status.Conditions = nil
+	_, ok = JobUsage(job, now.Add(-24*time.Hour), now)
+	assert.False(t, ok)
+}
+
+func TestAggregate(t *testing.T) {
+	end := now.Add(-time.Hour)
+	jobs := []batchv1.Job{
+		newJob("a", "jane", now.Add(-2*time.Hour), &end, "1"),
+		newJob("b", "john", now.Add(-2*time.Hour), &end, "4"),
+		newJob("c", "jane", now.Add(-2*time.Hour), &end, "1"),
+		{ObjectMeta: metav1.ObjectMeta{Name: "pending"}},
+	}
+
+	records := Aggregate(jobs, now.Add(-24*time.Hour), now, func(job batchv1.Job) string { return job.Labels["frink/user"] })
+	if assert.Len(t, records, 2) {
+		assert.Equal(t, "john", records[0].Key)
+		assert.Equal(t, "jane", records[1].Key)
+		assert.Equal(t, 2, records[1].Jobs)
+		assert.InDelta(t, 2, records[1].GPUHours, 1e-9)
+	}
+}